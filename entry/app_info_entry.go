@@ -8,6 +8,8 @@ package rkentry
 import (
 	"context"
 	"encoding/json"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"github.com/rookie-ninja/rk-entry/v2/os"
 	"strings"
 )
 
@@ -21,6 +23,11 @@ type bootConfigAppInfo struct {
 		HomeUrl     string   `yaml:"homeUrl" json:"homeUrl"`
 		DocsUrl     []string `yaml:"docsUrl" json:"docsUrl"`
 		Maintainers []string `yaml:"maintainers" json:"maintainers"`
+		ErrorStyle  string   `yaml:"errorStyle" json:"errorStyle"`
+		ErrorPolicy struct {
+			Development    *bool    `yaml:"development" json:"development"`
+			RedactPatterns []string `yaml:"redactPatterns" json:"redactPatterns"`
+		} `yaml:"errorPolicy" json:"errorPolicy"`
 	} `yaml:"app"`
 }
 
@@ -36,6 +43,8 @@ type appInfoEntry struct {
 	HomeUrl          string   `json:"-" yaml:"-"`
 	DocsUrl          []string `json:"-" yaml:"-"`
 	Maintainers      []string `json:"-" yaml:"-"`
+	ErrorStyle       string   `json:"-" yaml:"-"`
+	ErrorDevelopment bool     `json:"-" yaml:"-"`
 }
 
 // appInfoEntryDefault generate a AppInfo entry with default fields.
@@ -45,15 +54,25 @@ func appInfoEntryDefault() *appInfoEntry {
 		entryType:        appInfoEntryType,
 		entryDescription: "Internal RK entry which describes application with fields of appName, version and etc.",
 		AppName:          "rk",
-		Version:          "local",
+		Version:          defaultAppVersion(),
 		Lang:             "golang",
 		Keywords:         []string{},
 		HomeUrl:          "",
 		DocsUrl:          []string{},
 		Maintainers:      []string{},
+		ErrorStyle:       rkerror.StyleGoogle,
 	}
 }
 
+// defaultAppVersion returns version of binary injected with ldflags or read from build info, local returned if missing
+func defaultAppVersion() string {
+	if version := rkos.NewBuildInfo().Version; len(version) > 0 {
+		return version
+	}
+
+	return "local"
+}
+
 // registerAppInfoEntryYAML register appInfoEntry with bytes of YAML
 func registerAppInfoEntryYAML(raw []byte) map[string]Entry {
 	// Unmarshal user provided config into boot config struct
@@ -91,6 +110,32 @@ func registerAppInfoEntryYAML(raw []byte) map[string]Entry {
 		entry.Maintainers = make([]string, 0)
 	}
 
+	// error style, builtin styles are google, amazon and problem,
+	// custom style should be registered with rkerror.RegisterStyle before bootstrap
+	if len(config.App.ErrorStyle) > 0 {
+		builder, err := rkerror.GetStyle(config.App.ErrorStyle)
+		if err != nil {
+			ShutdownWithError(err)
+		}
+		entry.ErrorStyle = config.App.ErrorStyle
+		GlobalAppCtx.SetErrorBuilder(builder)
+	}
+
+	// error policy, in development mode, internal details and stack traces are exposed to client,
+	// development mode set in code is kept if not configured,
+	// redact patterns are appended to rkerror.DefaultRedactPatterns
+	if config.App.ErrorPolicy.Development != nil {
+		rkerror.SetDevelopment(*config.App.ErrorPolicy.Development)
+	}
+	entry.ErrorDevelopment = rkerror.IsDevelopment()
+	if len(config.App.ErrorPolicy.RedactPatterns) > 0 {
+		patterns := append([]string{}, rkerror.DefaultRedactPatterns...)
+		patterns = append(patterns, config.App.ErrorPolicy.RedactPatterns...)
+		if err := rkerror.SetRedactPatterns(patterns...); err != nil {
+			ShutdownWithError(err)
+		}
+	}
+
 	GlobalAppCtx.appInfoEntry = entry
 
 	EventEntryStdout = NewEventEntryStdout()
@@ -131,14 +176,16 @@ func (entry *appInfoEntry) String() string {
 // MarshalJSON Marshal entry.
 func (entry *appInfoEntry) MarshalJSON() ([]byte, error) {
 	m := map[string]interface{}{
-		"name":        entry.GetName(),
-		"type":        entry.GetType(),
-		"description": entry.GetDescription(),
-		"appName":     entry.AppName,
-		"lang":        entry.Lang,
-		"homeUrl":     entry.HomeUrl,
-		"docsUrl":     entry.DocsUrl,
-		"maintainers": strings.Join(entry.Maintainers, ","),
+		"name":             entry.GetName(),
+		"type":             entry.GetType(),
+		"description":      entry.GetDescription(),
+		"appName":          entry.AppName,
+		"lang":             entry.Lang,
+		"homeUrl":          entry.HomeUrl,
+		"docsUrl":          entry.DocsUrl,
+		"maintainers":      strings.Join(entry.Maintainers, ","),
+		"errorStyle":       entry.ErrorStyle,
+		"errorDevelopment": entry.ErrorDevelopment,
 	}
 
 	return json.Marshal(m)
@@ -6,6 +6,9 @@
 package rkentry
 
 import (
+	"errors"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"github.com/rookie-ninja/rk-entry/v2/os"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -79,3 +82,68 @@ func assertNotPanic(t *testing.T) {
 		assert.True(t, true)
 	}
 }
+
+func TestRegisterAppInfoEntry_WithErrorStyle(t *testing.T) {
+	defer GlobalAppCtx.SetErrorBuilder(rkerror.NewErrorBuilderGoogle())
+
+	// default
+	assert.IsType(t, &rkerror.ErrorBuilderGoogle{}, GlobalAppCtx.GetErrorBuilder())
+
+	// builtin style
+	entries := registerAppInfoEntryYAML([]byte("app:\n  errorStyle: problem"))
+	assert.Equal(t, "problem", entries[appInfoEntryName].(*appInfoEntry).ErrorStyle)
+	assert.IsType(t, &rkerror.ErrorBuilderProblem{}, GlobalAppCtx.GetErrorBuilder())
+	assert.IsType(t, &rkerror.ErrorProblem{}, rkerror.FromError(errors.New("ut")))
+
+	// custom style
+	rkerror.RegisterStyle(rkerror.StyleCustom, &rkerror.ErrorBuilderProblem{TypeBaseUrl: "https://example.com"})
+	registerAppInfoEntryYAML([]byte("app:\n  errorStyle: Custom"))
+	assert.Equal(t, "https://example.com", GlobalAppCtx.GetErrorBuilder().(*rkerror.ErrorBuilderProblem).TypeBaseUrl)
+
+	// unknown style
+	defer assertPanic(t)
+	registerAppInfoEntryYAML([]byte("app:\n  errorStyle: unknown"))
+}
+
+func TestRegisterAppInfoEntry_WithErrorPolicy(t *testing.T) {
+	defer rkerror.SetDevelopment(false)
+	defer rkerror.SetRedactPatterns(rkerror.DefaultRedactPatterns...)
+
+	// default
+	entries := registerAppInfoEntryYAML([]byte("app:\n  name: ut"))
+	assert.False(t, entries[appInfoEntryName].(*appInfoEntry).ErrorDevelopment)
+	assert.Equal(t, "ssn=123", rkerror.Redact("ssn=123"))
+
+	// with policy
+	entries = registerAppInfoEntryYAML([]byte("app:\n  errorPolicy:\n    development: true\n    redactPatterns: [\"(ssn=)\\\\d+\"]"))
+	assert.True(t, entries[appInfoEntryName].(*appInfoEntry).ErrorDevelopment)
+	assert.True(t, rkerror.IsDevelopment())
+	assert.Equal(t, "ssn=[REDACTED] password=[REDACTED]", rkerror.Redact("ssn=123 password=abc"))
+
+	// development mode is kept if not configured
+	entries = registerAppInfoEntryYAML([]byte("app:\n  name: ut"))
+	assert.True(t, entries[appInfoEntryName].(*appInfoEntry).ErrorDevelopment)
+	assert.True(t, rkerror.IsDevelopment())
+
+	// development mode is reset by later config
+	entries = registerAppInfoEntryYAML([]byte("app:\n  errorPolicy:\n    development: false"))
+	assert.False(t, entries[appInfoEntryName].(*appInfoEntry).ErrorDevelopment)
+	assert.False(t, rkerror.IsDevelopment())
+
+	// invalid pattern
+	defer assertPanic(t)
+	registerAppInfoEntryYAML([]byte("app:\n  errorPolicy:\n    redactPatterns: [\"(\"]"))
+}
+
+func TestRegisterAppInfoEntry_WithBuildVersion(t *testing.T) {
+	defer func() { rkos.BuildVersion = "" }()
+
+	// fallback to build info
+	rkos.BuildVersion = "v1.0.0"
+	entries := registerAppInfoEntryYAML([]byte("app:\n  name: ut"))
+	assert.Equal(t, "v1.0.0", entries[appInfoEntryName].(*appInfoEntry).Version)
+
+	// version in YAML first
+	entries = registerAppInfoEntryYAML([]byte("app:\n  version: v2.0.0"))
+	assert.Equal(t, "v2.0.0", entries[appInfoEntryName].(*appInfoEntry).Version)
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"github.com/rookie-ninja/rk-entry/v2/os"
+	"net/http"
+	"path"
+	"runtime"
+	"sort"
+)
+
+// @title RK Common Service
+// @version 1.0
+// @description This is builtin RK common service.
+
+// @contact.name rk-dev
+// @contact.url https://github.com/rookie-ninja/rk-entry
+// @contact.email lark@pointgoal.io
+
+// @license.name Apache 2.0 License
+// @license.url https://github.com/rookie-ninja/rk-entry/blob/master/LICENSE.txt
+
+// @securityDefinitions.basic BasicAuth
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey JWT
+// @in header
+// @name Authorization
+
+// @schemes http https
+
+const defaultCommonServicePathPrefix = "/rk/v1/"
+
+// BootCommonService Bootstrap config of common service.
+// 1: Enabled: Enable common service.
+// 2: PathPrefix: Prefix of common service paths, default is /rk/v1/.
+type BootCommonService struct {
+	Enabled    bool   `yaml:"enabled" json:"enabled"`
+	PathPrefix string `yaml:"pathPrefix" json:"pathPrefix"`
+}
+
+// CommonServiceEntry RK common service which contains commonly used APIs.
+// Handlers are standard http.HandlerFunc, web framework entries could mount them with paths of entry.
+// 1: Ready Returns ready status of application.
+// 2: Alive Returns alive status of application.
+// 3: Gc Trigger gc and returns memory stats of GC before and after.
+// 4: Info Returns process info.
+// 5: Entries Returns entries registered in GlobalAppCtx.
+type CommonServiceEntry struct {
+	entryName        string `json:"-" yaml:"-"`
+	entryType        string `json:"-" yaml:"-"`
+	entryDescription string `json:"-" yaml:"-"`
+	ReadyPath        string `json:"-" yaml:"-"`
+	AlivePath        string `json:"-" yaml:"-"`
+	GcPath           string `json:"-" yaml:"-"`
+	InfoPath         string `json:"-" yaml:"-"`
+	EntriesPath      string `json:"-" yaml:"-"`
+}
+
+// CommonServiceEntryOption Common service entry option.
+type CommonServiceEntryOption func(*CommonServiceEntry)
+
+// WithNameCommonServiceEntry Provide name.
+func WithNameCommonServiceEntry(name string) CommonServiceEntryOption {
+	return func(entry *CommonServiceEntry) {
+		entry.entryName = name
+	}
+}
+
+// RegisterCommonServiceEntry Create new common service entry with options.
+func RegisterCommonServiceEntry(boot *BootCommonService, opts ...CommonServiceEntryOption) *CommonServiceEntry {
+	if !boot.Enabled {
+		return nil
+	}
+
+	pathPrefix := boot.PathPrefix
+	if len(pathPrefix) < 1 {
+		pathPrefix = defaultCommonServicePathPrefix
+	}
+	pathPrefix = path.Join("/", pathPrefix)
+
+	entry := &CommonServiceEntry{
+		entryName:        "CommonServiceEntry",
+		entryType:        CommonServiceEntryType,
+		entryDescription: "Internal RK entry which implements commonly used API.",
+		ReadyPath:        path.Join(pathPrefix, "ready"),
+		AlivePath:        path.Join(pathPrefix, "alive"),
+		GcPath:           path.Join(pathPrefix, "gc"),
+		InfoPath:         path.Join(pathPrefix, "info"),
+		EntriesPath:      path.Join(pathPrefix, "entries"),
+	}
+
+	for i := range opts {
+		opts[i](entry)
+	}
+
+	return entry
+}
+
+// Bootstrap common service entry.
+func (entry *CommonServiceEntry) Bootstrap(context.Context) {}
+
+// Interrupt common service entry.
+func (entry *CommonServiceEntry) Interrupt(context.Context) {}
+
+// GetName Get name of entry.
+func (entry *CommonServiceEntry) GetName() string {
+	return entry.entryName
+}
+
+// GetType Get entry type.
+func (entry *CommonServiceEntry) GetType() string {
+	return entry.entryType
+}
+
+// GetDescription Get description of entry.
+func (entry *CommonServiceEntry) GetDescription() string {
+	return entry.entryDescription
+}
+
+// String Stringfy entry.
+func (entry *CommonServiceEntry) String() string {
+	bytes, _ := json.Marshal(entry)
+	return string(bytes)
+}
+
+// MarshalJSON Marshal entry
+func (entry *CommonServiceEntry) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"name":        entry.entryName,
+		"type":        entry.entryType,
+		"description": entry.entryDescription,
+		"readyPath":   entry.ReadyPath,
+		"alivePath":   entry.AlivePath,
+		"gcPath":      entry.GcPath,
+		"infoPath":    entry.InfoPath,
+		"entriesPath": entry.EntriesPath,
+	}
+
+	return json.Marshal(&m)
+}
+
+// UnmarshalJSON Not supported.
+func (entry *CommonServiceEntry) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// Ready handler
+// @Summary Get application readiness status
+// @Id 8001
+// @version 1.0
+// @Security ApiKeyAuth
+// @Security BasicAuth
+// @Security JWT
+// @produce application/json
+// @Success 200 {object} readyResp
+// @Failure 503 {object} rkerror.ErrorGoogle
+// @Router /rk/v1/ready [get]
+func (entry *CommonServiceEntry) Ready(writer http.ResponseWriter, request *http.Request) {
+	if !GlobalAppCtx.IsReady(request, writer) {
+		writeJSON(writer, http.StatusServiceUnavailable,
+			GlobalAppCtx.GetErrorBuilder().New(http.StatusServiceUnavailable, "application is not ready"))
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, &readyResp{
+		Ready: true,
+	})
+}
+
+// Alive handler
+// @Summary Get application liveness status
+// @Id 8002
+// @version 1.0
+// @Security ApiKeyAuth
+// @Security BasicAuth
+// @Security JWT
+// @produce application/json
+// @Success 200 {object} aliveResp
+// @Failure 503 {object} rkerror.ErrorGoogle
+// @Router /rk/v1/alive [get]
+func (entry *CommonServiceEntry) Alive(writer http.ResponseWriter, request *http.Request) {
+	if !GlobalAppCtx.IsAlive(request, writer) {
+		writeJSON(writer, http.StatusServiceUnavailable,
+			GlobalAppCtx.GetErrorBuilder().New(http.StatusServiceUnavailable, "application is not alive"))
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, &aliveResp{
+		Alive: true,
+	})
+}
+
+// Gc handler
+// @Summary Trigger Gc
+// @Id 8003
+// @version 1.0
+// @Security ApiKeyAuth
+// @Security BasicAuth
+// @Security JWT
+// @produce application/json
+// @Success 200 {object} gcResp
+// @Router /rk/v1/gc [get]
+func (entry *CommonServiceEntry) Gc(writer http.ResponseWriter, request *http.Request) {
+	before := rkos.NewMemInfo()
+	runtimeBefore := rkos.NewRuntimeInfo()
+	runtime.GC()
+	after := rkos.NewMemInfo()
+	runtimeAfter := rkos.NewRuntimeInfo()
+
+	writeJSON(writer, http.StatusOK, &gcResp{
+		MemStatBeforeGc:     before,
+		MemStatAfterGc:      after,
+		RuntimeStatBeforeGc: runtimeBefore,
+		RuntimeStatAfterGc:  runtimeAfter,
+	})
+}
+
+// Info handler
+// @Summary Get application and process info
+// @Id 8004
+// @version 1.0
+// @Security ApiKeyAuth
+// @Security BasicAuth
+// @Security JWT
+// @produce application/json
+// @Success 200 {object} ProcessInfo
+// @Router /rk/v1/info [get]
+func (entry *CommonServiceEntry) Info(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, NewProcessInfo())
+}
+
+// Entries handler
+// @Summary List entries registered in application
+// @Id 8005
+// @version 1.0
+// @Security ApiKeyAuth
+// @Security BasicAuth
+// @Security JWT
+// @produce application/json
+// @Success 200 {object} entriesResp
+// @Router /rk/v1/entries [get]
+func (entry *CommonServiceEntry) Entries(writer http.ResponseWriter, request *http.Request) {
+	resp := &entriesResp{
+		Entries: make(map[string][]*entryElement),
+	}
+
+	for entryType, byName := range GlobalAppCtx.ListEntries() {
+		elements := make([]*entryElement, 0)
+		for _, e := range byName {
+			element := &entryElement{
+				EntryName:        e.GetName(),
+				EntryType:        e.GetType(),
+				EntryDescription: e.GetDescription(),
+				EntryMeta:        json.RawMessage(e.String()),
+			}
+
+			if !json.Valid(element.EntryMeta) {
+				element.EntryMeta = nil
+			}
+
+			if status := GlobalAppCtx.GetEntryStatus(e.GetType(), e.GetName()); status != nil {
+				element.State = status.State
+			}
+
+			elements = append(elements, element)
+		}
+
+		sort.Slice(elements, func(i, j int) bool {
+			return elements[i].EntryName < elements[j].EntryName
+		})
+
+		resp.Entries[entryType] = elements
+	}
+
+	writeJSON(writer, http.StatusOK, resp)
+}
+
+// writeJSON marshal object and write it to http.ResponseWriter with code
+func writeJSON(writer http.ResponseWriter, code int, obj interface{}) {
+	contentType := rkerror.ContentTypeJSON
+	if v, ok := obj.(rkerror.ErrorInterface); ok {
+		contentType = rkerror.ContentType(v)
+		obj = rkerror.Sanitize(v)
+	}
+
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		code = http.StatusInternalServerError
+		bytes, _ = json.Marshal(rkerror.Sanitize(GlobalAppCtx.GetErrorBuilder().New(code, "failed to marshal response", rkerror.Internal(err))))
+	}
+
+	writer.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	writer.WriteHeader(code)
+	writer.Write(bytes)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterCommonServiceEntry(t *testing.T) {
+	// with disabled
+	assert.Nil(t, RegisterCommonServiceEntry(&BootCommonService{}))
+
+	// with default prefix
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+	assert.Equal(t, "/rk/v1/ready", entry.ReadyPath)
+	assert.Equal(t, "/rk/v1/alive", entry.AlivePath)
+	assert.Equal(t, "/rk/v1/gc", entry.GcPath)
+	assert.Equal(t, "/rk/v1/info", entry.InfoPath)
+	assert.Equal(t, "/rk/v1/entries", entry.EntriesPath)
+	assert.NotEmpty(t, entry.GetName())
+	assert.Equal(t, CommonServiceEntryType, entry.GetType())
+	assert.NotEmpty(t, entry.GetDescription())
+	assert.NotEmpty(t, entry.String())
+	assert.Nil(t, entry.UnmarshalJSON(nil))
+
+	// with prefix and options
+	entry = RegisterCommonServiceEntry(&BootCommonService{
+		Enabled:    true,
+		PathPrefix: "ut-prefix/",
+	}, WithNameCommonServiceEntry("ut-common"))
+	assert.Equal(t, "ut-common", entry.GetName())
+	assert.Equal(t, "/ut-prefix/ready", entry.ReadyPath)
+}
+
+func TestCommonServiceEntry_Bootstrap_Interrupt(t *testing.T) {
+	defer assertNotPanic(t)
+
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+	entry.Bootstrap(context.TODO())
+	entry.Interrupt(context.TODO())
+}
+
+func TestCommonServiceEntry_Ready(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.SetReadinessCheck(nil)
+
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+
+	// bootstrapping
+	mock := &EntryMock{Name: "ut-entry"}
+	GlobalAppCtx.AddEntry(mock)
+	GlobalAppCtx.SetEntryState(mock, EntryStateBootstrapping)
+	writer := httptest.NewRecorder()
+	entry.Ready(writer, httptest.NewRequest(http.MethodGet, entry.ReadyPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+
+	// bootstrapped
+	GlobalAppCtx.BootstrapEntry(context.TODO(), mock)
+	writer = httptest.NewRecorder()
+	entry.Ready(writer, httptest.NewRequest(http.MethodGet, entry.ReadyPath, nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Equal(t, "application/json; charset=utf-8", writer.Header().Get("Content-Type"))
+
+	// readiness check failed
+	GlobalAppCtx.SetReadinessCheck(func(req *http.Request, resp http.ResponseWriter) bool {
+		return false
+	})
+	writer = httptest.NewRecorder()
+	entry.Ready(writer, httptest.NewRequest(http.MethodGet, entry.ReadyPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+}
+
+func TestCommonServiceEntry_Alive(t *testing.T) {
+	defer GlobalAppCtx.SetLivenessCheck(nil)
+
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+
+	writer := httptest.NewRecorder()
+	entry.Alive(writer, httptest.NewRequest(http.MethodGet, entry.AlivePath, nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	GlobalAppCtx.SetLivenessCheck(func(req *http.Request, resp http.ResponseWriter) bool {
+		return false
+	})
+	writer = httptest.NewRecorder()
+	entry.Alive(writer, httptest.NewRequest(http.MethodGet, entry.AlivePath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+}
+
+func TestCommonServiceEntry_Gc(t *testing.T) {
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+
+	writer := httptest.NewRecorder()
+	entry.Gc(writer, httptest.NewRequest(http.MethodGet, entry.GcPath, nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	resp := &gcResp{}
+	assert.Nil(t, json.Unmarshal(writer.Body.Bytes(), resp))
+	assert.NotNil(t, resp.MemStatBeforeGc)
+	assert.NotNil(t, resp.MemStatAfterGc)
+	assert.NotNil(t, resp.RuntimeStatAfterGc)
+	assert.True(t, resp.RuntimeStatAfterGc.GcCycles > resp.RuntimeStatBeforeGc.GcCycles)
+}
+
+func TestCommonServiceEntry_Info(t *testing.T) {
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+
+	writer := httptest.NewRecorder()
+	entry.Info(writer, httptest.NewRequest(http.MethodGet, entry.InfoPath, nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	resp := &ProcessInfo{}
+	assert.Nil(t, json.Unmarshal(writer.Body.Bytes(), resp))
+	assert.NotEmpty(t, resp.AppName)
+}
+
+func TestCommonServiceEntry_Entries(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	entry := RegisterCommonServiceEntry(&BootCommonService{
+		Enabled: true,
+	})
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.AddEntry(&EntryMock{Name: "ut-entry"})
+
+	writer := httptest.NewRecorder()
+	entry.Entries(writer, httptest.NewRequest(http.MethodGet, entry.EntriesPath, nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	resp := &entriesResp{}
+	assert.Nil(t, json.Unmarshal(writer.Body.Bytes(), resp))
+	assert.Len(t, resp.Entries[CommonServiceEntryType], 1)
+	assert.Equal(t, EntryStateRegistered, resp.Entries[CommonServiceEntryType][0].State)
+	assert.NotEmpty(t, resp.Entries[CommonServiceEntryType][0].EntryMeta)
+	assert.Len(t, resp.Entries["mock"], 1)
+	assert.Empty(t, resp.Entries["mock"][0].EntryMeta)
+}
+
+func TestWriteJSON_WithInternalDetail(t *testing.T) {
+	defer rkerror.SetInternalLogger(logInternalErrorDetails)
+
+	logged := make([]string, 0)
+	rkerror.SetInternalLogger(func(correlationId string, code int, msg string, details []string) {
+		logged = append(logged, details...)
+	})
+
+	err := GlobalAppCtx.GetErrorBuilder().New(http.StatusInternalServerError, "ut-msg", rkerror.Internal("ut-internal"))
+	assert.Empty(t, logged)
+
+	writer := httptest.NewRecorder()
+	writeJSON(writer, http.StatusInternalServerError, err)
+	assert.Equal(t, http.StatusInternalServerError, writer.Code)
+	assert.NotContains(t, writer.Body.String(), "ut-internal")
+	assert.Contains(t, writer.Body.String(), "correlationId")
+	assert.Equal(t, []string{"ut-internal"}, logged)
+}
@@ -8,9 +8,15 @@ package rkentry
 import (
 	"context"
 	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"go.uber.org/zap"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -24,17 +30,23 @@ var (
 				appInfoEntryName: appInfoEntryDefault(),
 			},
 		},
+		entryStatus:   map[string]map[string]*EntryStatus{},
 		embedFS:       map[string]map[string]*embed.FS{},
 		appInfoEntry:  appInfoEntryDefault(),
 		shutdownSig:   make(chan os.Signal),
 		shutdownHooks: make(map[string]ShutdownHook),
 		userValues:    make(map[string]interface{}),
+		observers:     make([]*lifecycleSubscription, 0),
 	}
 
 	builtinRegFuncList = []RegFunc{
 		registerAppInfoEntryYAML,
 		RegisterLoggerEntryYAML,
 		RegisterEventEntryYAML,
+		RegisterPProfEntryYAML,
+		RegisterSamplerEntryYAML,
+		RegisterPromEntryYAML,
+		RegisterTracerEntryYAML,
 		// RegisterConfigEntryYAML,
 		// RegisterCertEntryYAML,
 	}
@@ -54,6 +66,45 @@ type ShutdownHook func()
 type ReadinessCheck func(req *http.Request, resp http.ResponseWriter) bool
 type LivenessCheck func(req *http.Request, resp http.ResponseWriter) bool
 
+// EntryState defines lifecycle state of Entry tracked by appContext
+type EntryState string
+
+const (
+	// EntryStateRegistered entry added into GlobalAppCtx and waiting for Bootstrap
+	EntryStateRegistered EntryState = "registered"
+	// EntryStateBootstrapping entry is running Bootstrap
+	EntryStateBootstrapping EntryState = "bootstrapping"
+	// EntryStateReady entry finished Bootstrap
+	EntryStateReady EntryState = "ready"
+	// EntryStateFailed entry panicked while Bootstrap or Interrupt
+	EntryStateFailed EntryState = "failed"
+	// EntryStateInterrupting entry is running Interrupt
+	EntryStateInterrupting EntryState = "interrupting"
+	// EntryStateStopped entry finished Interrupt
+	EntryStateStopped EntryState = "stopped"
+)
+
+// EntryStatus lifecycle status of an Entry with timestamp of each state it reached
+type EntryStatus struct {
+	EntryName  string                   `json:"entryName" yaml:"entryName" example:"greeter"`
+	EntryType  string                   `json:"entryType" yaml:"entryType" example:"GinEntry"`
+	State      EntryState               `json:"state" yaml:"state" example:"ready"`
+	Error      string                   `json:"error,omitempty" yaml:"error,omitempty" example:""`
+	UpdatedAt  time.Time                `json:"updatedAt" yaml:"updatedAt" example:"2022-03-15T20:43:05+08:00"`
+	Timestamps map[EntryState]time.Time `json:"timestamps" yaml:"timestamps"`
+}
+
+// copy returns a deep copy of status in order to prevent concurrent modification
+func (s *EntryStatus) copy() *EntryStatus {
+	res := *s
+	res.Timestamps = make(map[EntryState]time.Time, len(s.Timestamps))
+	for k, v := range s.Timestamps {
+		res.Timestamps[k] = v
+	}
+
+	return &res
+}
+
 // Init global app context with bellow fields.
 func init() {
 	signal.Notify(GlobalAppCtx.shutdownSig,
@@ -61,21 +112,36 @@ func init() {
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
+
+	rkerror.SetInternalLogger(logInternalErrorDetails)
+}
+
+// logInternalErrorDetails logs internal error details hidden from client with default LoggerEntry
+func logInternalErrorDetails(correlationId string, code int, msg string, details []string) {
+	GlobalAppCtx.GetLoggerEntryDefault().Error("Internal error details hidden from client",
+		zap.String("correlationId", correlationId),
+		zap.Int("code", code),
+		zap.String("message", msg),
+		zap.Strings("details", details))
 }
 
 // Application context which contains bellow fields.
 // It is not recommended override this value since StartTime would be assigned to current time
 // at beginning of go process in init() function.
 type appContext struct {
-	startTime      time.Time                       `json:"-" yaml:"-"`
-	appInfoEntry   *appInfoEntry                   `json:"-" yaml:"-"`
-	readinessCheck ReadinessCheck                  `json:"-" yaml:"-"`
-	livenessCheck  LivenessCheck                   `json:"-" yaml:"-"`
-	entries        map[string]map[string]Entry     `json:"-" yaml:"-"`
-	embedFS        map[string]map[string]*embed.FS `json:"-" yaml:"-"`
-	userValues     map[string]interface{}          `json:"-" yaml:"-"`
-	shutdownSig    chan os.Signal                  `json:"-" yaml:"-"`
-	shutdownHooks  map[string]ShutdownHook         `json:"-" yaml:"-"`
+	startTime      time.Time                          `json:"-" yaml:"-"`
+	appInfoEntry   *appInfoEntry                      `json:"-" yaml:"-"`
+	readinessCheck ReadinessCheck                     `json:"-" yaml:"-"`
+	livenessCheck  LivenessCheck                      `json:"-" yaml:"-"`
+	entries        map[string]map[string]Entry        `json:"-" yaml:"-"`
+	entryStatus    map[string]map[string]*EntryStatus `json:"-" yaml:"-"`
+	entryStatusMu  sync.RWMutex                       `json:"-" yaml:"-"`
+	embedFS        map[string]map[string]*embed.FS    `json:"-" yaml:"-"`
+	userValues     map[string]interface{}             `json:"-" yaml:"-"`
+	shutdownSig    chan os.Signal                     `json:"-" yaml:"-"`
+	shutdownHooks  map[string]ShutdownHook            `json:"-" yaml:"-"`
+	observers      []*lifecycleSubscription           `json:"-" yaml:"-"`
+	observerMu     sync.RWMutex                       `json:"-" yaml:"-"`
 }
 
 // RegisterPluginRegFunc register rk plugins registration function.
@@ -145,7 +211,7 @@ func BootstrapBuiltInEntryFromYAML(raw []byte) {
 	for i := range builtinRegFuncList {
 		entries := builtinRegFuncList[i](raw)
 		for _, v := range entries {
-			v.Bootstrap(ctx)
+			GlobalAppCtx.BootstrapEntry(ctx, v)
 		}
 	}
 }
@@ -157,7 +223,7 @@ func BootstrapPluginEntryFromYAML(raw []byte) {
 	for i := range pluginRegFuncList {
 		entries := pluginRegFuncList[i](raw)
 		for _, v := range entries {
-			v.Bootstrap(ctx)
+			GlobalAppCtx.BootstrapEntry(ctx, v)
 		}
 	}
 }
@@ -169,7 +235,7 @@ func BootstrapWebFrameEntryFromYAML(raw []byte) {
 	for i := range webFrameRegFuncList {
 		entries := webFrameRegFuncList[i](raw)
 		for _, v := range entries {
-			v.Bootstrap(ctx)
+			GlobalAppCtx.BootstrapEntry(ctx, v)
 		}
 	}
 }
@@ -181,7 +247,7 @@ func BootstrapUserEntryFromYAML(raw []byte) {
 	for i := range userDefRegFuncList {
 		entries := userDefRegFuncList[i](raw)
 		for _, v := range entries {
-			v.Bootstrap(ctx)
+			GlobalAppCtx.BootstrapEntry(ctx, v)
 		}
 	}
 }
@@ -218,6 +284,29 @@ func (ctx *appContext) SetLivenessCheck(f LivenessCheck) {
 	ctx.livenessCheck = f
 }
 
+// IsReady returns false until every tracked Entry is ready, which is also false once any Entry is interrupting
+// or stopped via InterruptEntry, after that, user defined ReadinessCheck will be called if exists.
+func (ctx *appContext) IsReady(req *http.Request, resp http.ResponseWriter) bool {
+	if !ctx.IsBootstrapped() {
+		return false
+	}
+
+	if ctx.readinessCheck != nil {
+		return ctx.readinessCheck(req, resp)
+	}
+
+	return true
+}
+
+// IsAlive calls user defined LivenessCheck if exists.
+func (ctx *appContext) IsAlive(req *http.Request, resp http.ResponseWriter) bool {
+	if ctx.livenessCheck != nil {
+		return ctx.livenessCheck(req, resp)
+	}
+
+	return true
+}
+
 // ********************************
 // ****** User value related ******
 // ********************************
@@ -225,6 +314,12 @@ func (ctx *appContext) SetLivenessCheck(f LivenessCheck) {
 // AddValue add value to GlobalAppCtx.
 func (ctx *appContext) AddValue(key string, value interface{}) {
 	ctx.userValues[key] = value
+
+	ctx.publish(&LifecycleEvent{
+		Type:  LifecycleValueChanged,
+		Key:   key,
+		Value: value,
+	})
 }
 
 // GetValue returns value from GlobalAppCtx.
@@ -239,13 +334,22 @@ func (ctx *appContext) ListValues() map[string]interface{} {
 
 // RemoveValue remove value from GlobalAppCtx.
 func (ctx *appContext) RemoveValue(key string) {
+	if _, ok := ctx.userValues[key]; !ok {
+		return
+	}
+
 	delete(ctx.userValues, key)
+
+	ctx.publish(&LifecycleEvent{
+		Type: LifecycleValueChanged,
+		Key:  key,
+	})
 }
 
 // ClearValues clear values from GlobalAppCtx.
 func (ctx *appContext) ClearValues() {
 	for k := range ctx.userValues {
-		delete(ctx.userValues, k)
+		ctx.RemoveValue(k)
 	}
 }
 
@@ -257,6 +361,21 @@ func (ctx *appContext) GetAppInfoEntry() *appInfoEntry {
 	return ctx.appInfoEntry
 }
 
+// ***********************************
+// ****** Error builder related ******
+// ***********************************
+
+// GetErrorBuilder returns ErrorBuilder selected by app.errorStyle in boot config.
+// Returns rkerror.ErrorBuilderGoogle if not configured.
+func (ctx *appContext) GetErrorBuilder() rkerror.ErrorBuilder {
+	return rkerror.DefaultBuilder()
+}
+
+// SetErrorBuilder sets ErrorBuilder which should be used by every entry and plugin.
+func (ctx *appContext) SetErrorBuilder(builder rkerror.ErrorBuilder) {
+	rkerror.SetDefaultBuilder(builder)
+}
+
 // func (ctx *appContext) GetConfigEntry(entryName string) *ConfigEntry {
 // 	entries := ctx.entries[ConfigEntryType]
 
@@ -343,10 +462,24 @@ func (ctx *appContext) AddEntry(entry Entry) {
 	} else {
 		v[entry.GetName()] = entry
 	}
+
+	// state of entry added again is kept unless it was stopped, for example, a ready entry stays ready
+	if status := ctx.GetEntryStatus(entry.GetType(), entry.GetName()); status == nil || status.State == EntryStateStopped {
+		ctx.SetEntryState(entry, EntryStateRegistered)
+	}
+
+	ctx.publish(&LifecycleEvent{
+		Type:  LifecycleEntryAdded,
+		Entry: entry,
+	})
 }
 
 func (ctx *appContext) clearEntries() {
 	ctx.entries = map[string]map[string]Entry{}
+
+	ctx.entryStatusMu.Lock()
+	ctx.entryStatus = map[string]map[string]*EntryStatus{}
+	ctx.entryStatusMu.Unlock()
 }
 
 func (ctx *appContext) GetEntry(entryType, entryName string) Entry {
@@ -362,13 +495,42 @@ func (ctx *appContext) RemoveEntry(entry Entry) {
 		return
 	}
 
-	if v, ok := ctx.entries[entry.GetType()]; ok {
+	v, ok := ctx.entries[entry.GetType()]
+	if !ok {
+		return
+	}
+	removed, ok := v[entry.GetName()]
+	if !ok {
+		return
+	}
+	delete(v, entry.GetName())
+
+	ctx.entryStatusMu.Lock()
+	if v, ok := ctx.entryStatus[entry.GetType()]; ok {
 		delete(v, entry.GetName())
 	}
+	ctx.entryStatusMu.Unlock()
+
+	ctx.publish(&LifecycleEvent{
+		Type:  LifecycleEntryRemoved,
+		Entry: removed,
+	})
 }
 
 func (ctx *appContext) RemoveEntryByType(entryType string) {
+	removed := ctx.entries[entryType]
 	delete(ctx.entries, entryType)
+
+	ctx.entryStatusMu.Lock()
+	delete(ctx.entryStatus, entryType)
+	ctx.entryStatusMu.Unlock()
+
+	for _, entry := range removed {
+		ctx.publish(&LifecycleEvent{
+			Type:  LifecycleEntryRemoved,
+			Entry: entry,
+		})
+	}
 }
 
 func (ctx *appContext) ListEntriesByType(entryType string) map[string]Entry {
@@ -403,6 +565,181 @@ func (ctx *appContext) GetCryptoEntry(entryName string) Crypto {
 	return nil
 }
 
+// **********************************
+// ****** Entry state related *******
+// **********************************
+
+// SetEntryState records lifecycle state of Entry with current timestamp.
+func (ctx *appContext) SetEntryState(entry Entry, state EntryState) {
+	ctx.setEntryState(entry, state, nil)
+}
+
+// SetEntryFailed marks Entry as EntryStateFailed with error.
+func (ctx *appContext) SetEntryFailed(entry Entry, err error) {
+	if err == nil {
+		err = errors.New("unknown error")
+	}
+
+	ctx.setEntryState(entry, EntryStateFailed, err)
+}
+
+func (ctx *appContext) setEntryState(entry Entry, state EntryState, err error) {
+	if entry == nil {
+		return
+	}
+
+	now := time.Now()
+
+	ctx.entryStatusMu.Lock()
+	defer ctx.entryStatusMu.Unlock()
+
+	byName, ok := ctx.entryStatus[entry.GetType()]
+	if !ok {
+		byName = make(map[string]*EntryStatus)
+		ctx.entryStatus[entry.GetType()] = byName
+	}
+
+	status, ok := byName[entry.GetName()]
+	if !ok {
+		status = &EntryStatus{
+			EntryName:  entry.GetName(),
+			EntryType:  entry.GetType(),
+			Timestamps: make(map[EntryState]time.Time),
+		}
+		byName[entry.GetName()] = status
+	}
+
+	status.State = state
+	status.UpdatedAt = now
+	status.Timestamps[state] = now
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+}
+
+// GetEntryStatus returns a copy of lifecycle status of Entry, nil if Entry is not tracked.
+func (ctx *appContext) GetEntryStatus(entryType, entryName string) *EntryStatus {
+	ctx.entryStatusMu.RLock()
+	defer ctx.entryStatusMu.RUnlock()
+
+	if v, ok := ctx.entryStatus[entryType]; ok {
+		if status, ok := v[entryName]; ok {
+			return status.copy()
+		}
+	}
+
+	return nil
+}
+
+// ListEntryStatus returns copies of lifecycle status of all tracked entries.
+func (ctx *appContext) ListEntryStatus() []*EntryStatus {
+	ctx.entryStatusMu.RLock()
+	defer ctx.entryStatusMu.RUnlock()
+
+	res := make([]*EntryStatus, 0)
+	for _, byName := range ctx.entryStatus {
+		for _, status := range byName {
+			res = append(res, status.copy())
+		}
+	}
+
+	return res
+}
+
+// IsBootstrapped returns true if every tracked Entry is in EntryStateReady.
+//
+// Entries bootstrapped by calling Entry.Bootstrap directly instead of BootstrapEntry, like sub entries of
+// web framework plugins, should be marked by SetEntryState with EntryStateReady after Bootstrap.
+func (ctx *appContext) IsBootstrapped() bool {
+	ctx.entryStatusMu.RLock()
+	defer ctx.entryStatusMu.RUnlock()
+
+	for _, byName := range ctx.entryStatus {
+		for _, status := range byName {
+			if status.State != EntryStateReady {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// BootstrapEntry calls Entry.Bootstrap and records lifecycle state.
+//
+// Entry will be marked as EntryStateFailed if Bootstrap panics, the panic will be passed to caller.
+func (ctx *appContext) BootstrapEntry(c context.Context, entry Entry) {
+	if entry == nil {
+		return
+	}
+
+	defer ctx.recoverEntry(entry)
+
+	ctx.SetEntryState(entry, EntryStateBootstrapping)
+	entry.Bootstrap(c)
+	ctx.SetEntryState(entry, EntryStateReady)
+
+	ctx.publish(&LifecycleEvent{
+		Type:  LifecycleEntryBootstrapped,
+		Entry: entry,
+	})
+}
+
+// InterruptEntry calls Entry.Interrupt and records lifecycle state.
+//
+// Entry will be marked as EntryStateFailed if Interrupt panics, the panic will be passed to caller.
+func (ctx *appContext) InterruptEntry(c context.Context, entry Entry) {
+	if entry == nil {
+		return
+	}
+
+	defer ctx.recoverEntry(entry)
+
+	ctx.SetEntryState(entry, EntryStateInterrupting)
+	entry.Interrupt(c)
+	ctx.SetEntryState(entry, EntryStateStopped)
+
+	ctx.publish(&LifecycleEvent{
+		Type:  LifecycleEntryInterrupted,
+		Entry: entry,
+	})
+}
+
+// recoverEntry marks Entry as failed and re-panic
+func (ctx *appContext) recoverEntry(entry Entry) {
+	if r := recover(); r != nil {
+		if err, ok := r.(error); ok {
+			ctx.SetEntryFailed(entry, err)
+		} else {
+			ctx.SetEntryFailed(entry, fmt.Errorf("%v", r))
+		}
+		panic(r)
+	}
+}
+
+// StartupProbeHandler returns http.Handler which can be used as startupProbe of kubernetes.
+//
+// Returns http.StatusOK if every tracked Entry is ready, http.StatusServiceUnavailable otherwise.
+func (ctx *appContext) StartupProbeHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		resp := &startupResp{
+			Started: ctx.IsBootstrapped(),
+			Entries: ctx.ListEntryStatus(),
+		}
+
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if resp.Started {
+			writer.WriteHeader(http.StatusOK)
+		} else {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		bytes, _ := json.Marshal(resp)
+		writer.Write(bytes)
+	})
+}
+
 // ***********************************
 // ****** Shutdown hook related ******
 // ***********************************
@@ -459,6 +796,10 @@ func (ctx *appContext) clearShutdownHooks() {
 // WaitForShutdownSig waits for shutdown signal.
 func (ctx *appContext) WaitForShutdownSig() {
 	<-ctx.shutdownSig
+
+	ctx.publish(&LifecycleEvent{
+		Type: LifecycleShutdownStarted,
+	})
 }
 
 // GetShutdownSig returns shutdown signal.
@@ -8,8 +8,10 @@ package rkentry
 import (
 	"context"
 	"embed"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"syscall"
 	"testing"
@@ -404,6 +406,165 @@ func TestAppContext_SetReadinessCheck(t *testing.T) {
 	assert.NotNil(t, GlobalAppCtx.livenessCheck)
 }
 
+func TestAppContext_EntryState(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	entry := &EntryMock{
+		Name: "unit-test-entry",
+	}
+
+	// empty context is bootstrapped
+	assert.True(t, GlobalAppCtx.IsBootstrapped())
+
+	// registered entry is not bootstrapped yet
+	GlobalAppCtx.AddEntry(entry)
+	status := GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName())
+	assert.Equal(t, EntryStateRegistered, status.State)
+	assert.False(t, GlobalAppCtx.IsBootstrapped())
+	assert.False(t, GlobalAppCtx.IsReady(nil, nil))
+
+	GlobalAppCtx.SetEntryState(entry, EntryStateBootstrapping)
+	assert.False(t, GlobalAppCtx.IsBootstrapped())
+	assert.False(t, GlobalAppCtx.IsReady(nil, nil))
+
+	GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+	status = GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName())
+	assert.Equal(t, EntryStateReady, status.State)
+	assert.Contains(t, status.Timestamps, EntryStateRegistered)
+	assert.Contains(t, status.Timestamps, EntryStateBootstrapping)
+	assert.Contains(t, status.Timestamps, EntryStateReady)
+	assert.True(t, GlobalAppCtx.IsBootstrapped())
+
+	// ready entry added again is kept as ready
+	GlobalAppCtx.AddEntry(entry)
+	assert.Equal(t, EntryStateReady, GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName()).State)
+	assert.True(t, GlobalAppCtx.IsBootstrapped())
+
+	GlobalAppCtx.InterruptEntry(context.TODO(), entry)
+	status = GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName())
+	assert.Equal(t, EntryStateStopped, status.State)
+	assert.False(t, GlobalAppCtx.IsReady(nil, nil))
+	assert.Len(t, GlobalAppCtx.ListEntryStatus(), 1)
+
+	// stopped entry added again is registered
+	GlobalAppCtx.AddEntry(entry)
+	assert.Equal(t, EntryStateRegistered, GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName()).State)
+
+	GlobalAppCtx.RemoveEntry(entry)
+	assert.Nil(t, GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName()))
+	assert.Empty(t, GlobalAppCtx.ListEntryStatus())
+}
+
+func TestAppContext_BootstrapEntry_WithPanic(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	entry := &EntryPanicMock{}
+	GlobalAppCtx.AddEntry(entry)
+
+	func() {
+		defer assertPanic(t)
+		GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+	}()
+
+	status := GlobalAppCtx.GetEntryStatus(entry.GetType(), entry.GetName())
+	assert.Equal(t, EntryStateFailed, status.State)
+	assert.Equal(t, "ut-panic", status.Error)
+	assert.False(t, GlobalAppCtx.IsBootstrapped())
+}
+
+func TestAppContext_IsReady(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.SetReadinessCheck(nil)
+
+	entry := &EntryMock{
+		Name: "unit-test-entry",
+	}
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.SetReadinessCheck(func(req *http.Request, resp http.ResponseWriter) bool {
+		return false
+	})
+
+	// bootstrapping
+	GlobalAppCtx.SetEntryState(entry, EntryStateBootstrapping)
+	assert.False(t, GlobalAppCtx.IsReady(nil, nil))
+
+	// bootstrapped, but readiness check returns false
+	GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+	assert.False(t, GlobalAppCtx.IsReady(nil, nil))
+
+	GlobalAppCtx.SetReadinessCheck(nil)
+	assert.True(t, GlobalAppCtx.IsReady(nil, nil))
+	assert.True(t, GlobalAppCtx.IsAlive(nil, nil))
+}
+
+func TestAppContext_StartupProbeHandler(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	entry := &EntryMock{
+		Name: "unit-test-entry",
+	}
+	GlobalAppCtx.AddEntry(entry)
+
+	GlobalAppCtx.SetEntryState(entry, EntryStateBootstrapping)
+
+	handler := GlobalAppCtx.StartupProbeHandler()
+
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+	assert.Contains(t, writer.Body.String(), string(EntryStateBootstrapping))
+
+	GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Contains(t, writer.Body.String(), string(EntryStateReady))
+}
+
+func TestAppContext_IsReady_WithEntryBootstrappedDirectly(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	// bootstrapped via BootstrapEntry
+	entry := &EntryMock{
+		Name: "unit-test-entry",
+	}
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+
+	// sub entry added and bootstrapped directly, state is kept as registered
+	subEntry := &EntryMock{
+		Name: "unit-test-sub-entry",
+	}
+	GlobalAppCtx.AddEntry(subEntry)
+	subEntry.Bootstrap(context.TODO())
+
+	assert.Equal(t, EntryStateRegistered, GlobalAppCtx.GetEntryStatus(subEntry.GetType(), subEntry.GetName()).State)
+	assert.False(t, GlobalAppCtx.IsBootstrapped())
+	assert.False(t, GlobalAppCtx.IsReady(nil, nil))
+
+	writer := httptest.NewRecorder()
+	GlobalAppCtx.StartupProbeHandler().ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+
+	// marked as ready explicitly
+	GlobalAppCtx.SetEntryState(subEntry, EntryStateReady)
+	assert.True(t, GlobalAppCtx.IsBootstrapped())
+	assert.True(t, GlobalAppCtx.IsReady(nil, nil))
+
+	writer = httptest.NewRecorder()
+	GlobalAppCtx.StartupProbeHandler().ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+}
+
+type EntryPanicMock struct {
+	EntryMock
+}
+
+func (entry *EntryPanicMock) Bootstrap(context.Context) {
+	panic(errors.New("ut-panic"))
+}
+
 type EntryMock struct {
 	Name string
 }
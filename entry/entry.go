@@ -35,6 +35,8 @@ const (
 	SignerJwtEntryType = "SignerJwtEntry"
 	CryptoEntryType    = "CryptoEntry"
 	PProfEntryType     = "PProfEntry"
+	SamplerEntryType   = "SamplerEntry"
+	TracerEntryType    = "TracerEntry"
 )
 
 // RegFunc can be used to create an entry could be any kinds of services or pieces of codes which
@@ -320,3 +320,20 @@ func (entry *EventEntry) Sync() {
 		entry.baseLogger.Sync()
 	}
 }
+
+// CreateEventWithContext creates event with trace id and span id of active span and request id in ctx.
+func (entry *EventEntry) CreateEventWithContext(ctx context.Context, opts ...rkquery.EventOption) rkquery.Event {
+	event := entry.EventFactory.CreateEvent(opts...)
+	InjectTraceToEvent(ctx, event)
+
+	return event
+}
+
+// CreateEventThreadSafeWithContext creates thread safe event with trace id and span id of active span
+// and request id in ctx.
+func (entry *EventEntry) CreateEventThreadSafeWithContext(ctx context.Context, opts ...rkquery.EventOption) rkquery.Event {
+	event := entry.EventFactory.CreateEventThreadSafe(opts...)
+	InjectTraceToEvent(ctx, event)
+
+	return event
+}
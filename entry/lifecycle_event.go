@@ -0,0 +1,113 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"time"
+)
+
+// LifecycleEventType defines type of LifecycleEvent published by appContext
+type LifecycleEventType string
+
+const (
+	// LifecycleEntryAdded published after Entry was added via AddEntry
+	LifecycleEntryAdded LifecycleEventType = "EntryAdded"
+	// LifecycleEntryRemoved published after Entry was removed via RemoveEntry
+	LifecycleEntryRemoved LifecycleEventType = "EntryRemoved"
+	// LifecycleEntryBootstrapped published after Entry finished Bootstrap via BootstrapEntry
+	LifecycleEntryBootstrapped LifecycleEventType = "EntryBootstrapped"
+	// LifecycleEntryInterrupted published after Entry finished Interrupt via InterruptEntry
+	LifecycleEntryInterrupted LifecycleEventType = "EntryInterrupted"
+	// LifecycleShutdownStarted published after shutdown signal received
+	LifecycleShutdownStarted LifecycleEventType = "ShutdownStarted"
+	// LifecycleValueChanged published after user value was added or removed
+	LifecycleValueChanged LifecycleEventType = "ValueChanged"
+)
+
+// LifecycleEvent is published by appContext to observers.
+//
+// Entry would be nil for LifecycleShutdownStarted and LifecycleValueChanged.
+// Key and Value would be filled for LifecycleValueChanged only, Value would be nil if key was removed.
+type LifecycleEvent struct {
+	Type  LifecycleEventType
+	Time  time.Time
+	Entry Entry
+	Key   string
+	Value interface{}
+}
+
+// LifecycleObserver receives LifecycleEvent synchronously.
+type LifecycleObserver func(event *LifecycleEvent)
+
+// lifecycleSubscription observer with event types it is interested in
+type lifecycleSubscription struct {
+	observer LifecycleObserver
+	types    map[LifecycleEventType]bool
+}
+
+// Subscribe registers observer for lifecycle events of GlobalAppCtx.
+//
+// Observer will receive all types of events if no types provided.
+// Events are delivered synchronously in order of subscription, in the goroutine which triggers them, so that
+// observer is able to attach itself to an Entry before it is bootstrapped. Subscribe in init() in order not to miss
+// entries registered by builtin registration functions.
+//
+// Returns a function which removes the subscription.
+func (ctx *appContext) Subscribe(observer LifecycleObserver, types ...LifecycleEventType) func() {
+	if observer == nil {
+		return func() {}
+	}
+
+	sub := &lifecycleSubscription{
+		observer: observer,
+		types:    make(map[LifecycleEventType]bool),
+	}
+	for i := range types {
+		sub.types[types[i]] = true
+	}
+
+	ctx.observerMu.Lock()
+	ctx.observers = append(ctx.observers, sub)
+	ctx.observerMu.Unlock()
+
+	return func() {
+		ctx.observerMu.Lock()
+		defer ctx.observerMu.Unlock()
+
+		for i := range ctx.observers {
+			if ctx.observers[i] == sub {
+				ctx.observers = append(ctx.observers[:i], ctx.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// publish delivers event to observers in order of subscription.
+// Lock is released before calling observers, so observers may interact with appContext.
+func (ctx *appContext) publish(event *LifecycleEvent) {
+	event.Time = time.Now()
+
+	ctx.observerMu.RLock()
+	subs := make([]*lifecycleSubscription, 0, len(ctx.observers))
+	for _, sub := range ctx.observers {
+		if len(sub.types) < 1 || sub.types[event.Type] {
+			subs = append(subs, sub)
+		}
+	}
+	ctx.observerMu.RUnlock()
+
+	for i := range subs {
+		subs[i].observer(event)
+	}
+}
+
+// Internal use only.
+func (ctx *appContext) clearObservers() {
+	ctx.observerMu.Lock()
+	ctx.observers = make([]*lifecycleSubscription, 0)
+	ctx.observerMu.Unlock()
+}
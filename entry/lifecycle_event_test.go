@@ -0,0 +1,168 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"syscall"
+	"testing"
+)
+
+func TestAppContext_Subscribe_WithNilObserver(t *testing.T) {
+	defer assertNotPanic(t)
+
+	unsubscribe := GlobalAppCtx.Subscribe(nil)
+	unsubscribe()
+}
+
+func TestAppContext_Subscribe_HappyCase(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.clearObservers()
+
+	events := make([]*LifecycleEvent, 0)
+	unsubscribe := GlobalAppCtx.Subscribe(func(event *LifecycleEvent) {
+		events = append(events, event)
+	})
+
+	entry := &EntryMock{
+		Name: "unit-test-entry",
+	}
+
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+	GlobalAppCtx.InterruptEntry(context.TODO(), entry)
+	GlobalAppCtx.RemoveEntry(entry)
+	GlobalAppCtx.AddValue("key", "value")
+	GlobalAppCtx.RemoveValue("key")
+
+	assert.Len(t, events, 6)
+	assert.Equal(t, LifecycleEntryAdded, events[0].Type)
+	assert.Equal(t, entry, events[0].Entry)
+	assert.Equal(t, LifecycleEntryBootstrapped, events[1].Type)
+	assert.Equal(t, LifecycleEntryInterrupted, events[2].Type)
+	assert.Equal(t, LifecycleEntryRemoved, events[3].Type)
+	assert.Equal(t, LifecycleValueChanged, events[4].Type)
+	assert.Equal(t, "key", events[4].Key)
+	assert.Equal(t, "value", events[4].Value)
+	assert.Equal(t, LifecycleValueChanged, events[5].Type)
+	assert.Nil(t, events[5].Value)
+	assert.False(t, events[0].Time.IsZero())
+
+	// no more events after unsubscribe
+	unsubscribe()
+	GlobalAppCtx.AddEntry(entry)
+	assert.Len(t, events, 6)
+}
+
+func TestAppContext_Subscribe_InOrder(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.clearObservers()
+
+	order := make([]int, 0)
+	unsubscribes := make([]func(), 0)
+	for i := 0; i < 10; i++ {
+		index := i
+		unsubscribes = append(unsubscribes, GlobalAppCtx.Subscribe(func(event *LifecycleEvent) {
+			order = append(order, index)
+		}))
+	}
+
+	GlobalAppCtx.AddValue("key", "value")
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, order)
+
+	// order is kept after unsubscribe
+	order = order[:0]
+	unsubscribes[3]()
+	unsubscribes[7]()
+	GlobalAppCtx.AddValue("key", "value")
+	assert.Equal(t, []int{0, 1, 2, 4, 5, 6, 8, 9}, order)
+}
+
+func TestAppContext_Subscribe_EntryRemoved(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.clearObservers()
+
+	removed := make([]Entry, 0)
+	GlobalAppCtx.Subscribe(func(event *LifecycleEvent) {
+		removed = append(removed, event.Entry)
+	}, LifecycleEntryRemoved)
+
+	entry := &EntryMock{Name: "unit-test-entry"}
+
+	// not present
+	GlobalAppCtx.RemoveEntry(entry)
+	assert.Empty(t, removed)
+
+	// removed once
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.RemoveEntry(entry)
+	GlobalAppCtx.RemoveEntry(entry)
+	assert.Equal(t, []Entry{entry}, removed)
+
+	// removed by type
+	removed = removed[:0]
+	other := &EntryMock{Name: "unit-test-entry-other"}
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.AddEntry(other)
+	GlobalAppCtx.RemoveEntryByType(entry.GetType())
+	GlobalAppCtx.RemoveEntryByType(entry.GetType())
+	assert.ElementsMatch(t, []Entry{entry, other}, removed)
+}
+
+func TestAppContext_Subscribe_WithTypes(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.clearObservers()
+
+	events := make([]*LifecycleEvent, 0)
+	GlobalAppCtx.Subscribe(func(event *LifecycleEvent) {
+		events = append(events, event)
+	}, LifecycleEntryAdded)
+
+	entry := &EntryMock{
+		Name: "unit-test-entry",
+	}
+
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.RemoveEntry(entry)
+	GlobalAppCtx.AddValue("key", "value")
+	GlobalAppCtx.ClearValues()
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, LifecycleEntryAdded, events[0].Type)
+}
+
+func TestAppContext_Subscribe_ObserverAddsEntry(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+	defer GlobalAppCtx.clearObservers()
+
+	// observer is allowed to interact with GlobalAppCtx
+	GlobalAppCtx.Subscribe(func(event *LifecycleEvent) {
+		if event.Entry.GetName() == "unit-test-entry" {
+			GlobalAppCtx.AddEntry(&EntryMock{Name: "unit-test-entry-attached"})
+		}
+	}, LifecycleEntryAdded)
+
+	GlobalAppCtx.AddEntry(&EntryMock{Name: "unit-test-entry"})
+	assert.NotNil(t, GlobalAppCtx.GetEntry("mock", "unit-test-entry-attached"))
+}
+
+func TestAppContext_Subscribe_ShutdownStarted(t *testing.T) {
+	defer GlobalAppCtx.clearObservers()
+
+	events := make([]*LifecycleEvent, 0)
+	GlobalAppCtx.Subscribe(func(event *LifecycleEvent) {
+		events = append(events, event)
+	}, LifecycleShutdownStarted)
+
+	go func() {
+		GlobalAppCtx.shutdownSig <- syscall.SIGTERM
+	}()
+	GlobalAppCtx.WaitForShutdownSig()
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, LifecycleShutdownStarted, events[0].Type)
+}
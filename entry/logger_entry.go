@@ -9,11 +9,13 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"github.com/rookie-ninja/rk-logger"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -124,6 +126,16 @@ func RegisterLoggerEntry(boot *BootLogger) []*LoggerEntry {
 			syncers = append(syncers, lokiSyncer)
 		}
 
+		// initial fields are added into core before wrapped, redact them in advance
+		var redactor *logRedactor
+		if logger.Redaction.Enabled {
+			var err error
+			if redactor, err = newLogRedactor(&logger.Redaction); err != nil {
+				ShutdownWithError(err)
+			}
+			zapLoggerConfig.InitialFields = redactor.redactInitialFields(zapLoggerConfig.InitialFields)
+		}
+
 		// Create app logger with config
 		zapLogger, err := rklogger.NewZapLoggerWithConfAndSyncer(zapLoggerConfig, zapLoggerLumberjackConfig, syncers, zap.AddCaller())
 
@@ -131,10 +143,44 @@ func RegisterLoggerEntry(boot *BootLogger) []*LoggerEntry {
 			ShutdownWithError(err)
 		}
 
-		entry.Logger = zapLogger
+		// additional sinks are teed with core of logger, so that sampling and redaction apply to them as well
+		sinks, err := newLogSinks(&logger.Sinks)
+		if err != nil {
+			ShutdownWithError(err)
+		}
+		if len(sinks) > 0 {
+			zapLogger = zapLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return newLogSinkCore(core, sinks, zapLoggerConfig)
+			}))
+		}
+
+		// redaction wraps the core shared by every output, including files and loki
+		if redactor != nil {
+			zapLogger = zapLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return &redactionCore{Core: core, redactor: redactor}
+			}))
+		}
+
+		// sampling and rate limiting rules could be changed at runtime,
+		// dropped lines are reported with logger without sampling
+		entry.sampler = newLogSampler()
+		if err := entry.sampler.setRules(logger.Sampling.Rules, logger.Sampling.RateLimits); err != nil {
+			ShutdownWithError(err)
+		}
+		entry.sampler.interval = time.Duration(logger.Sampling.ReportIntervalMs) * time.Millisecond
+		if logger.Sampling.ReportIntervalMs == 0 {
+			entry.sampler.interval = time.Minute
+		}
+
+		hookedLogger := zapLogger.WithOptions(zap.Hooks(entry.countWrite))
+		entry.sampler.logger = hookedLogger
+		entry.Logger = hookedLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &samplingCore{Core: core, sampler: entry.sampler}
+		}))
 		entry.LoggerConfig = zapLoggerConfig
 		entry.LumberjackConfig = zapLoggerLumberjackConfig
 		entry.lokiSyncer = lokiSyncer
+		entry.sinks = sinks
 
 		GlobalAppCtx.AddEntry(entry)
 		res = append(res, entry)
@@ -173,10 +219,15 @@ type BootLoggerE struct {
 	Zap         *rklogger.ZapConfigWrap `yaml:"zap" json:"zap"`
 	Lumberjack  *lumberjack.Logger      `yaml:"lumberjack" json:"lumberjack"`
 	Loki        BootLoki                `yaml:"loki" json:"loki"`
+	Sampling    BootLoggerSampling      `yaml:"sampling" json:"sampling"`
+	Redaction   BootLoggerRedaction     `yaml:"redaction" json:"redaction"`
+	Sinks       BootLoggerSinks         `yaml:"sinks" json:"sinks"`
 }
 
 // LoggerEntry contains bellow fields.
 type LoggerEntry struct {
+	// writeCounts is accessed atomically and kept as the first field for 64-bit alignment on 32-bit platforms
+	writeCounts [zapcore.FatalLevel - zapcore.DebugLevel + 1]uint64 `yaml:"-" json:"-"`
 	*zap.Logger
 	entryName        string               `yaml:"-" json:"-"`
 	entryType        string               `yaml:"-" json:"-"`
@@ -185,6 +236,8 @@ type LoggerEntry struct {
 	LoggerConfig     *zap.Config          `yaml:"-" json:"-"`
 	LumberjackConfig *lumberjack.Logger   `yaml:"-" json:"-"`
 	lokiSyncer       *rklogger.LokiSyncer `yaml:"-" json:"-"`
+	sampler          *logSampler          `yaml:"-" json:"-"`
+	sinks            []*logSink           `yaml:"-" json:"-"`
 	bootstrapOnce    sync.Once            `yaml:"-" json:"-"`
 }
 
@@ -194,11 +247,27 @@ func (entry *LoggerEntry) Bootstrap(ctx context.Context) {
 		if entry.lokiSyncer != nil {
 			entry.lokiSyncer.Bootstrap(ctx)
 		}
+
+		if entry.sampler != nil {
+			entry.sampler.start()
+		}
+
+		for _, sink := range entry.sinks {
+			sink.start()
+		}
 	})
 }
 
 // Interrupt entry.
 func (entry *LoggerEntry) Interrupt(ctx context.Context) {
+	if entry.sampler != nil {
+		entry.sampler.stop()
+	}
+
+	for _, sink := range entry.sinks {
+		sink.stop()
+	}
+
 	if entry.lokiSyncer != nil {
 		entry.lokiSyncer.Interrupt(ctx)
 	}
@@ -277,3 +346,110 @@ func (entry *LoggerEntry) Sync() {
 		entry.Logger.Sync()
 	}
 }
+
+// WithContext returns logger with trace_id and span_id of active span and request_id in ctx as fields,
+// so that logs could be joined with traces.
+//
+// Logger is returned as it is if there is nothing in ctx.
+func (entry *LoggerEntry) WithContext(ctx context.Context) *zap.Logger {
+	fields := TraceFieldsFromContext(ctx)
+	if len(fields) < 1 {
+		return entry.Logger
+	}
+
+	return entry.Logger.With(fields...)
+}
+
+// WriteCounts returns number of lines written by level since entry registered.
+func (entry *LoggerEntry) WriteCounts() map[string]uint64 {
+	res := make(map[string]uint64)
+
+	for level := zapcore.DebugLevel; level <= zapcore.FatalLevel; level++ {
+		res[level.String()] = atomic.LoadUint64(&entry.writeCounts[level-zapcore.DebugLevel])
+	}
+
+	return res
+}
+
+// countWrite is a zap hook which counts lines by level
+func (entry *LoggerEntry) countWrite(e zapcore.Entry) error {
+	if e.Level >= zapcore.DebugLevel && e.Level <= zapcore.FatalLevel {
+		atomic.AddUint64(&entry.writeCounts[e.Level-zapcore.DebugLevel], 1)
+	}
+
+	return nil
+}
+
+// SetSamplingRules replaces sampling rules at runtime, counters of previous rules are cleared.
+//
+// Rate limit rules are kept as they are.
+func (entry *LoggerEntry) SetSamplingRules(rules ...*LoggerSamplingRule) error {
+	if entry.sampler == nil {
+		return fmt.Errorf("sampling is not supported by logger entry %s", entry.GetName())
+	}
+
+	return entry.sampler.setSamplingRules(rules)
+}
+
+// SetRateLimitRules replaces rate limit rules at runtime, token buckets of previous rules are cleared.
+//
+// Sampling rules are kept as they are.
+func (entry *LoggerEntry) SetRateLimitRules(rules ...*LoggerRateLimitRule) error {
+	if entry.sampler == nil {
+		return fmt.Errorf("rate limiting is not supported by logger entry %s", entry.GetName())
+	}
+
+	return entry.sampler.setRateLimitRules(rules)
+}
+
+// GetSamplingRules returns copy of current sampling rules.
+func (entry *LoggerEntry) GetSamplingRules() []*LoggerSamplingRule {
+	res := make([]*LoggerSamplingRule, 0)
+
+	if entry.sampler != nil {
+		for _, rule := range entry.sampler.getRules().sampling {
+			copied := *rule
+			res = append(res, &copied)
+		}
+	}
+
+	return res
+}
+
+// GetRateLimitRules returns copy of current rate limit rules.
+func (entry *LoggerEntry) GetRateLimitRules() []*LoggerRateLimitRule {
+	res := make([]*LoggerRateLimitRule, 0)
+
+	if entry.sampler != nil {
+		for _, rule := range entry.sampler.getRules().rateLimits {
+			copied := *rule
+			res = append(res, &copied)
+		}
+	}
+
+	return res
+}
+
+// DroppedCounts returns number of lines dropped by sampling, rate limiting and sinks since entry registered,
+// keyed by reason and level.
+func (entry *LoggerEntry) DroppedCounts() map[string]map[string]uint64 {
+	res := map[string]map[string]uint64{}
+
+	if entry.sampler != nil {
+		res = entry.sampler.droppedCounts()
+	}
+
+	// lines dropped by sinks are summed up by reason
+	for _, sink := range entry.sinks {
+		for reason, counts := range sink.droppedCounts() {
+			if _, ok := res[reason]; !ok {
+				res[reason] = make(map[string]uint64)
+			}
+			for level, count := range counts {
+				res[reason][level] += count
+			}
+		}
+	}
+
+	return res
+}
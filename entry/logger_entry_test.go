@@ -59,6 +59,34 @@ func TestRegisterLoggerEntry(t *testing.T) {
 	assert.NotEmpty(t, entries[0].String())
 }
 
+func TestLoggerEntry_WriteCounts(t *testing.T) {
+	entries := RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Zap: &rklogger.ZapConfigWrap{
+					Level:       "info",
+					OutputPaths: []string{"stdout"},
+					Encoding:    "console",
+				},
+			},
+		},
+	})
+	defer GlobalAppCtx.clearEntries()
+
+	entry := entries[0]
+	entry.Debug("msg")
+	entry.Info("msg")
+	entry.Info("msg")
+	entry.Warn("msg")
+
+	counts := entry.WriteCounts()
+	assert.Zero(t, counts["debug"])
+	assert.Equal(t, uint64(2), counts["info"])
+	assert.Equal(t, uint64(1), counts["warn"])
+	assert.Zero(t, counts["error"])
+}
+
 func TestLoggerEntry_UnmarshalJSON(t *testing.T) {
 	assert.Nil(t, NewLoggerEntryNoop().UnmarshalJSON(nil))
 }
@@ -0,0 +1,408 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// LoggerRedactPatternCard matches credit card numbers, matches are redacted only if Luhn checksum is valid
+const LoggerRedactPatternCard = `\b(?:4\d{3}|5[1-5]\d{2}|2[2-7]\d{2}|3[47]\d{2}|6(?:011|5\d{2}))[ -]?(?:\d[ -]?){8,11}\d\b`
+
+var (
+	// LoggerRedactKeys fields with key containing any of them as whole segments are redacted, case-insensitive.
+	// Segments are separated by _, -, ., space and camel case, so userPassword and X-Api-Key are redacted
+	// while max_tokens and secretary are not.
+	LoggerRedactKeys = []string{
+		"password",
+		"passwd",
+		"secret",
+		"token",
+		"authorization",
+		"apikey",
+		"api_key",
+		"cookie",
+	}
+
+	// LoggerRedactPatterns values matching any of them are redacted,
+	// the first group is kept if exists and the rest of match is replaced.
+	//
+	// Patterns of rkerror.DefaultRedactPatterns, credit card numbers and email addresses are included.
+	LoggerRedactPatterns = append(append([]string{}, rkerror.DefaultRedactPatterns...),
+		LoggerRedactPatternCard,
+		`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	)
+
+	loggerRedactors     = make(map[string]LoggerRedactor)
+	loggerRedactorsLock sync.RWMutex
+)
+
+// LoggerRedactor redacts field with custom logic, returns field as it is if nothing to redact.
+type LoggerRedactor func(field zapcore.Field) zapcore.Field
+
+// RegisterLoggerRedactor registers LoggerRedactor with name which could be referred in
+// redaction config of LoggerEntry.
+func RegisterLoggerRedactor(name string, redactor LoggerRedactor) {
+	if len(name) < 1 || redactor == nil {
+		return
+	}
+
+	loggerRedactorsLock.Lock()
+	defer loggerRedactorsLock.Unlock()
+
+	loggerRedactors[name] = redactor
+}
+
+// getLoggerRedactor returns registered LoggerRedactor with name
+func getLoggerRedactor(name string) LoggerRedactor {
+	loggerRedactorsLock.RLock()
+	defer loggerRedactorsLock.RUnlock()
+
+	return loggerRedactors[name]
+}
+
+// BootLoggerRedaction bootstrap config of redaction of LoggerEntry.
+// 1: Enabled: Enable redaction of message and fields written to every output of logger.
+// 2: Keys: Keys of fields to redact besides LoggerRedactKeys, field is redacted if key contains any of them as whole segments.
+// 3: Patterns: Regular expressions of values to redact besides LoggerRedactPatterns.
+// 4: Redactors: Names of LoggerRedactor registered with RegisterLoggerRedactor.
+// 5: Replacement: Replacement of redacted values, default is [REDACTED].
+type BootLoggerRedaction struct {
+	Enabled     bool     `yaml:"enabled" json:"enabled"`
+	Keys        []string `yaml:"keys" json:"keys"`
+	Patterns    []string `yaml:"patterns" json:"patterns"`
+	Redactors   []string `yaml:"redactors" json:"redactors"`
+	Replacement string   `yaml:"replacement" json:"replacement"`
+}
+
+// newLogRedactor creates logRedactor with config, error returned if pattern is invalid or redactor is missing
+func newLogRedactor(boot *BootLoggerRedaction) (*logRedactor, error) {
+	res := &logRedactor{
+		keys:        make([]string, 0),
+		patterns:    make([]*logRedactPattern, 0),
+		redactors:   make([]LoggerRedactor, 0),
+		replacement: boot.Replacement,
+	}
+
+	if len(res.replacement) < 1 {
+		res.replacement = rkerror.RedactReplacement
+	}
+
+	for _, key := range append(append([]string{}, LoggerRedactKeys...), boot.Keys...) {
+		if len(key) > 0 {
+			res.keys = append(res.keys, strings.Join(loggerKeySegments(key), ""))
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, LoggerRedactPatterns...), boot.Patterns...) {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		element := &logRedactPattern{regex: regex}
+		if pattern == LoggerRedactPatternCard {
+			element.validate = isLuhnValid
+		}
+		res.patterns = append(res.patterns, element)
+	}
+
+	for _, name := range boot.Redactors {
+		redactor := getLoggerRedactor(name)
+		if redactor == nil {
+			return nil, fmt.Errorf("logger redactor %s is not registered", name)
+		}
+		res.redactors = append(res.redactors, redactor)
+	}
+
+	return res, nil
+}
+
+// logRedactPattern is compiled value pattern, match is redacted only if validate returns true if exists
+type logRedactPattern struct {
+	regex    *regexp.Regexp
+	validate func(match string) bool
+}
+
+// logRedactor redacts message and fields by key, value patterns and custom redactors
+type logRedactor struct {
+	keys        []string
+	patterns    []*logRedactPattern
+	redactors   []LoggerRedactor
+	replacement string
+}
+
+// matchKey returns true if consecutive segments of key equal to any of sensitive keys
+func (r *logRedactor) matchKey(key string) bool {
+	segments := loggerKeySegments(key)
+
+	for i := range segments {
+		joined := ""
+		for j := i; j < len(segments); j++ {
+			joined += segments[j]
+			for _, k := range r.keys {
+				if joined == k {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// redactString applies patterns to value
+func (r *logRedactor) redactString(in string) string {
+	for _, pattern := range r.patterns {
+		switch {
+		case pattern.validate != nil:
+			in = pattern.regex.ReplaceAllStringFunc(in, func(match string) string {
+				if pattern.validate(match) {
+					return r.replacement
+				}
+				return match
+			})
+		case pattern.regex.NumSubexp() > 0:
+			in = pattern.regex.ReplaceAllString(in, "${1}"+r.replacement)
+		default:
+			in = pattern.regex.ReplaceAllString(in, r.replacement)
+		}
+	}
+
+	return in
+}
+
+// loggerKeySegments splits key into lower case segments by _, -, ., space and camel case,
+// like X-Api_Key into [x api key] and userAPIKey into [user api key]
+func loggerKeySegments(key string) []string {
+	res := make([]string, 0)
+	runes := []rune(key)
+	start := 0
+
+	flush := func(end int) {
+		if end > start {
+			res = append(res, strings.ToLower(string(runes[start:end])))
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			// fooBar before B, or FOOBar before B
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(runes))
+
+	return res
+}
+
+// isLuhnValid returns true if digits in number pass Luhn checksum, separators are ignored
+func isLuhnValid(number string) bool {
+	sum, count := 0, 0
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		digit := int(c - '0')
+		if count%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		count++
+	}
+
+	return count > 0 && sum%10 == 0
+}
+
+// redactValue redacts decoded JSON value recursively, returns true if anything is redacted
+func (r *logRedactor) redactValue(in interface{}) (interface{}, bool) {
+	switch v := in.(type) {
+	case string:
+		res := r.redactString(v)
+		return res, res != v
+	case map[string]interface{}:
+		changed := false
+		for key, value := range v {
+			if r.matchKey(key) {
+				v[key] = r.replacement
+				changed = true
+				continue
+			}
+
+			if redacted, ok := r.redactValue(value); ok {
+				v[key] = redacted
+				changed = true
+			}
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i := range v {
+			if redacted, ok := r.redactValue(v[i]); ok {
+				v[i] = redacted
+				changed = true
+			}
+		}
+		return v, changed
+	}
+
+	return in, false
+}
+
+// redactObject redacts value by converting it into JSON style value, returns true if anything is redacted
+func (r *logRedactor) redactObject(in interface{}) (interface{}, bool) {
+	bytes, err := json.Marshal(in)
+	if err != nil {
+		return in, false
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(bytes, &decoded); err != nil {
+		return in, false
+	}
+
+	return r.redactValue(decoded)
+}
+
+// redactField redacts field by key, then value, custom redactors are applied at last
+func (r *logRedactor) redactField(field zapcore.Field) zapcore.Field {
+	if field.Type != zapcore.NamespaceType && field.Type != zapcore.SkipType && r.matchKey(field.Key) {
+		field = zap.String(field.Key, r.replacement)
+	} else {
+		field = r.redactFieldValue(field)
+	}
+
+	for _, redactor := range r.redactors {
+		field = redactor(field)
+	}
+
+	return field
+}
+
+// redactFieldValue redacts value of string, error, stringer and object fields
+func (r *logRedactor) redactFieldValue(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = r.redactString(field.String)
+	case zapcore.ByteStringType:
+		if bytes, ok := field.Interface.([]byte); ok {
+			if redacted := r.redactString(string(bytes)); redacted != string(bytes) {
+				return zap.ByteString(field.Key, []byte(redacted))
+			}
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			if msg, redacted := err.Error(), r.redactString(err.Error()); redacted != msg {
+				return zap.String(field.Key, redacted)
+			}
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok && stringer != nil {
+			if str, redacted := stringer.String(), r.redactString(stringer.String()); redacted != str {
+				return zap.String(field.Key, redacted)
+			}
+		}
+	case zapcore.ReflectType:
+		if redacted, ok := r.redactObject(field.Interface); ok {
+			return zap.Any(field.Key, redacted)
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		// encode marshaler into map first, since JSON encoding of marshaler is not supported
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		if redacted, ok := r.redactObject(enc.Fields[field.Key]); ok {
+			return zap.Any(field.Key, redacted)
+		}
+	}
+
+	return field
+}
+
+// redactFields returns redacted copy of fields
+func (r *logRedactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	res := make([]zapcore.Field, len(fields))
+	for i := range fields {
+		res[i] = r.redactField(fields[i])
+	}
+
+	return res
+}
+
+// redactInitialFields returns redacted copy of initial fields of zap.Config which are added before cores are wrapped
+func (r *logRedactor) redactInitialFields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+
+	res := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if r.matchKey(key) {
+			res[key] = r.replacement
+			continue
+		}
+
+		res[key] = value
+		if str, ok := value.(string); ok {
+			res[key] = r.redactString(str)
+		} else if redacted, ok := r.redactObject(value); ok {
+			res[key] = redacted
+		}
+	}
+
+	return res
+}
+
+// redactionCore redacts message and fields before writing into underlying core,
+// so that nothing sensitive reaches any WriteSyncer of underlying core.
+type redactionCore struct {
+	zapcore.Core
+	redactor *logRedactor
+}
+
+// With implements zapcore.Core
+func (c *redactionCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactionCore{
+		Core:     c.Core.With(c.redactor.redactFields(fields)),
+		redactor: c.redactor,
+	}
+}
+
+// Check implements zapcore.Core
+func (c *redactionCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core, message, stack trace and fields are redacted
+func (c *redactionCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.redactString(ent.Message)
+	ent.Stack = c.redactor.redactString(ent.Stack)
+
+	return c.Core.Write(ent, c.redactor.redactFields(fields))
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	redactTestPassword = "ut-pass-2c1d"
+	redactTestToken    = "ut-token-8f0a"
+	redactTestCard     = "4111 1111 1111 1111"
+	redactTestEmail    = "ut-user@example.com"
+	redactTestCustom   = "ut-custom-77e1"
+)
+
+var redactTestSecrets = []string{
+	redactTestPassword,
+	redactTestToken,
+	redactTestCard,
+	redactTestEmail,
+	redactTestCustom,
+}
+
+type redactTestUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+func (u *redactTestUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddString("password", u.Password)
+	enc.AddString("email", u.Email)
+	return nil
+}
+
+type redactTestStringer string
+
+func (s redactTestStringer) String() string {
+	return string(s)
+}
+
+// redactTestSyncer records everything written
+type redactTestSyncer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (s *redactTestSyncer) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *redactTestSyncer) Sync() error {
+	return nil
+}
+
+func (s *redactTestSyncer) String() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.buf.String()
+}
+
+func newLogRedactorForTest(t *testing.T) *logRedactor {
+	RegisterLoggerRedactor("ut-redactor", func(field zapcore.Field) zapcore.Field {
+		if field.Type == zapcore.StringType {
+			field.String = strings.ReplaceAll(field.String, redactTestCustom, "***")
+		}
+		return field
+	})
+
+	redactor, err := newLogRedactor(&BootLoggerRedaction{
+		Enabled:   true,
+		Patterns:  []string{`ut-token-[0-9a-f]+`},
+		Redactors: []string{"ut-redactor"},
+	})
+	assert.Nil(t, err)
+
+	return redactor
+}
+
+// logWithSecrets writes secrets in every way supported by redaction
+func logWithSecrets(logger *zap.Logger) {
+	user := &redactTestUser{Name: "ut-user", Password: redactTestPassword, Email: redactTestEmail}
+
+	logger.With(zap.String("Authorization", "Bearer "+redactTestToken)).Info(
+		"login with card "+redactTestCard,
+		zap.String("password", redactTestPassword),
+		zap.String("msg", "sent to "+redactTestEmail),
+		zap.ByteString("raw", []byte("token="+redactTestToken)),
+		zap.Error(errors.New("invalid password="+redactTestPassword)),
+		zap.Stringer("stringer", redactTestStringer(redactTestEmail)),
+		zap.Any("user", user),
+		zap.Object("object", user),
+		zap.Strings("list", []string{redactTestEmail}),
+		zap.String("custom", redactTestCustom))
+}
+
+func assertRedacted(t *testing.T, output string) {
+	assert.NotEmpty(t, output)
+	assert.Contains(t, output, "[REDACTED]")
+	for _, secret := range redactTestSecrets {
+		assert.NotContains(t, output, secret)
+	}
+}
+
+func TestNewLogRedactor(t *testing.T) {
+	// invalid pattern
+	_, err := newLogRedactor(&BootLoggerRedaction{Patterns: []string{"["}})
+	assert.NotNil(t, err)
+
+	// missing redactor
+	_, err = newLogRedactor(&BootLoggerRedaction{Redactors: []string{"ut-missing"}})
+	assert.NotNil(t, err)
+
+	// with default
+	redactor, err := newLogRedactor(&BootLoggerRedaction{Keys: []string{"", "SSN"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "[REDACTED]", redactor.replacement)
+	assert.Len(t, redactor.keys, len(LoggerRedactKeys)+1)
+	assert.Len(t, redactor.patterns, len(LoggerRedactPatterns))
+
+	// custom replacement
+	redactor, err = newLogRedactor(&BootLoggerRedaction{Replacement: "***"})
+	assert.Nil(t, err)
+	assert.Equal(t, "user ***", redactor.redactString("user "+redactTestEmail))
+}
+
+func TestLoggerKeySegments(t *testing.T) {
+	assert.Equal(t, []string{"x", "api", "key"}, loggerKeySegments("X-Api_Key"))
+	assert.Equal(t, []string{"user", "api", "key"}, loggerKeySegments("userAPIKey"))
+	assert.Equal(t, []string{"max", "tokens"}, loggerKeySegments("max_tokens"))
+	assert.Equal(t, []string{"a", "b"}, loggerKeySegments("..a  b.."))
+	assert.Equal(t, []string{"id"}, loggerKeySegments("ID"))
+	assert.Empty(t, loggerKeySegments(""))
+}
+
+func TestIsLuhnValid(t *testing.T) {
+	assert.True(t, isLuhnValid("4111 1111 1111 1111"))
+	assert.True(t, isLuhnValid("5500-0000-0000-0004"))
+	assert.False(t, isLuhnValid("4111 1111 1111 1112"))
+	assert.False(t, isLuhnValid(""))
+}
+
+func TestLogRedactor_RedactString(t *testing.T) {
+	redactor := newLogRedactorForTest(t)
+
+	assert.Equal(t, "password=[REDACTED]", redactor.redactString("password="+redactTestPassword))
+	assert.Equal(t, "Bearer [REDACTED]", redactor.redactString("Bearer abc.def"))
+	assert.Equal(t, "card [REDACTED]", redactor.redactString("card "+redactTestCard))
+	assert.Equal(t, "card [REDACTED]", redactor.redactString("card 5500-0000-0000-0004"))
+	// numbers fail Luhn checksum are kept
+	assert.Equal(t, "order 4111 1111 1111 1112", redactor.redactString("order 4111 1111 1111 1112"))
+	assert.Equal(t, "mail [REDACTED]", redactor.redactString("mail "+redactTestEmail))
+	assert.Equal(t, "[REDACTED]", redactor.redactString(redactTestToken))
+
+	// timestamps and plain text are kept
+	assert.Equal(t, "ts 1650000000000", redactor.redactString("ts 1650000000000"))
+	assert.Equal(t, "plain text", redactor.redactString("plain text"))
+}
+
+func TestLogRedactor_RedactField(t *testing.T) {
+	redactor := newLogRedactorForTest(t)
+
+	// by key, case-insensitive
+	assert.Equal(t, zap.String("userPassword", "[REDACTED]"), redactor.redactField(zap.Int("userPassword", 1234)))
+	assert.Equal(t, zap.String("X-Api_Key", "[REDACTED]"), redactor.redactField(zap.String("X-Api_Key", "value")))
+	assert.Equal(t, zap.Namespace("token"), redactor.redactField(zap.Namespace("token")))
+
+	// by key segments
+	assert.Equal(t, zap.String("accessToken", "[REDACTED]"), redactor.redactField(zap.String("accessToken", "value")))
+	assert.Equal(t, zap.String("userAPIKey", "[REDACTED]"), redactor.redactField(zap.String("userAPIKey", "value")))
+	assert.Equal(t, zap.String("set.cookie", "[REDACTED]"), redactor.redactField(zap.String("set.cookie", "value")))
+	assert.Equal(t, zap.Int("max_tokens", 1024), redactor.redactField(zap.Int("max_tokens", 1024)))
+	assert.Equal(t, zap.String("secretary", "ut-user"), redactor.redactField(zap.String("secretary", "ut-user")))
+	assert.Equal(t, zap.String("passwordless", "true"), redactor.redactField(zap.String("passwordless", "true")))
+
+	// by value
+	assert.Equal(t, zap.String("key", "[REDACTED]"), redactor.redactField(zap.String("key", redactTestEmail)))
+	assert.Equal(t, zap.ByteString("key", []byte("[REDACTED]")), redactor.redactField(zap.ByteString("key", []byte(redactTestEmail))))
+	assert.Equal(t, zap.String("error", "[REDACTED]"), redactor.redactField(zap.Error(errors.New(redactTestEmail))))
+	assert.Equal(t, zap.String("key", "[REDACTED]"), redactor.redactField(zap.Stringer("key", redactTestStringer(redactTestEmail))))
+
+	user := &redactTestUser{Name: "ut-user", Password: redactTestPassword, Email: redactTestEmail}
+	expected := zap.Any("key", map[string]interface{}{"name": "ut-user", "password": "[REDACTED]", "email": "[REDACTED]"})
+	assert.Equal(t, expected, redactor.redactField(zap.Any("key", user)))
+	assert.Equal(t, expected, redactor.redactField(zap.Object("key", user)))
+	assert.Equal(t, zap.Any("key", []interface{}{"[REDACTED]", "value"}), redactor.redactField(zap.Strings("key", []string{redactTestEmail, "value"})))
+
+	// custom redactor
+	assert.Equal(t, zap.String("key", "***"), redactor.redactField(zap.String("key", redactTestCustom)))
+
+	// nothing to redact
+	assert.Equal(t, zap.Int("key", 1), redactor.redactField(zap.Int("key", 1)))
+	assert.Equal(t, zap.Any("key", user.Name), redactor.redactField(zap.Any("key", user.Name)))
+	err := errors.New("ut-error")
+	assert.Equal(t, zap.Error(err), redactor.redactField(zap.Error(err)))
+}
+
+func TestLogRedactor_RedactInitialFields(t *testing.T) {
+	redactor := newLogRedactorForTest(t)
+
+	assert.Nil(t, redactor.redactInitialFields(nil))
+
+	fields := map[string]interface{}{
+		"token":   redactTestToken,
+		"contact": redactTestEmail,
+		"nested":  map[string]interface{}{"secret": "value"},
+		"port":    8080,
+	}
+	res := redactor.redactInitialFields(fields)
+	assert.Equal(t, map[string]interface{}{
+		"token":   "[REDACTED]",
+		"contact": "[REDACTED]",
+		"nested":  map[string]interface{}{"secret": "[REDACTED]"},
+		"port":    8080,
+	}, res)
+
+	// original is kept
+	assert.Equal(t, redactTestToken, fields["token"])
+}
+
+func TestRedactionCore(t *testing.T) {
+	syncer := &redactTestSyncer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), syncer, zap.InfoLevel)
+	logger := zap.New(&redactionCore{Core: core, redactor: newLogRedactorForTest(t)})
+
+	logWithSecrets(logger)
+	logger.Debug(redactTestEmail)
+
+	assert.Equal(t, 1, strings.Count(syncer.String(), "\n"))
+	assertRedacted(t, syncer.String())
+	assert.Contains(t, syncer.String(), "ut-user")
+}
+
+func TestRedactionCore_Write(t *testing.T) {
+	syncer := &redactTestSyncer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), syncer, zap.InfoLevel)
+	redaction := &redactionCore{Core: core, redactor: newLogRedactorForTest(t)}
+
+	// stack trace is redacted
+	assert.Nil(t, redaction.Write(zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Message: "ut-message",
+		Stack:   "panic: password=" + redactTestPassword + "\nmain.main()",
+	}, nil))
+	assert.Contains(t, syncer.String(), "main.main()")
+	assert.NotContains(t, syncer.String(), redactTestPassword)
+
+	// error of underlying core is returned
+	failed := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&redactTestFailedWriter{}), zap.InfoLevel)
+	redaction = &redactionCore{Core: failed, redactor: newLogRedactorForTest(t)}
+	assert.NotNil(t, redaction.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "ut-message"}, nil))
+}
+
+type redactTestFailedWriter struct{}
+
+func (w *redactTestFailedWriter) Write([]byte) (int, error) {
+	return 0, errors.New("ut-error")
+}
+
+func TestLoggerEntry_Redaction(t *testing.T) {
+	// loki
+	lokiLock := sync.Mutex{}
+	lokiBody := bytes.Buffer{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		lokiLock.Lock()
+		lokiBody.Write(body)
+		lokiLock.Unlock()
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// lumberjack
+	filePath := path.Join(t.TempDir(), "ut.log")
+
+	RegisterLoggerRedactor("ut-redactor", func(field zapcore.Field) zapcore.Field {
+		if field.Type == zapcore.StringType {
+			field.String = strings.ReplaceAll(field.String, redactTestCustom, "***")
+		}
+		return field
+	})
+
+	entries := RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Zap: &rklogger.ZapConfigWrap{
+					Level:         "info",
+					OutputPaths:   []string{filePath},
+					Encoding:      "json",
+					InitialFields: map[string]interface{}{"apiKey": redactTestToken},
+				},
+				Loki: BootLoki{
+					Enabled: true,
+					Addr:    strings.TrimPrefix(server.URL, "http://"),
+				},
+				Redaction: BootLoggerRedaction{
+					Enabled:   true,
+					Patterns:  []string{`ut-token-[0-9a-f]+`},
+					Redactors: []string{"ut-redactor"},
+				},
+			},
+		},
+	})
+	defer GlobalAppCtx.clearEntries()
+
+	entry := entries[0]
+	entry.Bootstrap(context.TODO())
+
+	logWithSecrets(entry.Logger)
+	entry.Sugar().Infow("login", "password", redactTestPassword)
+	entry.Interrupt(context.TODO())
+
+	// file
+	bytes, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assertRedacted(t, string(bytes))
+	assert.Equal(t, 2, strings.Count(string(bytes), "\n"))
+
+	// loki
+	lokiLock.Lock()
+	defer lokiLock.Unlock()
+	assertRedacted(t, lokiBody.String())
+}
+
+func TestRegisterLoggerEntry_InvalidRedaction(t *testing.T) {
+	defer assertPanic(t)
+	defer GlobalAppCtx.clearEntries()
+
+	RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Redaction: BootLoggerRedaction{
+					Enabled:   true,
+					Redactors: []string{"ut-missing"},
+				},
+			},
+		},
+	})
+}
+
+func TestLoggerEntry_RedactionDisabled(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "ut.log")
+
+	entries := RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Zap: &rklogger.ZapConfigWrap{
+					OutputPaths: []string{filePath},
+				},
+			},
+		},
+	})
+	defer GlobalAppCtx.clearEntries()
+
+	entries[0].Info("ut-message", zap.String("password", redactTestPassword))
+	bytes, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(bytes), redactTestPassword)
+}
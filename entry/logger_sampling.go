@@ -0,0 +1,524 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// LoggerDropReasonSampled line dropped by sampling rule
+	LoggerDropReasonSampled = "sampled"
+	// LoggerDropReasonRateLimited line dropped by rate limit rule
+	LoggerDropReasonRateLimited = "rateLimited"
+
+	// max number of sampling counters and token buckets, all of them are cleared once exceeded
+	// in order to bound memory used by lines with unique messages or call sites
+	loggerSamplerMaxKeys = 4096
+)
+
+// reasons of dropped lines, index is used in logSampler.dropped
+var loggerDropReasons = []string{LoggerDropReasonSampled, LoggerDropReasonRateLimited}
+
+// BootLoggerSampling bootstrap config of sampling and rate limiting of LoggerEntry.
+// 1: Rules: Sampling rules, first matching rule is applied.
+// 2: RateLimits: Rate limit rules, first matching rule is applied.
+// 3: ReportIntervalMs: Interval of logging number of dropped lines, default is 60000 milliseconds, negative value disables it.
+//
+// Lines above error level are never dropped.
+type BootLoggerSampling struct {
+	Rules            []*LoggerSamplingRule  `yaml:"rules" json:"rules"`
+	RateLimits       []*LoggerRateLimitRule `yaml:"rateLimits" json:"rateLimits"`
+	ReportIntervalMs int64                  `yaml:"reportIntervalMs" json:"reportIntervalMs"`
+}
+
+// LoggerSamplingRule samples lines with the same level and message in each tick,
+// first lines are logged and every Nth line thereafter, others are dropped.
+// 1: Level: Level of lines, empty matches all levels.
+// 2: Message: Regular expression of message, empty matches all messages.
+// 3: First: Number of lines logged at the beginning of each tick.
+// 4: Thereafter: Log every Nth line after first lines, 0 drops all of them.
+// 5: TickMs: Length of tick, default is 1000 milliseconds.
+type LoggerSamplingRule struct {
+	Level      string `yaml:"level" json:"level"`
+	Message    string `yaml:"message" json:"message"`
+	First      int    `yaml:"first" json:"first"`
+	Thereafter int    `yaml:"thereafter" json:"thereafter"`
+	TickMs     int64  `yaml:"tickMs" json:"tickMs"`
+}
+
+// LoggerRateLimitRule limits lines of each call site with token bucket.
+// 1: Level: Level of lines, empty matches all levels.
+// 2: Message: Regular expression of message, empty matches all messages.
+// 3: Caller: Regular expression of call site like service/user.go:42, empty matches all call sites.
+// 4: Rate: Number of lines allowed per second of each call site.
+// 5: Burst: Max number of lines allowed at once of each call site, default is 1.
+//
+// Lines are keyed by message if caller is not added to logger.
+type LoggerRateLimitRule struct {
+	Level   string  `yaml:"level" json:"level"`
+	Message string  `yaml:"message" json:"message"`
+	Caller  string  `yaml:"caller" json:"caller"`
+	Rate    float64 `yaml:"rate" json:"rate"`
+	Burst   int     `yaml:"burst" json:"burst"`
+}
+
+// loggerMatcher matches level, message and caller of zapcore.Entry
+type loggerMatcher struct {
+	level   *zapcore.Level
+	message *regexp.Regexp
+	caller  *regexp.Regexp
+}
+
+func newLoggerMatcher(level, message, caller string) (*loggerMatcher, error) {
+	res := &loggerMatcher{}
+
+	if len(level) > 0 {
+		lvl := zapcore.InfoLevel
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, err
+		}
+		res.level = &lvl
+	}
+
+	var err error
+	if len(message) > 0 {
+		if res.message, err = regexp.Compile(message); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(caller) > 0 {
+		if res.caller, err = regexp.Compile(caller); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (m *loggerMatcher) match(ent zapcore.Entry) bool {
+	if m.level != nil && *m.level != ent.Level {
+		return false
+	}
+
+	if m.message != nil && !m.message.MatchString(ent.Message) {
+		return false
+	}
+
+	if m.caller != nil && !m.caller.MatchString(ent.Caller.TrimmedPath()) {
+		return false
+	}
+
+	return true
+}
+
+// logSamplerRules compiled rules, replaced as a whole while changing rules at runtime
+type logSamplerRules struct {
+	sampling         []*LoggerSamplingRule
+	rateLimits       []*LoggerRateLimitRule
+	samplingMatchers []*loggerMatcher
+	rateMatchers     []*loggerMatcher
+}
+
+// sampleCounter counts lines in current tick
+type sampleCounter struct {
+	resetAt time.Time
+	count   uint64
+}
+
+// tokenBucket of call site
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newLogSampler creates logSampler without rules
+func newLogSampler() *logSampler {
+	res := &logSampler{
+		counters: make(map[string]*sampleCounter),
+		buckets:  make(map[string]*tokenBucket),
+		now:      time.Now,
+	}
+	res.rules.Store(&logSamplerRules{})
+
+	return res
+}
+
+// logSampler drops lines by sampling rules and rate limit rules and counts dropped lines.
+type logSampler struct {
+	// dropped is accessed atomically and kept as the first field for 64-bit alignment on 32-bit platforms
+	dropped     [2][zapcore.FatalLevel - zapcore.DebugLevel + 1]uint64
+	reported    [2][zapcore.FatalLevel - zapcore.DebugLevel + 1]uint64
+	rules       atomic.Value
+	lock        sync.Mutex
+	counters    map[string]*sampleCounter
+	buckets     map[string]*tokenBucket
+	now         func() time.Time
+	logger      *zap.Logger
+	interval    time.Duration
+	quitChannel chan struct{}
+	waitGroup   sync.WaitGroup
+}
+
+// setRules validates and replaces rules, counters and buckets of previous rules are cleared
+func (s *logSampler) setRules(sampling []*LoggerSamplingRule, rateLimits []*LoggerRateLimitRule) error {
+	res := &logSamplerRules{}
+	if err := res.compileSampling(sampling); err != nil {
+		return err
+	}
+	if err := res.compileRateLimits(rateLimits); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.storeRules(res)
+	return nil
+}
+
+// setSamplingRules validates and replaces sampling rules, current rate limit rules are kept.
+// Current rules are read and replaced under lock, so that concurrent setters would not overwrite each other.
+func (s *logSampler) setSamplingRules(sampling []*LoggerSamplingRule) error {
+	res := &logSamplerRules{}
+	if err := res.compileSampling(sampling); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current := s.getRules()
+	res.rateLimits, res.rateMatchers = current.rateLimits, current.rateMatchers
+	s.storeRules(res)
+	return nil
+}
+
+// setRateLimitRules validates and replaces rate limit rules, current sampling rules are kept.
+// Current rules are read and replaced under lock, so that concurrent setters would not overwrite each other.
+func (s *logSampler) setRateLimitRules(rateLimits []*LoggerRateLimitRule) error {
+	res := &logSamplerRules{}
+	if err := res.compileRateLimits(rateLimits); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current := s.getRules()
+	res.sampling, res.samplingMatchers = current.sampling, current.samplingMatchers
+	s.storeRules(res)
+	return nil
+}
+
+// storeRules replaces rules and clears counters and buckets, lock should be held by caller
+func (s *logSampler) storeRules(rules *logSamplerRules) {
+	s.rules.Store(rules)
+	s.counters = make(map[string]*sampleCounter)
+	s.buckets = make(map[string]*tokenBucket)
+}
+
+// compileSampling validates sampling rules and creates matchers
+func (r *logSamplerRules) compileSampling(rules []*LoggerSamplingRule) error {
+	r.sampling = make([]*LoggerSamplingRule, 0)
+	r.samplingMatchers = make([]*loggerMatcher, 0)
+
+	for _, rule := range rules {
+		if rule == nil {
+			continue
+		}
+
+		if rule.First < 0 || rule.Thereafter < 0 {
+			return fmt.Errorf("first and thereafter of sampling rule should not be negative")
+		}
+
+		matcher, err := newLoggerMatcher(rule.Level, rule.Message, "")
+		if err != nil {
+			return err
+		}
+
+		copied := *rule
+		if copied.TickMs <= 0 {
+			copied.TickMs = 1000
+		}
+
+		r.sampling = append(r.sampling, &copied)
+		r.samplingMatchers = append(r.samplingMatchers, matcher)
+	}
+
+	return nil
+}
+
+// compileRateLimits validates rate limit rules and creates matchers
+func (r *logSamplerRules) compileRateLimits(rules []*LoggerRateLimitRule) error {
+	r.rateLimits = make([]*LoggerRateLimitRule, 0)
+	r.rateMatchers = make([]*loggerMatcher, 0)
+
+	for _, rule := range rules {
+		if rule == nil {
+			continue
+		}
+
+		if rule.Rate <= 0 {
+			return fmt.Errorf("rate of rate limit rule should be positive")
+		}
+
+		matcher, err := newLoggerMatcher(rule.Level, rule.Message, rule.Caller)
+		if err != nil {
+			return err
+		}
+
+		copied := *rule
+		if copied.Burst < 1 {
+			copied.Burst = 1
+		}
+
+		r.rateLimits = append(r.rateLimits, &copied)
+		r.rateMatchers = append(r.rateMatchers, matcher)
+	}
+
+	return nil
+}
+
+// getRules returns current rules
+func (s *logSampler) getRules() *logSamplerRules {
+	return s.rules.Load().(*logSamplerRules)
+}
+
+// enabled returns true if there is any rule
+func (s *logSampler) enabled() bool {
+	rules := s.getRules()
+	return len(rules.sampling) > 0 || len(rules.rateLimits) > 0
+}
+
+// allow returns false and counts dropped line if line should be dropped
+func (s *logSampler) allow(ent zapcore.Entry) bool {
+	if ent.Level < zapcore.DebugLevel || ent.Level > zapcore.ErrorLevel {
+		return true
+	}
+
+	rules := s.getRules()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+
+	for i, matcher := range rules.samplingMatchers {
+		if !matcher.match(ent) {
+			continue
+		}
+
+		if !s.sample(i, rules.sampling[i], ent, now) {
+			atomic.AddUint64(&s.dropped[0][ent.Level-zapcore.DebugLevel], 1)
+			return false
+		}
+		break
+	}
+
+	for i, matcher := range rules.rateMatchers {
+		if !matcher.match(ent) {
+			continue
+		}
+
+		if !s.take(i, rules.rateLimits[i], ent, now) {
+			atomic.AddUint64(&s.dropped[1][ent.Level-zapcore.DebugLevel], 1)
+			return false
+		}
+		break
+	}
+
+	return true
+}
+
+// sample counts line with the same level and message in current tick
+func (s *logSampler) sample(index int, rule *LoggerSamplingRule, ent zapcore.Entry, now time.Time) bool {
+	key := strconv.Itoa(index) + "/" + ent.Level.String() + "/" + ent.Message
+
+	counter, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= loggerSamplerMaxKeys {
+			s.counters = make(map[string]*sampleCounter)
+		}
+		counter = &sampleCounter{}
+		s.counters[key] = counter
+	}
+
+	if !now.Before(counter.resetAt) {
+		counter.count = 0
+		counter.resetAt = now.Add(time.Duration(rule.TickMs) * time.Millisecond)
+	}
+	counter.count++
+
+	if counter.count <= uint64(rule.First) {
+		return true
+	}
+
+	return rule.Thereafter > 0 && (counter.count-uint64(rule.First))%uint64(rule.Thereafter) == 0
+}
+
+// take takes a token from bucket of call site
+func (s *logSampler) take(index int, rule *LoggerRateLimitRule, ent zapcore.Entry, now time.Time) bool {
+	site := ent.Message
+	if ent.Caller.Defined {
+		site = ent.Caller.String()
+	}
+	key := strconv.Itoa(index) + "/" + site
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= loggerSamplerMaxKeys {
+			s.buckets = make(map[string]*tokenBucket)
+		}
+		bucket = &tokenBucket{tokens: float64(rule.Burst), last: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(rule.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rule.Rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+	return true
+}
+
+// droppedCounts returns number of dropped lines by reason and level
+func (s *logSampler) droppedCounts() map[string]map[string]uint64 {
+	res := make(map[string]map[string]uint64)
+
+	for i, reason := range loggerDropReasons {
+		res[reason] = make(map[string]uint64)
+		for level := zapcore.DebugLevel; level <= zapcore.FatalLevel; level++ {
+			res[reason][level.String()] = atomic.LoadUint64(&s.dropped[i][level-zapcore.DebugLevel])
+		}
+	}
+
+	return res
+}
+
+// report logs number of lines dropped since last report, nothing logged if no line dropped
+func (s *logSampler) report() {
+	fields := make([]zap.Field, 0)
+
+	for i, reason := range loggerDropReasons {
+		counts := make(map[string]uint64)
+		for level := zapcore.DebugLevel; level <= zapcore.FatalLevel; level++ {
+			current := atomic.LoadUint64(&s.dropped[i][level-zapcore.DebugLevel])
+			if delta := current - s.reported[i][level-zapcore.DebugLevel]; delta > 0 {
+				counts[level.String()] = delta
+			}
+			s.reported[i][level-zapcore.DebugLevel] = current
+		}
+
+		if len(counts) > 0 {
+			fields = append(fields, zap.Any(reason, counts))
+		}
+	}
+
+	if len(fields) > 0 && s.logger != nil {
+		s.logger.Warn("Dropped log lines by sampling and rate limiting", fields...)
+	}
+}
+
+// start reporting dropped lines periodically, noop if interval is not positive or already started
+func (s *logSampler) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.interval <= 0 || s.quitChannel != nil {
+		return
+	}
+
+	s.quitChannel = make(chan struct{})
+	s.waitGroup.Add(1)
+
+	go func(quitChannel chan struct{}) {
+		defer s.waitGroup.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quitChannel:
+				return
+			case <-ticker.C:
+				s.report()
+			}
+		}
+	}(s.quitChannel)
+}
+
+// stop reporting and wait for background goroutine to exit, dropped lines since last report are reported
+func (s *logSampler) stop() {
+	s.lock.Lock()
+	quitChannel := s.quitChannel
+	s.quitChannel = nil
+	s.lock.Unlock()
+
+	if quitChannel == nil {
+		return
+	}
+
+	close(quitChannel)
+	s.waitGroup.Wait()
+	s.report()
+}
+
+// samplingCore drops lines by logSampler before writing into underlying core.
+//
+// Decision is made in Write instead of Check since caller is not available in Check.
+type samplingCore struct {
+	zapcore.Core
+	sampler *logSampler
+}
+
+// With implements zapcore.Core
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:    c.Core.With(fields),
+		sampler: c.sampler,
+	}
+}
+
+// Check implements zapcore.Core
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.sampler.enabled() {
+		return c.Core.Check(ent, ce)
+	}
+
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core
+func (c *samplingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.sampler.allow(ent) {
+		return nil
+	}
+
+	// check with underlying core again, so that cores with different levels in tee are respected
+	if checked := c.Core.Check(ent, nil); checked != nil {
+		checked.Write(fields...)
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newLogSamplerForTest(t *testing.T, sampling []*LoggerSamplingRule, rateLimits []*LoggerRateLimitRule) (*logSampler, *time.Time) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	sampler := newLogSampler()
+	sampler.now = func() time.Time {
+		return now
+	}
+	assert.Nil(t, sampler.setRules(sampling, rateLimits))
+
+	return sampler, &now
+}
+
+func TestLogSampler_SetRules(t *testing.T) {
+	sampler := newLogSampler()
+	assert.False(t, sampler.enabled())
+
+	// invalid level
+	assert.NotNil(t, sampler.setRules([]*LoggerSamplingRule{{Level: "invalid"}}, nil))
+
+	// invalid message
+	assert.NotNil(t, sampler.setRules([]*LoggerSamplingRule{{Message: "["}}, nil))
+
+	// negative first
+	assert.NotNil(t, sampler.setRules([]*LoggerSamplingRule{{First: -1}}, nil))
+
+	// invalid caller
+	assert.NotNil(t, sampler.setRules(nil, []*LoggerRateLimitRule{{Caller: "[", Rate: 1}}))
+
+	// zero rate
+	assert.NotNil(t, sampler.setRules(nil, []*LoggerRateLimitRule{{}}))
+	assert.False(t, sampler.enabled())
+
+	// with default
+	assert.Nil(t, sampler.setRules([]*LoggerSamplingRule{nil, {First: 1}}, []*LoggerRateLimitRule{nil, {Rate: 1}}))
+	assert.True(t, sampler.enabled())
+	assert.Len(t, sampler.getRules().sampling, 1)
+	assert.Equal(t, int64(1000), sampler.getRules().sampling[0].TickMs)
+	assert.Len(t, sampler.getRules().rateLimits, 1)
+	assert.Equal(t, 1, sampler.getRules().rateLimits[0].Burst)
+}
+
+func TestLogSampler_Sampling(t *testing.T) {
+	sampler, now := newLogSamplerForTest(t, []*LoggerSamplingRule{
+		{Level: "info", Message: "^noisy", First: 2, Thereafter: 3, TickMs: 1000},
+	}, nil)
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "noisy line"}) {
+			allowed++
+		}
+	}
+	// 1, 2, 5, 8
+	assert.Equal(t, 4, allowed)
+	assert.Equal(t, uint64(6), sampler.droppedCounts()[LoggerDropReasonSampled]["info"])
+
+	// other level and message are not sampled
+	assert.True(t, sampler.allow(zapcore.Entry{Level: zapcore.WarnLevel, Message: "noisy line"}))
+	assert.True(t, sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "other line"}))
+
+	// counter is reset in next tick
+	*now = now.Add(time.Second)
+	assert.True(t, sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "noisy line"}))
+
+	// lines above error are never dropped
+	sampler, _ = newLogSamplerForTest(t, []*LoggerSamplingRule{{}}, nil)
+	assert.False(t, sampler.allow(zapcore.Entry{Level: zapcore.ErrorLevel}))
+	assert.True(t, sampler.allow(zapcore.Entry{Level: zapcore.DPanicLevel}))
+}
+
+func TestLogSampler_RateLimit(t *testing.T) {
+	sampler, now := newLogSamplerForTest(t, nil, []*LoggerRateLimitRule{
+		{Caller: "service.go", Rate: 2, Burst: 2},
+	})
+
+	siteA := zapcore.Entry{Level: zapcore.InfoLevel, Caller: zapcore.NewEntryCaller(0, "/ut/service.go", 10, true)}
+	siteB := zapcore.Entry{Level: zapcore.InfoLevel, Caller: zapcore.NewEntryCaller(0, "/ut/service.go", 20, true)}
+	other := zapcore.Entry{Level: zapcore.InfoLevel, Caller: zapcore.NewEntryCaller(0, "/ut/other.go", 10, true)}
+
+	// burst
+	assert.True(t, sampler.allow(siteA))
+	assert.True(t, sampler.allow(siteA))
+	assert.False(t, sampler.allow(siteA))
+
+	// buckets are per call site
+	assert.True(t, sampler.allow(siteB))
+
+	// not matched
+	for i := 0; i < 5; i++ {
+		assert.True(t, sampler.allow(other))
+	}
+
+	// refilled
+	*now = now.Add(500 * time.Millisecond)
+	assert.True(t, sampler.allow(siteA))
+	assert.False(t, sampler.allow(siteA))
+
+	assert.Equal(t, uint64(2), sampler.droppedCounts()[LoggerDropReasonRateLimited]["info"])
+	assert.Zero(t, sampler.droppedCounts()[LoggerDropReasonSampled]["info"])
+}
+
+func TestLogSampler_Report(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	sampler, _ := newLogSamplerForTest(t, []*LoggerSamplingRule{{Level: "info", First: 1}}, nil)
+	sampler.logger = zap.New(core)
+
+	// nothing dropped
+	sampler.report()
+	assert.Zero(t, logs.Len())
+
+	sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel})
+	sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel})
+	sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel})
+	sampler.report()
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]uint64{"info": 2}, logs.All()[0].ContextMap()[LoggerDropReasonSampled])
+
+	// only delta is reported
+	sampler.report()
+	assert.Equal(t, 1, logs.Len())
+
+	// report periodically
+	sampler.interval = 10 * time.Millisecond
+	sampler.start()
+	sampler.start()
+	sampler.allow(zapcore.Entry{Level: zapcore.InfoLevel})
+	sampler.stop()
+	sampler.stop()
+	assert.Equal(t, 2, logs.Len())
+}
+
+func TestSamplingCore(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	sampler := newLogSampler()
+	logger := zap.New(&samplingCore{Core: core, sampler: sampler}, zap.AddCaller())
+
+	// no rule
+	logger.Info("line")
+	logger.Info("line")
+	logger.Debug("line")
+	assert.Equal(t, 2, logs.Len())
+
+	// rate limited by call site, fields of With are kept
+	assert.Nil(t, sampler.setRules(nil, []*LoggerRateLimitRule{{Caller: "logger_sampling_test.go", Rate: 0.001}}))
+	child := logger.With(zap.String("key", "value"))
+	for i := 0; i < 3; i++ {
+		child.Info("line")
+	}
+	child.Debug("line")
+	assert.Equal(t, 3, logs.Len())
+	assert.Equal(t, "value", logs.All()[2].ContextMap()["key"])
+}
+
+func TestLoggerEntry_Sampling(t *testing.T) {
+	entries := RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Zap: &rklogger.ZapConfigWrap{
+					Level:       "info",
+					OutputPaths: []string{"stdout"},
+					Encoding:    "console",
+				},
+				Sampling: BootLoggerSampling{
+					Rules: []*LoggerSamplingRule{
+						{Level: "info", Message: "noisy", First: 1},
+					},
+					ReportIntervalMs: -1,
+				},
+			},
+		},
+	})
+	defer GlobalAppCtx.clearEntries()
+
+	entry := entries[0]
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+
+	assert.Len(t, entry.GetSamplingRules(), 1)
+	assert.Empty(t, entry.GetRateLimitRules())
+
+	for i := 0; i < 3; i++ {
+		entry.Info("noisy")
+	}
+	assert.Equal(t, uint64(1), entry.WriteCounts()["info"])
+	assert.Equal(t, uint64(2), entry.DroppedCounts()[LoggerDropReasonSampled]["info"])
+
+	// change rules at runtime
+	assert.Nil(t, entry.SetRateLimitRules(&LoggerRateLimitRule{Level: "warn", Rate: 0.001}))
+	assert.Len(t, entry.GetSamplingRules(), 1)
+	assert.Len(t, entry.GetRateLimitRules(), 1)
+
+	assert.Nil(t, entry.SetSamplingRules())
+	assert.Empty(t, entry.GetSamplingRules())
+	assert.Len(t, entry.GetRateLimitRules(), 1)
+
+	entry.Info("noisy")
+	for i := 0; i < 2; i++ {
+		entry.Warn("noisy")
+	}
+	assert.Equal(t, uint64(2), entry.WriteCounts()["info"])
+	assert.Equal(t, uint64(1), entry.WriteCounts()["warn"])
+	assert.Equal(t, uint64(1), entry.DroppedCounts()[LoggerDropReasonRateLimited]["warn"])
+
+	// invalid rules are rejected and current rules are kept
+	assert.NotNil(t, entry.SetRateLimitRules(&LoggerRateLimitRule{}))
+	assert.Len(t, entry.GetRateLimitRules(), 1)
+
+	// not supported
+	assert.NotNil(t, NewLoggerEntryNoop().SetSamplingRules())
+	assert.NotNil(t, NewLoggerEntryNoop().SetRateLimitRules())
+	assert.Empty(t, NewLoggerEntryNoop().GetSamplingRules())
+	assert.Empty(t, NewLoggerEntryNoop().GetRateLimitRules())
+	assert.Empty(t, NewLoggerEntryNoop().DroppedCounts())
+}
+
+func TestRegisterLoggerEntry_InvalidSampling(t *testing.T) {
+	defer assertPanic(t)
+	defer GlobalAppCtx.clearEntries()
+
+	RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Sampling: BootLoggerSampling{
+					RateLimits: []*LoggerRateLimitRule{{Rate: -1}},
+				},
+			},
+		},
+	})
+}
+
+func TestLoggerEntry_SetRules_Concurrently(t *testing.T) {
+	sampler := newLogSampler()
+	entry := &LoggerEntry{sampler: sampler}
+
+	// sampling rules and rate limit rules are changed at the same time, neither of them should be lost
+	for i := 0; i < 100; i++ {
+		wait := sync.WaitGroup{}
+		wait.Add(2)
+		start := make(chan struct{})
+
+		go func() {
+			defer wait.Done()
+			<-start
+			assert.Nil(t, entry.SetSamplingRules(&LoggerSamplingRule{First: i}))
+		}()
+
+		go func() {
+			defer wait.Done()
+			<-start
+			assert.Nil(t, entry.SetRateLimitRules(&LoggerRateLimitRule{Rate: float64(i + 1)}))
+		}()
+
+		close(start)
+		wait.Wait()
+
+		rules := sampler.getRules()
+		assert.Len(t, rules.sampling, 1)
+		assert.Equal(t, i, rules.sampling[0].First)
+		assert.Len(t, rules.rateLimits, 1)
+		assert.Equal(t, float64(i+1), rules.rateLimits[0].Rate)
+		assert.Len(t, rules.samplingMatchers, 1)
+		assert.Len(t, rules.rateMatchers, 1)
+	}
+}
@@ -0,0 +1,485 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bytes"
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// LoggerDropReasonSinkFull line dropped since queue of sink is full
+	LoggerDropReasonSinkFull = "sinkFull"
+	// LoggerDropReasonSinkFailed line dropped since sink failed to deliver it after retries
+	LoggerDropReasonSinkFailed = "sinkFailed"
+	// LoggerDropReasonSinkStopped line dropped since sink was stopped
+	LoggerDropReasonSinkStopped = "sinkStopped"
+
+	// LoggerSinkOnFullDrop drops new lines if queue of sink is full
+	LoggerSinkOnFullDrop = "drop"
+	// LoggerSinkOnFullBlock blocks writer until queue of sink has space or timed out
+	LoggerSinkOnFullBlock = "block"
+)
+
+// reasons of lines dropped by sink, index is used in logSink.dropped
+var loggerSinkDropReasons = []string{LoggerDropReasonSinkFull, LoggerDropReasonSinkFailed, LoggerDropReasonSinkStopped}
+
+// BootLoggerSinks bootstrap config of additional outputs of LoggerEntry.
+// 1: Syslog: RFC 5424 syslog over udp, tcp or unix socket.
+// 2: Journald: Native protocol of systemd-journald over its unix socket.
+// 3: Http: Batching HTTP sink with generic JSON, Elasticsearch bulk, Splunk HEC or Datadog format.
+//
+// Sinks share level, encoder config, initial fields, sampling and redaction with other outputs of logger.
+type BootLoggerSinks struct {
+	Syslog   BootLoggerSyslog   `yaml:"syslog" json:"syslog"`
+	Journald BootLoggerJournald `yaml:"journald" json:"journald"`
+	Http     BootLoggerHttpSink `yaml:"http" json:"http"`
+}
+
+// BootLoggerSinkDelivery bootstrap config of buffering, back-pressure and retry of sink.
+// 1: QueueSize: Max number of lines buffered in memory, default is 1024.
+// 2: OnFull: Behavior if queue is full, drop or block, default is drop.
+// 3: BlockTimeoutMs: Max time writer blocked if OnFull is block, lines are dropped after it, default is 1000 milliseconds.
+// 4: BatchSize: Max number of lines sent at once, default is 100.
+// 5: FlushIntervalMs: Interval of sending buffered lines, default is 1000 milliseconds.
+// 6: MaxRetries: Max number of retries of failed lines, default is 3, negative value disables retry.
+// 7: RetryBackoffMs: Initial wait before retry which doubles for each retry, default is 100 milliseconds.
+// 8: MaxRetryBackoffMs: Max wait before retry, default is 5000 milliseconds.
+// 9: TimeoutMs: Timeout of connecting and sending, default is 5000 milliseconds.
+type BootLoggerSinkDelivery struct {
+	QueueSize         int    `yaml:"queueSize" json:"queueSize"`
+	OnFull            string `yaml:"onFull" json:"onFull"`
+	BlockTimeoutMs    int64  `yaml:"blockTimeoutMs" json:"blockTimeoutMs"`
+	BatchSize         int    `yaml:"batchSize" json:"batchSize"`
+	FlushIntervalMs   int64  `yaml:"flushIntervalMs" json:"flushIntervalMs"`
+	MaxRetries        int    `yaml:"maxRetries" json:"maxRetries"`
+	RetryBackoffMs    int64  `yaml:"retryBackoffMs" json:"retryBackoffMs"`
+	MaxRetryBackoffMs int64  `yaml:"maxRetryBackoffMs" json:"maxRetryBackoffMs"`
+	TimeoutMs         int64  `yaml:"timeoutMs" json:"timeoutMs"`
+}
+
+// timeout returns timeout of connecting and sending with default
+func (d *BootLoggerSinkDelivery) timeout() time.Duration {
+	if d.TimeoutMs <= 0 {
+		return 5 * time.Second
+	}
+
+	return time.Duration(d.TimeoutMs) * time.Millisecond
+}
+
+// logSinkRecord is a line waiting to be sent by sink
+type logSinkRecord struct {
+	entry zapcore.Entry
+	line  []byte
+}
+
+// logSinkSender sends batch of records to destination.
+//
+// Records to retry and records failed permanently are returned, error describes the failure.
+type logSinkSender interface {
+	send(records []*logSinkRecord) (retry []*logSinkRecord, failed []*logSinkRecord, err error)
+	close() error
+}
+
+// newLogSinks creates sinks enabled in config, hostname and app name are used by default in sinks
+func newLogSinks(boot *BootLoggerSinks) ([]*logSink, error) {
+	res := make([]*logSink, 0)
+
+	hostname, _ := os.Hostname()
+	appName := GlobalAppCtx.GetAppInfoEntry().AppName
+
+	if boot.Syslog.Enabled {
+		sender, err := newSyslogSender(&boot.Syslog, hostname, appName)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, newLogSink("syslog", sender, &boot.Syslog.Delivery, false))
+	}
+
+	if boot.Journald.Enabled {
+		res = append(res, newLogSink("journald", newJournaldSender(&boot.Journald, appName), &boot.Journald.Delivery, false))
+	}
+
+	if boot.Http.Enabled {
+		sender, err := newHttpSinkSender(&boot.Http, hostname, appName)
+		if err != nil {
+			return nil, err
+		}
+		// documents of http sink are always encoded as JSON
+		res = append(res, newLogSink("http", sender, &boot.Http.Delivery, true))
+	}
+
+	for _, sink := range res {
+		if sink.onFull != LoggerSinkOnFullDrop && sink.onFull != LoggerSinkOnFullBlock {
+			return nil, fmt.Errorf("invalid onFull %s of %s sink, expect one of [%s, %s]",
+				sink.onFull, sink.name, LoggerSinkOnFullDrop, LoggerSinkOnFullBlock)
+		}
+	}
+
+	return res, nil
+}
+
+// newLogSink creates logSink with delivery config
+func newLogSink(name string, sender logSinkSender, delivery *BootLoggerSinkDelivery, forceJSON bool) *logSink {
+	res := &logSink{
+		name:            name,
+		sender:          sender,
+		forceJSON:       forceJSON,
+		onFull:          delivery.OnFull,
+		blockTimeout:    time.Duration(delivery.BlockTimeoutMs) * time.Millisecond,
+		batchSize:       delivery.BatchSize,
+		flushInterval:   time.Duration(delivery.FlushIntervalMs) * time.Millisecond,
+		maxRetries:      delivery.MaxRetries,
+		retryBackoff:    time.Duration(delivery.RetryBackoffMs) * time.Millisecond,
+		maxRetryBackoff: time.Duration(delivery.MaxRetryBackoffMs) * time.Millisecond,
+		flushChannel:    make(chan chan struct{}),
+		quitChannel:     make(chan struct{}),
+		errorOutput:     zapcore.Lock(os.Stderr),
+		sleep:           time.Sleep,
+	}
+
+	queueSize := delivery.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	res.queue = make(chan *logSinkRecord, queueSize)
+
+	if len(res.onFull) < 1 {
+		res.onFull = LoggerSinkOnFullDrop
+	}
+
+	if res.blockTimeout <= 0 {
+		res.blockTimeout = time.Second
+	}
+
+	if res.batchSize <= 0 {
+		res.batchSize = 100
+	}
+
+	if res.flushInterval <= 0 {
+		res.flushInterval = time.Second
+	}
+
+	if res.maxRetries == 0 {
+		res.maxRetries = 3
+	}
+
+	if res.retryBackoff <= 0 {
+		res.retryBackoff = 100 * time.Millisecond
+	}
+
+	if res.maxRetryBackoff <= 0 {
+		res.maxRetryBackoff = 5 * time.Second
+	}
+
+	return res
+}
+
+// logSink buffers records in queue and sends them with sender in background.
+//
+// Writers are never blocked by slow destination if OnFull is drop, lines are dropped and counted instead.
+type logSink struct {
+	// dropped is accessed atomically and kept as the first field for 64-bit alignment on 32-bit platforms
+	dropped         [3][zapcore.FatalLevel - zapcore.DebugLevel + 1]uint64
+	name            string
+	sender          logSinkSender
+	forceJSON       bool
+	onFull          string
+	blockTimeout    time.Duration
+	batchSize       int
+	flushInterval   time.Duration
+	maxRetries      int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	queue           chan *logSinkRecord
+	flushChannel    chan chan struct{}
+	quitChannel     chan struct{}
+	running         int32
+	startOnce       sync.Once
+	stopOnce        sync.Once
+	waitGroup       sync.WaitGroup
+	errorOutput     zapcore.WriteSyncer
+	closeOutput     func()
+	sleep           func(time.Duration)
+}
+
+// enqueue record, record is dropped if queue is full after waiting by OnFull or sink stopped
+func (s *logSink) enqueue(record *logSinkRecord) {
+	select {
+	case <-s.quitChannel:
+		s.drop(2, record)
+		return
+	default:
+	}
+
+	select {
+	case s.queue <- record:
+		return
+	default:
+	}
+
+	if s.onFull == LoggerSinkOnFullBlock {
+		timer := time.NewTimer(s.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case s.queue <- record:
+			return
+		case <-timer.C:
+		case <-s.quitChannel:
+			s.drop(2, record)
+			return
+		}
+	}
+
+	s.drop(0, record)
+}
+
+// drop counts dropped records with index of reason in loggerSinkDropReasons
+func (s *logSink) drop(reason int, records ...*logSinkRecord) {
+	for _, record := range records {
+		if record.entry.Level >= zapcore.DebugLevel && record.entry.Level <= zapcore.FatalLevel {
+			atomic.AddUint64(&s.dropped[reason][record.entry.Level-zapcore.DebugLevel], 1)
+		}
+	}
+}
+
+// droppedCounts returns number of dropped lines by reason and level
+func (s *logSink) droppedCounts() map[string]map[string]uint64 {
+	res := make(map[string]map[string]uint64)
+
+	for i, reason := range loggerSinkDropReasons {
+		res[reason] = make(map[string]uint64)
+		for level := zapcore.DebugLevel; level <= zapcore.FatalLevel; level++ {
+			res[reason][level.String()] = atomic.LoadUint64(&s.dropped[i][level-zapcore.DebugLevel])
+		}
+	}
+
+	return res
+}
+
+// start sending records in background, noop if already started
+func (s *logSink) start() {
+	s.startOnce.Do(func() {
+		atomic.StoreInt32(&s.running, 1)
+		s.waitGroup.Add(1)
+		go s.run()
+	})
+}
+
+// stop sending records in background, buffered records are sent before sender closed
+func (s *logSink) stop() {
+	// start it if not started yet, so that buffered records are sent
+	s.start()
+
+	s.stopOnce.Do(func() {
+		close(s.quitChannel)
+		s.waitGroup.Wait()
+		atomic.StoreInt32(&s.running, 0)
+		s.sender.close()
+
+		// background goroutine exited, nothing would be reported to error output
+		if s.closeOutput != nil {
+			s.closeOutput()
+		}
+	})
+}
+
+// sync sends buffered records and waits, noop if sink is not running
+func (s *logSink) sync() error {
+	if atomic.LoadInt32(&s.running) != 1 {
+		return nil
+	}
+
+	done := make(chan struct{})
+	select {
+	case s.flushChannel <- done:
+	case <-s.quitChannel:
+		return nil
+	}
+
+	select {
+	case <-done:
+	case <-s.quitChannel:
+	}
+
+	return nil
+}
+
+// run sends records by batch size or flush interval until stopped
+func (s *logSink) run() {
+	defer s.waitGroup.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*logSinkRecord, 0, s.batchSize)
+
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		case <-ticker.C:
+			batch = s.flush(batch)
+		case done := <-s.flushChannel:
+			batch = s.drain(batch)
+			close(done)
+		case <-s.quitChannel:
+			s.drain(batch)
+			return
+		}
+	}
+}
+
+// drain sends batch and all records in queue
+func (s *logSink) drain(batch []*logSinkRecord) []*logSinkRecord {
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		default:
+			return s.flush(batch)
+		}
+	}
+}
+
+// flush sends batch with retry, records still failed after retries are dropped, emptied batch returned
+func (s *logSink) flush(batch []*logSinkRecord) []*logSinkRecord {
+	pending := batch
+	backoff := s.retryBackoff
+
+	for retries := 0; len(pending) > 0; retries++ {
+		retry, failed, err := s.sender.send(pending)
+		s.drop(1, failed...)
+
+		if len(retry) > 0 && retries < s.maxRetries {
+			pending = retry
+			s.sleep(backoff)
+			if backoff *= 2; backoff > s.maxRetryBackoff {
+				backoff = s.maxRetryBackoff
+			}
+			continue
+		}
+
+		s.drop(1, retry...)
+		if err != nil {
+			// same as zap, internal errors are reported to ErrorOutput of zap config
+			fmt.Fprintf(s.errorOutput, "%v Failed to send %d log lines to %s sink: %v\n",
+				time.Now().UTC(), len(retry)+len(failed), s.name, err)
+			s.errorOutput.Sync()
+		}
+		break
+	}
+
+	return batch[:0]
+}
+
+// newLogSinkCore creates core writes into sinks with level, encoder config and initial fields of config
+func newLogSinkCore(core zapcore.Core, sinks []*logSink, config *zap.Config) zapcore.Core {
+	initialFields := make([]zapcore.Field, 0)
+	for k, v := range config.InitialFields {
+		initialFields = append(initialFields, zap.Any(k, v))
+	}
+
+	cores := []zapcore.Core{core}
+	for _, sink := range sinks {
+		// ErrorOutput of zap logger is not exposed, open error output paths for each sink which are closed
+		// while sink stopped
+		if len(config.ErrorOutputPaths) > 0 && sink.closeOutput == nil {
+			if output, closeOutput, err := zap.Open(config.ErrorOutputPaths...); err == nil {
+				sink.errorOutput = output
+				sink.closeOutput = closeOutput
+			}
+		}
+
+		var encoder zapcore.Encoder
+		if config.Encoding == "console" && !sink.forceJSON {
+			encoder = zapcore.NewConsoleEncoder(config.EncoderConfig)
+		} else {
+			encoder = zapcore.NewJSONEncoder(config.EncoderConfig)
+		}
+
+		for i := range initialFields {
+			initialFields[i].AddTo(encoder)
+		}
+
+		cores = append(cores, &logSinkCore{
+			LevelEnabler: config.Level,
+			encoder:      encoder,
+			sink:         sink,
+		})
+	}
+
+	return zapcore.NewTee(cores...)
+}
+
+// logSinkCore encodes lines and enqueues them into logSink
+type logSinkCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	sink    *logSink
+}
+
+// With implements zapcore.Core
+func (c *logSinkCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for i := range fields {
+		fields[i].AddTo(encoder)
+	}
+
+	return &logSinkCore{
+		LevelEnabler: c.LevelEnabler,
+		encoder:      encoder,
+		sink:         c.sink,
+	}
+}
+
+// Check implements zapcore.Core
+func (c *logSinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core
+func (c *logSinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	line := make([]byte, len(bytes.TrimRight(buf.Bytes(), "\r\n")))
+	copy(line, buf.Bytes())
+	buf.Free()
+
+	c.sink.enqueue(&logSinkRecord{entry: ent, line: line})
+
+	// same as ioCore of zap, lines above error are flushed immediately since process may exit right after them
+	if ent.Level > zapcore.ErrorLevel {
+		return c.sink.sync()
+	}
+
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (c *logSinkCore) Sync() error {
+	return c.sink.sync()
+}
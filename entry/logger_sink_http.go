@@ -0,0 +1,320 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// LoggerHttpSinkFormatJson sends lines as JSON array
+	LoggerHttpSinkFormatJson = "json"
+	// LoggerHttpSinkFormatElasticsearch sends lines with Elasticsearch bulk API
+	LoggerHttpSinkFormatElasticsearch = "elasticsearch"
+	// LoggerHttpSinkFormatSplunk sends lines with Splunk HTTP Event Collector
+	LoggerHttpSinkFormatSplunk = "splunk"
+	// LoggerHttpSinkFormatDatadog sends lines with Datadog logs API
+	LoggerHttpSinkFormatDatadog = "datadog"
+
+	// default url of Datadog logs API
+	datadogLogsUrlDefault = "https://http-intake.logs.datadoghq.com/api/v2/logs"
+)
+
+// BootLoggerHttpSink bootstrap config of batching HTTP sink.
+// 1: Enabled: Enable HTTP sink.
+// 2: Url: Url lines posted to, like http://localhost:9200/_bulk or http://localhost:8088/services/collector/event.
+// 3: Format: One of json, elasticsearch, splunk and datadog, default is json.
+// 4: Token: Bearer token of json, API key of elasticsearch and datadog, HEC token of splunk.
+// 5: Headers: Additional headers of requests.
+// 6: Index: Index of elasticsearch and splunk.
+// 7: Source: Source of splunk and ddsource of datadog, default is application name.
+// 8: Service: Service of datadog, default is application name.
+// 9: Tags: Tags of datadog.
+// 10: InsecureSkipVerify: Skip verification of server certificate.
+// 11: Delivery: Buffering, back-pressure and retry config.
+//
+// Url of datadog is https://http-intake.logs.datadoghq.com/api/v2/logs by default, required by other formats.
+type BootLoggerHttpSink struct {
+	Enabled            bool                   `yaml:"enabled" json:"enabled"`
+	Url                string                 `yaml:"url" json:"url"`
+	Format             string                 `yaml:"format" json:"format"`
+	Token              string                 `yaml:"token" json:"token"`
+	Headers            map[string]string      `yaml:"headers" json:"headers"`
+	Index              string                 `yaml:"index" json:"index"`
+	Source             string                 `yaml:"source" json:"source"`
+	Service            string                 `yaml:"service" json:"service"`
+	Tags               []string               `yaml:"tags" json:"tags"`
+	InsecureSkipVerify bool                   `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
+	Delivery           BootLoggerSinkDelivery `yaml:"delivery" json:"delivery"`
+}
+
+// httpSinkSender posts batch of records in one request
+type httpSinkSender struct {
+	url         string
+	format      string
+	contentType string
+	headers     map[string]string
+	client      *http.Client
+	encode      func(records []*logSinkRecord) []byte
+}
+
+// newHttpSinkSender creates sender encodes records with format
+func newHttpSinkSender(boot *BootLoggerHttpSink, hostname, appName string) (*httpSinkSender, error) {
+	res := &httpSinkSender{
+		url:         boot.Url,
+		format:      strings.ToLower(boot.Format),
+		contentType: "application/json",
+		headers:     make(map[string]string),
+		client: &http.Client{
+			Timeout: boot.Delivery.timeout(),
+		},
+	}
+
+	if boot.InsecureSkipVerify {
+		res.client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		}
+	}
+
+	if len(res.format) < 1 {
+		res.format = LoggerHttpSinkFormatJson
+	}
+
+	source, service := boot.Source, boot.Service
+	if len(source) < 1 {
+		source = appName
+	}
+	if len(service) < 1 {
+		service = appName
+	}
+
+	switch res.format {
+	case LoggerHttpSinkFormatJson:
+		if len(boot.Token) > 0 {
+			res.headers["Authorization"] = "Bearer " + boot.Token
+		}
+		res.encode = encodeHttpSinkJson
+	case LoggerHttpSinkFormatElasticsearch:
+		if len(boot.Token) > 0 {
+			res.headers["Authorization"] = "ApiKey " + boot.Token
+		}
+		res.contentType = "application/x-ndjson"
+		res.encode = newElasticsearchBulkEncoder(boot.Index)
+	case LoggerHttpSinkFormatSplunk:
+		if len(boot.Token) > 0 {
+			res.headers["Authorization"] = "Splunk " + boot.Token
+		}
+		res.encode = newSplunkHecEncoder(hostname, source, boot.Index)
+	case LoggerHttpSinkFormatDatadog:
+		if len(boot.Token) > 0 {
+			res.headers["DD-API-KEY"] = boot.Token
+		}
+		if len(res.url) < 1 {
+			res.url = datadogLogsUrlDefault
+		}
+		res.encode = newDatadogLogsEncoder(hostname, source, service, boot.Tags)
+	default:
+		return nil, fmt.Errorf("invalid http sink format %s, expect one of [%s, %s, %s, %s]", boot.Format,
+			LoggerHttpSinkFormatJson, LoggerHttpSinkFormatElasticsearch, LoggerHttpSinkFormatSplunk, LoggerHttpSinkFormatDatadog)
+	}
+
+	if len(res.url) < 1 {
+		return nil, fmt.Errorf("url of http sink with format %s is required", res.format)
+	}
+
+	for k, v := range boot.Headers {
+		res.headers[k] = v
+	}
+
+	return res, nil
+}
+
+// send implements logSinkSender, records are retried if request failed with network error, 408, 429 or 5xx
+func (s *httpSinkSender) send(records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(s.encode(records)))
+	if err != nil {
+		return nil, records, err
+	}
+
+	req.Header.Set("Content-Type", s.contentType)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return records, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if s.format == LoggerHttpSinkFormatElasticsearch {
+			return parseElasticsearchBulkResponse(resp.Body, records)
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, nil, nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status code %d, %s", resp.StatusCode, strings.TrimSpace(string(body)))
+
+	if isHttpSinkRetryable(resp.StatusCode) {
+		return records, nil, err
+	}
+
+	return nil, records, err
+}
+
+// close implements logSinkSender
+func (s *httpSinkSender) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// isHttpSinkRetryable returns true if request could be retried with status code
+func isHttpSinkRetryable(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// httpSinkDocument returns line as JSON document, line is wrapped as message if it is not a JSON object
+func httpSinkDocument(record *logSinkRecord) json.RawMessage {
+	if len(record.line) > 0 && record.line[0] == '{' && json.Valid(record.line) {
+		return record.line
+	}
+
+	res, _ := json.Marshal(map[string]string{"message": string(record.line)})
+	return res
+}
+
+// encodeHttpSinkJson encodes records as JSON array
+func encodeHttpSinkJson(records []*logSinkRecord) []byte {
+	docs := make([]json.RawMessage, 0, len(records))
+	for i := range records {
+		docs = append(docs, httpSinkDocument(records[i]))
+	}
+
+	res, _ := json.Marshal(docs)
+	return res
+}
+
+// newElasticsearchBulkEncoder encodes records as index actions of bulk API
+func newElasticsearchBulkEncoder(index string) func([]*logSinkRecord) []byte {
+	action := []byte(`{"index":{}}`)
+	if len(index) > 0 {
+		action, _ = json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": index},
+		})
+	}
+
+	return func(records []*logSinkRecord) []byte {
+		res := bytes.Buffer{}
+		for i := range records {
+			res.Write(action)
+			res.WriteByte('\n')
+			res.Write(httpSinkDocument(records[i]))
+			res.WriteByte('\n')
+		}
+
+		return res.Bytes()
+	}
+}
+
+// parseElasticsearchBulkResponse returns items failed with retryable status to retry, other failed items are failed
+func parseElasticsearchBulkResponse(body io.Reader, records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error) {
+	resp := &struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}{}
+
+	// nothing to retry if response could not be parsed, since request was accepted
+	if err := json.NewDecoder(body).Decode(resp); err != nil || !resp.Errors {
+		return nil, nil, nil
+	}
+
+	retry, failed := make([]*logSinkRecord, 0), make([]*logSinkRecord, 0)
+	var lastErr error
+	for i := range resp.Items {
+		if i >= len(records) {
+			break
+		}
+
+		for _, item := range resp.Items[i] {
+			if item.Status >= 200 && item.Status < 300 {
+				continue
+			}
+
+			lastErr = fmt.Errorf("bulk item failed with status %d, %s", item.Status, string(item.Error))
+			if isHttpSinkRetryable(item.Status) {
+				retry = append(retry, records[i])
+			} else {
+				failed = append(failed, records[i])
+			}
+		}
+	}
+
+	return retry, failed, lastErr
+}
+
+// newSplunkHecEncoder encodes records as concatenated events of HTTP Event Collector
+func newSplunkHecEncoder(hostname, source, index string) func([]*logSinkRecord) []byte {
+	return func(records []*logSinkRecord) []byte {
+		res := bytes.Buffer{}
+		for i := range records {
+			event := map[string]interface{}{
+				"time":   float64(records[i].entry.Time.UnixNano()) / 1e9,
+				"host":   hostname,
+				"source": source,
+				"event":  httpSinkDocument(records[i]),
+			}
+			if len(index) > 0 {
+				event["index"] = index
+			}
+
+			bytes, _ := json.Marshal(event)
+			res.Write(bytes)
+		}
+
+		return res.Bytes()
+	}
+}
+
+// newDatadogLogsEncoder encodes records as JSON array of Datadog logs API
+func newDatadogLogsEncoder(hostname, source, service string, tags []string) func([]*logSinkRecord) []byte {
+	ddtags := strings.Join(tags, ",")
+
+	return func(records []*logSinkRecord) []byte {
+		logs := make([]map[string]interface{}, 0, len(records))
+		for i := range records {
+			log := map[string]interface{}{
+				"message":  string(records[i].line),
+				"status":   records[i].entry.Level.String(),
+				"hostname": hostname,
+				"service":  service,
+				"ddsource": source,
+			}
+			if len(ddtags) > 0 {
+				log["ddtags"] = ddtags
+			}
+			logs = append(logs, log)
+		}
+
+		res, _ := json.Marshal(logs)
+		return res
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// httpSinkServerForTest records requests and responds with status and body
+type httpSinkServerForTest struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	status   int
+	response string
+}
+
+func newHttpSinkServerForTest() *httpSinkServerForTest {
+	res := &httpSinkServerForTest{
+		status: http.StatusOK,
+	}
+
+	res.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+
+		res.lock.Lock()
+		defer res.lock.Unlock()
+
+		res.requests = append(res.requests, request)
+		res.bodies = append(res.bodies, string(body))
+		writer.WriteHeader(res.status)
+		writer.Write([]byte(res.response))
+	}))
+
+	return res
+}
+
+func newHttpSinkRecordsForTest() []*logSinkRecord {
+	return []*logSinkRecord{
+		newLogSinkRecordForTest(zapcore.InfoLevel, `{"msg":"ut-info"}`),
+		newLogSinkRecordForTest(zapcore.WarnLevel, "ut-plain"),
+	}
+}
+
+func TestNewHttpSinkSender(t *testing.T) {
+	// missing url
+	_, err := newHttpSinkSender(&BootLoggerHttpSink{}, "ut-host", "ut-app")
+	assert.NotNil(t, err)
+
+	// invalid format
+	_, err = newHttpSinkSender(&BootLoggerHttpSink{Url: "http://localhost", Format: "invalid"}, "ut-host", "ut-app")
+	assert.NotNil(t, err)
+
+	// with default
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{Url: "http://localhost"}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	assert.Equal(t, LoggerHttpSinkFormatJson, sender.format)
+	assert.Equal(t, "application/json", sender.contentType)
+	assert.Empty(t, sender.headers)
+	assert.Nil(t, sender.client.Transport)
+
+	sender, err = newHttpSinkSender(&BootLoggerHttpSink{Format: "Datadog"}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	assert.Equal(t, datadogLogsUrlDefault, sender.url)
+
+	// headers
+	sender, err = newHttpSinkSender(&BootLoggerHttpSink{
+		Url:                "https://localhost",
+		Token:              "ut-token",
+		Headers:            map[string]string{"X-Key": "value"},
+		InsecureSkipVerify: true,
+	}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer ut-token", "X-Key": "value"}, sender.headers)
+	assert.NotNil(t, sender.client.Transport)
+}
+
+func TestHttpSinkSender_Json(t *testing.T) {
+	server := newHttpSinkServerForTest()
+	defer server.Close()
+
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{Url: server.URL, Token: "ut-token"}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	defer sender.close()
+
+	retry, failed, err := sender.send(newHttpSinkRecordsForTest())
+	assert.Empty(t, retry)
+	assert.Empty(t, failed)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Bearer ut-token", server.requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "application/json", server.requests[0].Header.Get("Content-Type"))
+	assert.JSONEq(t, `[{"msg":"ut-info"},{"message":"ut-plain"}]`, server.bodies[0])
+}
+
+func TestHttpSinkSender_Elasticsearch(t *testing.T) {
+	server := newHttpSinkServerForTest()
+	defer server.Close()
+
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{
+		Url:    server.URL,
+		Format: LoggerHttpSinkFormatElasticsearch,
+		Token:  "ut-token",
+		Index:  "ut-index",
+	}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+
+	records := newHttpSinkRecordsForTest()
+	retry, failed, err := sender.send(records)
+	assert.Empty(t, retry)
+	assert.Empty(t, failed)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "ApiKey ut-token", server.requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "application/x-ndjson", server.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, `{"index":{"_index":"ut-index"}}`+"\n"+`{"msg":"ut-info"}`+"\n"+
+		`{"index":{"_index":"ut-index"}}`+"\n"+`{"message":"ut-plain"}`+"\n", server.bodies[0])
+
+	// partial failure
+	server.response = `{"errors":true,"items":[{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`
+	retry, failed, err = sender.send(records)
+	assert.Equal(t, records[:1], retry)
+	assert.Equal(t, records[1:], failed)
+	assert.NotNil(t, err)
+
+	// without index
+	assert.Equal(t, `{"index":{}}`+"\n"+`{"msg":"ut-info"}`+"\n",
+		string(newElasticsearchBulkEncoder("")(records[:1])))
+}
+
+func TestHttpSinkSender_Splunk(t *testing.T) {
+	server := newHttpSinkServerForTest()
+	defer server.Close()
+
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{
+		Url:    server.URL,
+		Format: LoggerHttpSinkFormatSplunk,
+		Token:  "ut-token",
+		Index:  "ut-index",
+	}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+
+	_, _, err = sender.send(newHttpSinkRecordsForTest())
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Splunk ut-token", server.requests[0].Header.Get("Authorization"))
+	decoder := json.NewDecoder(strings.NewReader(server.bodies[0]))
+	events := make([]map[string]interface{}, 0)
+	for decoder.More() {
+		event := make(map[string]interface{})
+		assert.Nil(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"time": float64(1640995200), "host": "ut-host", "source": "ut-app", "index": "ut-index", "event": map[string]interface{}{"msg": "ut-info"}},
+		{"time": float64(1640995200), "host": "ut-host", "source": "ut-app", "index": "ut-index", "event": map[string]interface{}{"message": "ut-plain"}},
+	}, events)
+}
+
+func TestHttpSinkSender_Datadog(t *testing.T) {
+	server := newHttpSinkServerForTest()
+	defer server.Close()
+
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{
+		Url:     server.URL,
+		Format:  LoggerHttpSinkFormatDatadog,
+		Token:   "ut-token",
+		Service: "ut-service",
+		Tags:    []string{"env:ut", "team:ut"},
+	}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+
+	_, _, err = sender.send(newHttpSinkRecordsForTest())
+	assert.Nil(t, err)
+
+	assert.Equal(t, "ut-token", server.requests[0].Header.Get("DD-API-KEY"))
+	assert.JSONEq(t, `[
+		{"message":"{\"msg\":\"ut-info\"}","status":"info","hostname":"ut-host","service":"ut-service","ddsource":"ut-app","ddtags":"env:ut,team:ut"},
+		{"message":"ut-plain","status":"warn","hostname":"ut-host","service":"ut-service","ddsource":"ut-app","ddtags":"env:ut,team:ut"}
+	]`, server.bodies[0])
+}
+
+func TestHttpSinkSender_Failure(t *testing.T) {
+	server := newHttpSinkServerForTest()
+	defer server.Close()
+
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{Url: server.URL}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	records := newHttpSinkRecordsForTest()
+
+	// retryable
+	for _, status := range []int{http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		server.status = status
+		retry, failed, err := sender.send(records)
+		assert.Equal(t, records, retry)
+		assert.Empty(t, failed)
+		assert.NotNil(t, err)
+	}
+
+	// failed permanently
+	server.status = http.StatusBadRequest
+	server.response = "ut-error"
+	retry, failed, err := sender.send(records)
+	assert.Empty(t, retry)
+	assert.Equal(t, records, failed)
+	assert.Contains(t, err.Error(), "ut-error")
+
+	// network error
+	server.Close()
+	retry, failed, err = sender.send(records)
+	assert.Equal(t, records, retry)
+	assert.Empty(t, failed)
+	assert.NotNil(t, err)
+}
+
+func TestLogSink_HttpRetry(t *testing.T) {
+	server := newHttpSinkServerForTest()
+	defer server.Close()
+	server.status = http.StatusServiceUnavailable
+
+	sender, err := newHttpSinkSender(&BootLoggerHttpSink{Url: server.URL}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+
+	sink := newLogSink("http", sender, &BootLoggerSinkDelivery{MaxRetries: 2, RetryBackoffMs: 1}, true)
+	sink.enqueue(newLogSinkRecordForTest(zapcore.ErrorLevel, `{"msg":"ut-error"}`))
+	sink.stop()
+
+	assert.Len(t, server.bodies, 3)
+	assert.Equal(t, uint64(1), sink.droppedCounts()[LoggerDropReasonSinkFailed]["error"])
+}
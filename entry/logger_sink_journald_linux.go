@@ -0,0 +1,59 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package rkentry
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"net"
+	"os"
+)
+
+// sendJournaldMemfd sends oversized entry as sealed memfd over SCM_RIGHTS, same as sd_journal_sendv() of systemd
+func sendJournaldMemfd(conn net.Conn, msg []byte) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("journald connection is not a unix socket")
+	}
+
+	fd, err := unix.MemfdCreate("journal-message", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return err
+	}
+
+	file := os.NewFile(uintptr(fd), "journal-message")
+	defer file.Close()
+
+	if _, err := file.Write(msg); err != nil {
+		return err
+	}
+
+	// journald only accepts sealed memfd
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return err
+	}
+
+	// WriteMsgUnix is not allowed on connected datagram socket, send with raw connection instead
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	rights := unix.UnixRights(int(file.Fd()))
+	var sendErr error
+	if err := rawConn.Write(func(socket uintptr) bool {
+		sendErr = unix.Sendmsg(int(socket), nil, rights, nil, 0)
+		return sendErr != unix.EAGAIN
+	}); err != nil {
+		return err
+	}
+
+	return sendErr
+}
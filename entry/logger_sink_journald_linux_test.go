@@ -0,0 +1,75 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package rkentry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendJournaldMemfd(t *testing.T) {
+	// not a unix socket
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	assert.NotNil(t, sendJournaldMemfd(client, []byte("MESSAGE=ut-message\n")))
+
+	socketPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sender := newJournaldSender(&BootLoggerJournald{Socket: socketPath}, "ut-app")
+	defer sender.close()
+
+	oversized := newLogSinkRecordForTest(zapcore.InfoLevel, strings.Repeat("a", 4*1024*1024))
+	retry, failed, err := sender.send([]*logSinkRecord{oversized})
+	if err != nil {
+		// memfd is not supported by kernel
+		t.Skipf("memfd not available, %v", err)
+	}
+	assert.Empty(t, retry)
+	assert.Empty(t, failed)
+
+	// empty datagram with sealed memfd
+	oob := make([]byte, unix.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(nil, oob)
+	assert.Nil(t, err)
+	assert.Zero(t, n)
+
+	messages, err := unix.ParseSocketControlMessage(oob[:oobn])
+	assert.Nil(t, err)
+	assert.Len(t, messages, 1)
+	fds, err := unix.ParseUnixRights(&messages[0])
+	assert.Nil(t, err)
+	assert.Len(t, fds, 1)
+
+	file := os.NewFile(uintptr(fds[0]), "journal-message")
+	defer file.Close()
+
+	seals, err := unix.FcntlInt(file.Fd(), unix.F_GET_SEALS, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL, seals)
+
+	file.Seek(0, 0)
+	content, err := ioutil.ReadAll(file)
+	assert.Nil(t, err)
+	fields := parseJournaldFields(t, string(content))
+	assert.Equal(t, string(oversized.line), fields["MESSAGE"])
+	assert.Equal(t, "ut-app", fields["SYSLOG_IDENTIFIER"])
+}
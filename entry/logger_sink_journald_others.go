@@ -0,0 +1,19 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package rkentry
+
+import (
+	"net"
+	"syscall"
+)
+
+// sendJournaldMemfd is not supported since memfd is only available on linux
+func sendJournaldMemfd(conn net.Conn, msg []byte) error {
+	return syscall.EMSGSIZE
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package rkentry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"syscall"
+	"testing"
+)
+
+func TestSendJournaldMemfd(t *testing.T) {
+	assert.Equal(t, syscall.EMSGSIZE, sendJournaldMemfd(nil, []byte("MESSAGE=ut-message\n")))
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// LoggerSyslogNetworkUdp sends syslog messages over udp, one message per datagram
+	LoggerSyslogNetworkUdp = "udp"
+	// LoggerSyslogNetworkTcp sends syslog messages over tcp with octet counting framing of RFC 6587
+	LoggerSyslogNetworkTcp = "tcp"
+	// LoggerSyslogNetworkUnix sends syslog messages over unix stream socket with octet counting framing
+	LoggerSyslogNetworkUnix = "unix"
+	// LoggerSyslogNetworkUnixgram sends syslog messages over unix datagram socket, one message per datagram
+	LoggerSyslogNetworkUnixgram = "unixgram"
+
+	// default socket of systemd-journald native protocol
+	journaldSocketDefault = "/run/systemd/journal/socket"
+)
+
+// syslog facilities of RFC 5424
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// BootLoggerSyslog bootstrap config of RFC 5424 syslog sink.
+// 1: Enabled: Enable syslog sink.
+// 2: Network: One of udp, tcp, unix and unixgram, default is udp.
+// 3: Addr: Address of syslog server, default is localhost:514, or /dev/log for unix and unixgram.
+// 4: Facility: Facility of messages like user, daemon and local0, default is user.
+// 5: AppName: APP-NAME of messages, default is application name.
+// 6: Hostname: HOSTNAME of messages, default is hostname of os.
+// 7: Delivery: Buffering, back-pressure and retry config.
+type BootLoggerSyslog struct {
+	Enabled  bool                   `yaml:"enabled" json:"enabled"`
+	Network  string                 `yaml:"network" json:"network"`
+	Addr     string                 `yaml:"addr" json:"addr"`
+	Facility string                 `yaml:"facility" json:"facility"`
+	AppName  string                 `yaml:"appName" json:"appName"`
+	Hostname string                 `yaml:"hostname" json:"hostname"`
+	Delivery BootLoggerSinkDelivery `yaml:"delivery" json:"delivery"`
+}
+
+// BootLoggerJournald bootstrap config of systemd-journald sink.
+// 1: Enabled: Enable journald sink.
+// 2: Socket: Path of journald socket, default is /run/systemd/journal/socket.
+// 3: Identifier: SYSLOG_IDENTIFIER of entries, default is application name.
+// 4: Delivery: Buffering, back-pressure and retry config.
+//
+// Entries exceed max datagram size of socket are sent as sealed memfd on linux,
+// they are dropped as sinkFailed on other platforms or if memfd is not available.
+type BootLoggerJournald struct {
+	Enabled    bool                   `yaml:"enabled" json:"enabled"`
+	Socket     string                 `yaml:"socket" json:"socket"`
+	Identifier string                 `yaml:"identifier" json:"identifier"`
+	Delivery   BootLoggerSinkDelivery `yaml:"delivery" json:"delivery"`
+}
+
+// syslogSeverity returns severity of RFC 5424 and priority of journald of level
+func syslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	case zapcore.FatalLevel:
+		return 0
+	}
+
+	return 5
+}
+
+// syslogHeaderValue returns printable ASCII value with max length, nil value "-" returned if empty
+func syslogHeaderValue(in string, maxLen int) string {
+	res := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, in)
+
+	if len(res) > maxLen {
+		res = res[:maxLen]
+	}
+
+	if len(res) < 1 {
+		return "-"
+	}
+
+	return res
+}
+
+// socketSender writes into lazily connected socket, connection is closed and reconnected after failure
+type socketSender struct {
+	network string
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+	encode  func(record *logSinkRecord) []byte
+	// sendOversized sends message rejected with EMSGSIZE in other way, optional
+	sendOversized func(conn net.Conn, msg []byte) error
+}
+
+// send implements logSinkSender, records not sent are retried and oversized datagram fails permanently
+// unless it is sent by sendOversized
+func (s *socketSender) send(records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error) {
+	failed := make([]*logSinkRecord, 0)
+
+	for i := range records {
+		if s.conn == nil {
+			conn, err := net.DialTimeout(s.network, s.addr, s.timeout)
+			if err != nil {
+				return records[i:], failed, err
+			}
+			s.conn = conn
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+		msg := s.encode(records[i])
+		if _, err := s.conn.Write(msg); err != nil {
+			if errors.Is(err, syscall.EMSGSIZE) {
+				if s.sendOversized == nil || s.sendOversized(s.conn, msg) != nil {
+					failed = append(failed, records[i])
+				}
+				continue
+			}
+
+			s.close()
+			return records[i:], failed, err
+		}
+	}
+
+	if len(failed) > 0 {
+		return nil, failed, syscall.EMSGSIZE
+	}
+
+	return nil, failed, nil
+}
+
+// close implements logSinkSender
+func (s *socketSender) close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// newSyslogSender creates sender formats records as RFC 5424 messages
+func newSyslogSender(boot *BootLoggerSyslog, hostname, appName string) (*socketSender, error) {
+	res := &socketSender{
+		network: strings.ToLower(boot.Network),
+		addr:    boot.Addr,
+		timeout: boot.Delivery.timeout(),
+	}
+
+	if len(res.network) < 1 {
+		res.network = LoggerSyslogNetworkUdp
+	}
+
+	switch res.network {
+	case LoggerSyslogNetworkUdp, LoggerSyslogNetworkTcp:
+		if len(res.addr) < 1 {
+			res.addr = "localhost:514"
+		}
+	case LoggerSyslogNetworkUnix, LoggerSyslogNetworkUnixgram:
+		if len(res.addr) < 1 {
+			res.addr = "/dev/log"
+		}
+	default:
+		return nil, fmt.Errorf("invalid syslog network %s, expect one of [%s, %s, %s, %s]", boot.Network,
+			LoggerSyslogNetworkUdp, LoggerSyslogNetworkTcp, LoggerSyslogNetworkUnix, LoggerSyslogNetworkUnixgram)
+	}
+
+	facilityName := strings.ToLower(boot.Facility)
+	if len(facilityName) < 1 {
+		facilityName = "user"
+	}
+	facility, ok := syslogFacilities[facilityName]
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog facility %s", boot.Facility)
+	}
+
+	if len(boot.Hostname) > 0 {
+		hostname = boot.Hostname
+	}
+	if len(boot.AppName) > 0 {
+		appName = boot.AppName
+	}
+
+	// HEADER without PRI and TIMESTAMP, STRUCTURED-DATA is omitted
+	// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG
+	suffix := fmt.Sprintf(" %s %s %d - - ",
+		syslogHeaderValue(hostname, 255), syslogHeaderValue(appName, 48), os.Getpid())
+	framed := res.network == LoggerSyslogNetworkTcp || res.network == LoggerSyslogNetworkUnix
+
+	res.encode = func(record *logSinkRecord) []byte {
+		msg := bytes.Buffer{}
+		msg.WriteString("<")
+		msg.WriteString(strconv.Itoa(facility*8 + syslogSeverity(record.entry.Level)))
+		msg.WriteString(">1 ")
+		msg.WriteString(record.entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+		msg.WriteString(suffix)
+		msg.Write(record.line)
+
+		if !framed {
+			return msg.Bytes()
+		}
+
+		// octet counting of RFC 6587, MSG-LEN SP SYSLOG-MSG
+		return append([]byte(strconv.Itoa(msg.Len())+" "), msg.Bytes()...)
+	}
+
+	return res, nil
+}
+
+// newJournaldSender creates sender formats records as datagrams of journald native protocol
+func newJournaldSender(boot *BootLoggerJournald, appName string) *socketSender {
+	res := &socketSender{
+		network:       LoggerSyslogNetworkUnixgram,
+		addr:          boot.Socket,
+		timeout:       boot.Delivery.timeout(),
+		sendOversized: sendJournaldMemfd,
+	}
+
+	if len(res.addr) < 1 {
+		res.addr = journaldSocketDefault
+	}
+
+	if len(boot.Identifier) > 0 {
+		appName = boot.Identifier
+	}
+
+	res.encode = func(record *logSinkRecord) []byte {
+		msg := bytes.Buffer{}
+		writeJournaldField(&msg, "MESSAGE", record.line)
+		writeJournaldField(&msg, "PRIORITY", []byte(strconv.Itoa(syslogSeverity(record.entry.Level))))
+		writeJournaldField(&msg, "SYSLOG_IDENTIFIER", []byte(appName))
+		writeJournaldField(&msg, "SYSLOG_TIMESTAMP", []byte(record.entry.Time.Format(time.RFC3339Nano)))
+
+		if record.entry.Caller.Defined {
+			writeJournaldField(&msg, "CODE_FILE", []byte(record.entry.Caller.File))
+			writeJournaldField(&msg, "CODE_LINE", []byte(strconv.Itoa(record.entry.Caller.Line)))
+			if len(record.entry.Caller.Function) > 0 {
+				writeJournaldField(&msg, "CODE_FUNC", []byte(record.entry.Caller.Function))
+			}
+		}
+
+		return msg.Bytes()
+	}
+
+	return res
+}
+
+// writeJournaldField writes KEY=VALUE, or KEY, little endian length and VALUE if value contains new line
+func writeJournaldField(msg *bytes.Buffer, key string, value []byte) {
+	msg.WriteString(key)
+
+	if bytes.IndexByte(value, '\n') < 0 {
+		msg.WriteByte('=')
+		msg.Write(value)
+		msg.WriteByte('\n')
+		return
+	}
+
+	msg.WriteByte('\n')
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	msg.Write(size)
+	msg.Write(value)
+	msg.WriteByte('\n')
+}
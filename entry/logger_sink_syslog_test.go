@@ -0,0 +1,320 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readPackets reads datagrams from conn until timed out
+func readPackets(t *testing.T, conn net.PacketConn, expect int) []string {
+	res := make([]string, 0)
+	buf := make([]byte, 65536)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for len(res) < expect {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		res = append(res, string(buf[:n]))
+	}
+
+	assert.Len(t, res, expect)
+	return res
+}
+
+// readOctetCounted reads messages framed with octet counting from first connection of listener
+func readOctetCounted(t *testing.T, listener net.Listener, expect int) []string {
+	res := make([]string, 0)
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	reader := bufio.NewReader(conn)
+	for len(res) < expect {
+		size, err := reader.ReadString(' ')
+		if err != nil {
+			break
+		}
+		length, _ := strconv.Atoi(strings.TrimSpace(size))
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(reader, msg); err != nil {
+			break
+		}
+		res = append(res, string(msg))
+	}
+
+	assert.Len(t, res, expect)
+	return res
+}
+
+func newSyslogSenderForTest(t *testing.T, network, addr string) *socketSender {
+	sender, err := newSyslogSender(&BootLoggerSyslog{
+		Network:  network,
+		Addr:     addr,
+		Facility: "local0",
+	}, "ut host", "ut-app")
+	assert.Nil(t, err)
+
+	return sender
+}
+
+func TestNewSyslogSender(t *testing.T) {
+	// with default
+	sender, err := newSyslogSender(&BootLoggerSyslog{}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	assert.Equal(t, LoggerSyslogNetworkUdp, sender.network)
+	assert.Equal(t, "localhost:514", sender.addr)
+	assert.Equal(t, 5*time.Second, sender.timeout)
+
+	sender, err = newSyslogSender(&BootLoggerSyslog{Network: "UNIX"}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/log", sender.addr)
+
+	// invalid network
+	_, err = newSyslogSender(&BootLoggerSyslog{Network: "invalid"}, "ut-host", "ut-app")
+	assert.NotNil(t, err)
+
+	// invalid facility
+	_, err = newSyslogSender(&BootLoggerSyslog{Facility: "invalid"}, "ut-host", "ut-app")
+	assert.NotNil(t, err)
+
+	// message
+	sender, err = newSyslogSender(&BootLoggerSyslog{
+		Facility: "daemon",
+		AppName:  "ut-name",
+		Hostname: "ut-hostname",
+	}, "ut-host", "ut-app")
+	assert.Nil(t, err)
+	record := newLogSinkRecordForTest(zapcore.ErrorLevel, "ut-message")
+	assert.Equal(t, fmt.Sprintf("<27>1 2022-01-01T00:00:00.000000Z ut-hostname ut-name %d - - ut-message", os.Getpid()),
+		string(sender.encode(record)))
+}
+
+func TestSyslogHeaderValue(t *testing.T) {
+	assert.Equal(t, "-", syslogHeaderValue("", 10))
+	assert.Equal(t, "ut_host", syslogHeaderValue("ut host", 10))
+	assert.Equal(t, "ut-h", syslogHeaderValue("ut-host", 4))
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	assert.Equal(t, 7, syslogSeverity(zapcore.DebugLevel))
+	assert.Equal(t, 6, syslogSeverity(zapcore.InfoLevel))
+	assert.Equal(t, 4, syslogSeverity(zapcore.WarnLevel))
+	assert.Equal(t, 3, syslogSeverity(zapcore.ErrorLevel))
+	assert.Equal(t, 2, syslogSeverity(zapcore.DPanicLevel))
+	assert.Equal(t, 1, syslogSeverity(zapcore.PanicLevel))
+	assert.Equal(t, 0, syslogSeverity(zapcore.FatalLevel))
+	assert.Equal(t, 5, syslogSeverity(zapcore.Level(10)))
+}
+
+func TestSyslogSender_Udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sender := newSyslogSenderForTest(t, LoggerSyslogNetworkUdp, conn.LocalAddr().String())
+	defer sender.close()
+
+	retry, failed, err := sender.send([]*logSinkRecord{
+		newLogSinkRecordForTest(zapcore.InfoLevel, "ut-info"),
+		newLogSinkRecordForTest(zapcore.WarnLevel, "ut-warn"),
+	})
+	assert.Empty(t, retry)
+	assert.Empty(t, failed)
+	assert.Nil(t, err)
+
+	packets := readPackets(t, conn, 2)
+	prefix := fmt.Sprintf("1 2022-01-01T00:00:00.000000Z ut_host ut-app %d - - ", os.Getpid())
+	assert.Equal(t, "<134>"+prefix+"ut-info", packets[0])
+	assert.Equal(t, "<132>"+prefix+"ut-warn", packets[1])
+}
+
+func TestSyslogSender_Tcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	sender := newSyslogSenderForTest(t, LoggerSyslogNetworkTcp, listener.Addr().String())
+	defer sender.close()
+
+	_, _, err = sender.send([]*logSinkRecord{
+		newLogSinkRecordForTest(zapcore.InfoLevel, "ut-info"),
+		newLogSinkRecordForTest(zapcore.InfoLevel, "ut multi\nline"),
+	})
+	assert.Nil(t, err)
+
+	msgs := readOctetCounted(t, listener, 2)
+	assert.True(t, strings.HasPrefix(msgs[0], "<134>1 "))
+	assert.True(t, strings.HasSuffix(msgs[0], " ut-info"))
+	assert.True(t, strings.HasSuffix(msgs[1], " ut multi\nline"))
+
+	// retried if server is gone
+	listener.Close()
+	sender.close()
+	records := []*logSinkRecord{newLogSinkRecordForTest(zapcore.InfoLevel, "ut-info")}
+	retry, failed, err := sender.send(records)
+	assert.Equal(t, records, retry)
+	assert.Empty(t, failed)
+	assert.NotNil(t, err)
+}
+
+func TestSyslogSender_Unix(t *testing.T) {
+	// stream
+	streamPath := path.Join(t.TempDir(), "stream.sock")
+	listener, err := net.Listen("unix", streamPath)
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	sender := newSyslogSenderForTest(t, LoggerSyslogNetworkUnix, streamPath)
+	defer sender.close()
+
+	_, _, err = sender.send([]*logSinkRecord{newLogSinkRecordForTest(zapcore.InfoLevel, "ut-stream")})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(readOctetCounted(t, listener, 1)[0], " ut-stream"))
+
+	// datagram
+	gramPath := path.Join(t.TempDir(), "gram.sock")
+	conn, err := net.ListenPacket("unixgram", gramPath)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sender = newSyslogSenderForTest(t, LoggerSyslogNetworkUnixgram, gramPath)
+	defer sender.close()
+
+	_, _, err = sender.send([]*logSinkRecord{newLogSinkRecordForTest(zapcore.InfoLevel, "ut-gram")})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(readPackets(t, conn, 1)[0], " ut-gram"))
+}
+
+// parseJournaldFields parses datagram of journald native protocol
+func parseJournaldFields(t *testing.T, packet string) map[string]string {
+	res := make(map[string]string)
+	buf := []byte(packet)
+
+	for len(buf) > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		assert.True(t, i > 0)
+		line := buf[:i]
+
+		if eq := bytes.IndexByte(line, '='); eq > 0 {
+			res[string(line[:eq])] = string(line[eq+1:])
+			buf = buf[i+1:]
+			continue
+		}
+
+		size := binary.LittleEndian.Uint64(buf[i+1 : i+9])
+		res[string(line)] = string(buf[i+9 : i+9+int(size)])
+		assert.Equal(t, byte('\n'), buf[i+9+int(size)])
+		buf = buf[i+10+int(size):]
+	}
+
+	return res
+}
+
+func TestJournaldSender(t *testing.T) {
+	// with default
+	sender := newJournaldSender(&BootLoggerJournald{}, "ut-app")
+	assert.Equal(t, LoggerSyslogNetworkUnixgram, sender.network)
+	assert.Equal(t, journaldSocketDefault, sender.addr)
+
+	socketPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", socketPath)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sender = newJournaldSender(&BootLoggerJournald{Socket: socketPath, Identifier: "ut-id"}, "ut-app")
+	defer sender.close()
+
+	record := newLogSinkRecordForTest(zapcore.WarnLevel, "ut multi\nline")
+	record.entry.Caller = zapcore.NewEntryCaller(0, "/ut/service.go", 42, true)
+	record.entry.Caller.Function = "ut.Func"
+
+	retry, failed, err := sender.send([]*logSinkRecord{
+		record,
+		newLogSinkRecordForTest(zapcore.InfoLevel, "ut-info"),
+	})
+	assert.Empty(t, retry)
+	assert.Empty(t, failed)
+	assert.Nil(t, err)
+
+	packets := readPackets(t, conn, 2)
+	assert.Equal(t, map[string]string{
+		"MESSAGE":           "ut multi\nline",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "ut-id",
+		"SYSLOG_TIMESTAMP":  "2022-01-01T00:00:00Z",
+		"CODE_FILE":         "/ut/service.go",
+		"CODE_LINE":         "42",
+		"CODE_FUNC":         "ut.Func",
+	}, parseJournaldFields(t, packets[0]))
+	assert.Equal(t, map[string]string{
+		"MESSAGE":           "ut-info",
+		"PRIORITY":          "6",
+		"SYSLOG_IDENTIFIER": "ut-id",
+		"SYSLOG_TIMESTAMP":  "2022-01-01T00:00:00Z",
+	}, parseJournaldFields(t, packets[1]))
+
+	// missing socket is retried
+	sender = newJournaldSender(&BootLoggerJournald{Socket: path.Join(t.TempDir(), "missing.sock")}, "ut-app")
+	retry, _, err = sender.send([]*logSinkRecord{record})
+	assert.Len(t, retry, 1)
+	assert.NotNil(t, err)
+}
+
+func TestJournaldSender_Oversized(t *testing.T) {
+	socketPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", socketPath)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sender := newJournaldSender(&BootLoggerJournald{Socket: socketPath}, "ut-app")
+	defer sender.close()
+
+	// failed if not able to send in other way
+	oversizedMsgs := make([][]byte, 0)
+	sender.sendOversized = func(conn net.Conn, msg []byte) error {
+		oversizedMsgs = append(oversizedMsgs, msg)
+		return errors.New("ut-error")
+	}
+
+	oversized := newLogSinkRecordForTest(zapcore.InfoLevel, strings.Repeat("a", 4*1024*1024))
+	retry, failed, err := sender.send([]*logSinkRecord{
+		oversized,
+		newLogSinkRecordForTest(zapcore.InfoLevel, "ut-info"),
+	})
+	assert.Empty(t, retry)
+	assert.Equal(t, []*logSinkRecord{oversized}, failed)
+	assert.NotNil(t, err)
+	assert.Len(t, oversizedMsgs, 1)
+	assert.Equal(t, oversized.line, []byte(parseJournaldFields(t, string(oversizedMsgs[0]))["MESSAGE"]))
+	assert.Equal(t, "ut-info", parseJournaldFields(t, readPackets(t, conn, 1)[0])["MESSAGE"])
+
+	// sent in other way
+	sender.sendOversized = func(conn net.Conn, msg []byte) error {
+		return nil
+	}
+	retry, failed, err = sender.send([]*logSinkRecord{oversized})
+	assert.Empty(t, retry)
+	assert.Empty(t, failed)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,442 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLogSinkSender records lines sent, results of each send could be overridden by fail
+type fakeLogSinkSender struct {
+	lock   sync.Mutex
+	lines  []string
+	calls  int
+	closed bool
+	fail   func(call int, records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error)
+}
+
+func (s *fakeLogSinkSender) send(records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.calls++
+	if s.fail != nil {
+		if retry, failed, err := s.fail(s.calls, records); err != nil {
+			return retry, failed, err
+		}
+	}
+
+	for i := range records {
+		s.lines = append(s.lines, string(records[i].line))
+	}
+
+	return nil, nil, nil
+}
+
+func (s *fakeLogSinkSender) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *fakeLogSinkSender) getLines() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, s.lines...)
+}
+
+func newLogSinkRecordForTest(level zapcore.Level, line string) *logSinkRecord {
+	return &logSinkRecord{
+		entry: zapcore.Entry{Level: level, Time: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		line:  []byte(line),
+	}
+}
+
+func TestNewLogSink(t *testing.T) {
+	// with default
+	sink := newLogSink("ut-sink", &fakeLogSinkSender{}, &BootLoggerSinkDelivery{}, false)
+	assert.Equal(t, 1024, cap(sink.queue))
+	assert.Equal(t, LoggerSinkOnFullDrop, sink.onFull)
+	assert.Equal(t, time.Second, sink.blockTimeout)
+	assert.Equal(t, 100, sink.batchSize)
+	assert.Equal(t, time.Second, sink.flushInterval)
+	assert.Equal(t, 3, sink.maxRetries)
+	assert.Equal(t, 100*time.Millisecond, sink.retryBackoff)
+	assert.Equal(t, 5*time.Second, sink.maxRetryBackoff)
+	assert.Equal(t, 5*time.Second, (&BootLoggerSinkDelivery{}).timeout())
+
+	// with config
+	sink = newLogSink("ut-sink", &fakeLogSinkSender{}, &BootLoggerSinkDelivery{
+		QueueSize:         10,
+		OnFull:            LoggerSinkOnFullBlock,
+		BlockTimeoutMs:    10,
+		BatchSize:         5,
+		FlushIntervalMs:   20,
+		MaxRetries:        -1,
+		RetryBackoffMs:    30,
+		MaxRetryBackoffMs: 40,
+		TimeoutMs:         50,
+	}, true)
+	assert.Equal(t, 10, cap(sink.queue))
+	assert.Equal(t, LoggerSinkOnFullBlock, sink.onFull)
+	assert.Equal(t, 10*time.Millisecond, sink.blockTimeout)
+	assert.Equal(t, 5, sink.batchSize)
+	assert.Equal(t, 20*time.Millisecond, sink.flushInterval)
+	assert.Equal(t, -1, sink.maxRetries)
+	assert.Equal(t, 30*time.Millisecond, sink.retryBackoff)
+	assert.Equal(t, 40*time.Millisecond, sink.maxRetryBackoff)
+	assert.True(t, sink.forceJSON)
+}
+
+func TestNewLogSinks(t *testing.T) {
+	// nothing enabled
+	sinks, err := newLogSinks(&BootLoggerSinks{})
+	assert.Nil(t, err)
+	assert.Empty(t, sinks)
+
+	// invalid onFull
+	_, err = newLogSinks(&BootLoggerSinks{
+		Journald: BootLoggerJournald{Enabled: true, Delivery: BootLoggerSinkDelivery{OnFull: "invalid"}},
+	})
+	assert.NotNil(t, err)
+
+	// invalid syslog
+	_, err = newLogSinks(&BootLoggerSinks{Syslog: BootLoggerSyslog{Enabled: true, Network: "invalid"}})
+	assert.NotNil(t, err)
+
+	// invalid http
+	_, err = newLogSinks(&BootLoggerSinks{Http: BootLoggerHttpSink{Enabled: true}})
+	assert.NotNil(t, err)
+
+	// all enabled
+	sinks, err = newLogSinks(&BootLoggerSinks{
+		Syslog:   BootLoggerSyslog{Enabled: true},
+		Journald: BootLoggerJournald{Enabled: true},
+		Http:     BootLoggerHttpSink{Enabled: true, Url: "http://localhost:8080"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, sinks, 3)
+	assert.False(t, sinks[0].forceJSON)
+	assert.False(t, sinks[1].forceJSON)
+	assert.True(t, sinks[2].forceJSON)
+}
+
+func TestLogSink_Batch(t *testing.T) {
+	sender := &fakeLogSinkSender{}
+	sink := newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{BatchSize: 2, FlushIntervalMs: 60000}, false)
+
+	// noop before started
+	assert.Nil(t, sink.sync())
+
+	sink.start()
+	sink.start()
+	defer sink.stop()
+
+	for _, line := range []string{"a", "b", "c"} {
+		sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, line))
+	}
+
+	// sent by batch size
+	assert.Eventually(t, func() bool {
+		return len(sender.getLines()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// sent by sync
+	assert.Nil(t, sink.sync())
+	assert.Equal(t, []string{"a", "b", "c"}, sender.getLines())
+
+	// sent by flush interval
+	sink = newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{FlushIntervalMs: 10}, false)
+	sink.start()
+	defer sink.stop()
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "d"))
+	assert.Eventually(t, func() bool {
+		return len(sender.getLines()) == 4
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLogSink_Retry(t *testing.T) {
+	sender := &fakeLogSinkSender{
+		fail: func(call int, records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error) {
+			switch call {
+			case 1:
+				// first one is sent, rest are retried
+				return records[1:], nil, errors.New("ut-error")
+			case 2:
+				// first one failed permanently
+				return records[1:], records[:1], errors.New("ut-error")
+			}
+			return nil, nil, nil
+		},
+	}
+	sink := newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{RetryBackoffMs: 100, MaxRetryBackoffMs: 150}, false)
+
+	backoffs := make([]time.Duration, 0)
+	sink.sleep = func(d time.Duration) {
+		backoffs = append(backoffs, d)
+	}
+
+	records := []*logSinkRecord{
+		newLogSinkRecordForTest(zapcore.InfoLevel, "a"),
+		newLogSinkRecordForTest(zapcore.WarnLevel, "b"),
+		newLogSinkRecordForTest(zapcore.ErrorLevel, "c"),
+	}
+	assert.Empty(t, sink.flush(records))
+	assert.Equal(t, 3, sender.calls)
+	assert.Equal(t, []string{"c"}, sender.getLines())
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}, backoffs)
+	assert.Equal(t, uint64(1), sink.droppedCounts()[LoggerDropReasonSinkFailed]["warn"])
+
+	// dropped after max retries
+	sender = &fakeLogSinkSender{
+		fail: func(call int, records []*logSinkRecord) ([]*logSinkRecord, []*logSinkRecord, error) {
+			return records, nil, errors.New("ut-error")
+		},
+	}
+	sink = newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{MaxRetries: 2}, false)
+	sink.sleep = func(time.Duration) {}
+	errorOutput := &bytes.Buffer{}
+	sink.errorOutput = zapcore.AddSync(errorOutput)
+	sink.flush([]*logSinkRecord{newLogSinkRecordForTest(zapcore.InfoLevel, "a")})
+	assert.Equal(t, 3, sender.calls)
+	assert.Equal(t, uint64(1), sink.droppedCounts()[LoggerDropReasonSinkFailed]["info"])
+	assert.Contains(t, errorOutput.String(), "Failed to send 1 log lines to ut-sink sink: ut-error")
+
+	// retry disabled
+	sender.calls = 0
+	sink = newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{MaxRetries: -1}, false)
+	sink.flush([]*logSinkRecord{newLogSinkRecordForTest(zapcore.InfoLevel, "a")})
+	assert.Equal(t, 1, sender.calls)
+}
+
+func TestLogSink_BackPressure(t *testing.T) {
+	// drop
+	sink := newLogSink("ut-sink", &fakeLogSinkSender{}, &BootLoggerSinkDelivery{QueueSize: 1}, false)
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "a"))
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "b"))
+	assert.Equal(t, uint64(1), sink.droppedCounts()[LoggerDropReasonSinkFull]["info"])
+
+	// block until timed out
+	sink = newLogSink("ut-sink", &fakeLogSinkSender{}, &BootLoggerSinkDelivery{
+		QueueSize:      1,
+		OnFull:         LoggerSinkOnFullBlock,
+		BlockTimeoutMs: 20,
+	}, false)
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "a"))
+	start := time.Now()
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "b"))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	assert.Equal(t, uint64(1), sink.droppedCounts()[LoggerDropReasonSinkFull]["info"])
+
+	// block until space available
+	sender := &fakeLogSinkSender{}
+	sink = newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{
+		QueueSize:      1,
+		OnFull:         LoggerSinkOnFullBlock,
+		BlockTimeoutMs: 5000,
+	}, false)
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "a"))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		sink.start()
+	}()
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "b"))
+	sink.stop()
+	assert.Equal(t, []string{"a", "b"}, sender.getLines())
+	assert.Zero(t, sink.droppedCounts()[LoggerDropReasonSinkFull]["info"])
+}
+
+func TestLogSink_Stop(t *testing.T) {
+	sender := &fakeLogSinkSender{}
+	sink := newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{FlushIntervalMs: 60000}, false)
+
+	// buffered lines are sent even if not started
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "a"))
+	sink.stop()
+	sink.stop()
+	assert.Equal(t, []string{"a"}, sender.getLines())
+	assert.True(t, sender.closed)
+
+	// dropped after stopped
+	sink.enqueue(newLogSinkRecordForTest(zapcore.InfoLevel, "b"))
+	assert.Nil(t, sink.sync())
+	assert.Equal(t, []string{"a"}, sender.getLines())
+	assert.Equal(t, uint64(1), sink.droppedCounts()[LoggerDropReasonSinkStopped]["info"])
+	assert.Zero(t, sink.droppedCounts()[LoggerDropReasonSinkFull]["info"])
+}
+
+func TestLogSinkCore(t *testing.T) {
+	jsonSender, consoleSender := &fakeLogSinkSender{}, &fakeLogSinkSender{}
+	jsonSink := newLogSink("ut-json", jsonSender, &BootLoggerSinkDelivery{}, true)
+	consoleSink := newLogSink("ut-console", consoleSender, &BootLoggerSinkDelivery{}, false)
+	jsonSink.start()
+	consoleSink.start()
+	defer jsonSink.stop()
+	defer consoleSink.stop()
+
+	config := rklogger.NewZapStdoutConfig()
+	config.Encoding = "console"
+	config.EncoderConfig.TimeKey = ""
+	config.InitialFields = map[string]interface{}{"app": "ut-app"}
+
+	logger := zap.New(newLogSinkCore(zapcore.NewNopCore(), []*logSink{jsonSink, consoleSink}, config))
+	logger.With(zap.String("key", "value")).Info("ut-message")
+	logger.Debug("ut-debug")
+	assert.Nil(t, logger.Sync())
+
+	// json is forced
+	assert.Len(t, jsonSender.getLines(), 1)
+	doc := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(jsonSender.getLines()[0]), &doc))
+	assert.Equal(t, "ut-message", doc["msg"])
+	assert.Equal(t, "value", doc["key"])
+	assert.Equal(t, "ut-app", doc["app"])
+
+	// line ending is trimmed
+	assert.Len(t, consoleSender.getLines(), 1)
+	assert.Contains(t, consoleSender.getLines()[0], "ut-message")
+	assert.Contains(t, consoleSender.getLines()[0], `"key": "value"`)
+	assert.NotContains(t, consoleSender.getLines()[0], "\n")
+}
+
+func TestLogSinkCore_SyncAboveError(t *testing.T) {
+	sender := &fakeLogSinkSender{}
+	sink := newLogSink("ut-sink", sender, &BootLoggerSinkDelivery{FlushIntervalMs: 60000}, false)
+	sink.start()
+	defer sink.stop()
+
+	logger := zap.New(newLogSinkCore(zapcore.NewNopCore(), []*logSink{sink}, rklogger.NewZapStdoutConfig()))
+
+	// buffered until flush interval
+	logger.Error("ut-error")
+	assert.Empty(t, sender.getLines())
+
+	// flushed immediately with buffered lines
+	logger.DPanic("ut-dpanic")
+	assert.Len(t, sender.getLines(), 2)
+	assert.Contains(t, sender.getLines()[1], "ut-dpanic")
+}
+
+func TestNewLogSinkCore_WithErrorOutput(t *testing.T) {
+	sink := newLogSink("ut-sink", &fakeLogSinkSender{}, &BootLoggerSinkDelivery{}, false)
+	defaultOutput := sink.errorOutput
+
+	// stderr by default, invalid paths are ignored
+	config := rklogger.NewZapStdoutConfig()
+	config.ErrorOutputPaths = []string{"invalid://path"}
+	newLogSinkCore(zapcore.NewNopCore(), []*logSink{sink}, config)
+	assert.Equal(t, defaultOutput, sink.errorOutput)
+
+	// opened once and closed while sink stopped
+	config.ErrorOutputPaths = []string{path.Join(t.TempDir(), "error.log")}
+	newLogSinkCore(zapcore.NewNopCore(), []*logSink{sink}, config)
+	output := sink.errorOutput
+	assert.NotEqual(t, defaultOutput, output)
+
+	newLogSinkCore(zapcore.NewNopCore(), []*logSink{sink}, config)
+	assert.Equal(t, output, sink.errorOutput)
+
+	_, err := output.Write([]byte("ut-error"))
+	assert.Nil(t, err)
+	sink.stop()
+	_, err = output.Write([]byte("ut-error"))
+	assert.NotNil(t, err)
+}
+
+func TestLoggerEntry_Sinks(t *testing.T) {
+	lock := sync.Mutex{}
+	docs := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		batch := make([]map[string]interface{}, 0)
+		json.Unmarshal(body, &batch)
+		docs = append(docs, batch...)
+	}))
+	defer server.Close()
+
+	entries := RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Zap: &rklogger.ZapConfigWrap{
+					Level:       "info",
+					OutputPaths: []string{"stdout"},
+				},
+				Sampling: BootLoggerSampling{
+					Rules:            []*LoggerSamplingRule{{Message: "noisy", First: 1}},
+					ReportIntervalMs: -1,
+				},
+				Redaction: BootLoggerRedaction{
+					Enabled: true,
+				},
+				Sinks: BootLoggerSinks{
+					Http: BootLoggerHttpSink{
+						Enabled: true,
+						Url:     server.URL,
+					},
+				},
+			},
+		},
+	})
+	defer GlobalAppCtx.clearEntries()
+
+	entry := entries[0]
+	entry.Bootstrap(context.TODO())
+
+	for i := 0; i < 3; i++ {
+		entry.Info("noisy")
+	}
+	entry.Info("login", zap.String("password", redactTestPassword))
+	entry.Interrupt(context.TODO())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// sampled and redacted
+	assert.Len(t, docs, 2)
+	assert.Equal(t, "noisy", docs[0]["msg"])
+	assert.Equal(t, "[REDACTED]", docs[1]["password"])
+
+	// dropped counts of sinks
+	assert.Equal(t, uint64(2), entry.DroppedCounts()[LoggerDropReasonSampled]["info"])
+	assert.Zero(t, entry.DroppedCounts()[LoggerDropReasonSinkFull]["info"])
+	assert.Zero(t, entry.DroppedCounts()[LoggerDropReasonSinkFailed]["info"])
+}
+
+func TestRegisterLoggerEntry_InvalidSinks(t *testing.T) {
+	defer assertPanic(t)
+	defer GlobalAppCtx.clearEntries()
+
+	RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Sinks: BootLoggerSinks{
+					Syslog: BootLoggerSyslog{Enabled: true, Facility: "invalid"},
+				},
+			},
+		},
+	})
+}
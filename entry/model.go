@@ -6,6 +6,7 @@
 package rkentry
 
 import (
+	"encoding/json"
 	"github.com/rookie-ninja/rk-entry/v2/os"
 	"os"
 	"os/user"
@@ -22,36 +23,65 @@ type readyResp struct {
 	Ready bool `json:"ready" yaml:"ready" example:"true"`
 }
 
+// startupResp response of startup probe
+type startupResp struct {
+	Started bool           `json:"started" yaml:"started" example:"true"`
+	Entries []*EntryStatus `json:"entries" yaml:"entries"`
+}
+
+// entriesResp response of /entries
+// Entries are grouped by entry type.
+type entriesResp struct {
+	Entries map[string][]*entryElement `json:"entries" yaml:"entries"`
+}
+
+// entryElement element of entriesResp
+type entryElement struct {
+	EntryName        string          `json:"entryName" yaml:"entryName" example:"greeter"`
+	EntryType        string          `json:"entryType" yaml:"entryType" example:"GinEntry"`
+	EntryDescription string          `json:"entryDescription" yaml:"entryDescription" example:"Internal RK entry."`
+	State            EntryState      `json:"state,omitempty" yaml:"state,omitempty" example:"ready"`
+	EntryMeta        json.RawMessage `json:"entryMeta,omitempty" yaml:"entryMeta,omitempty" swaggertype:"object"`
+}
+
 // gcResp response of /gc
 // Returns memory stats of GC before and after.
 type gcResp struct {
-	MemStatBeforeGc *rkos.MemInfo `json:"memStatBeforeGc" yaml:"memStatBeforeGc"`
-	MemStatAfterGc  *rkos.MemInfo `json:"memStatAfterGc" yaml:"memStatAfterGc"`
+	MemStatBeforeGc     *rkos.MemInfo     `json:"memStatBeforeGc" yaml:"memStatBeforeGc"`
+	MemStatAfterGc      *rkos.MemInfo     `json:"memStatAfterGc" yaml:"memStatAfterGc"`
+	RuntimeStatBeforeGc *rkos.RuntimeInfo `json:"runtimeStatBeforeGc" yaml:"runtimeStatBeforeGc"`
+	RuntimeStatAfterGc  *rkos.RuntimeInfo `json:"runtimeStatAfterGc" yaml:"runtimeStatAfterGc"`
 }
 
 // ProcessInfo process information for a running application.
 type ProcessInfo struct {
-	AppName     string          `json:"appName" yaml:"appName" example:"rk-app"`
-	Version     string          `json:"version" yaml:"version" example:"dev"`
-	Description string          `json:"description" yaml:"description" example:"RK application"`
-	Keywords    []string        `json:"keywords" yaml:"keywords" example:""`
-	HomeUrl     string          `json:"homeUrl" yaml:"homeUrl" example:"https://example.com"`
-	DocsUrl     []string        `json:"docsUrl" yaml:"docsUrl" example:""`
-	Maintainers []string        `json:"maintainers" yaml:"maintainers" example:"rk-dev"`
-	UID         string          `json:"uid" yaml:"uid" example:"501"`
-	GID         string          `json:"gid" yaml:"gid" example:"20"`
-	Username    string          `json:"username" yaml:"username" example:"lark"`
-	StartTime   string          `json:"startTime" yaml:"startTime" example:"2022-03-15T20:43:05+08:00"`
-	UpTimeSec   int64           `json:"upTimeSec" yaml:"upTimeSec" example:"13"`
-	Region      string          `json:"region" yaml:"region" example:"us-east-1"`
-	AZ          string          `json:"az" yaml:"az" example:"us-east-1c"`
-	Realm       string          `json:"realm" yaml:"realm" example:"rookie-ninja"`
-	Domain      string          `json:"domain" yaml:"domain" example:"dev"`
-	CpuInfo     *rkos.CpuInfo   `json:"cpuInfo" yaml:"cpuInfo"`
-	MemInfo     *rkos.MemInfo   `json:"memInfo" yaml:"memInfo"`
-	NetInfo     *rkos.NetInfo   `json:"netInfo" yaml:"netInfo"`
-	OsInfo      *rkos.OsInfo    `json:"osInfo" yaml:"osInfo"`
-	GoEnvInfo   *rkos.GoEnvInfo `json:"goEnvInfo" yaml:"goEnvInfo"`
+	AppName     string            `json:"appName" yaml:"appName" example:"rk-app"`
+	Version     string            `json:"version" yaml:"version" example:"dev"`
+	Description string            `json:"description" yaml:"description" example:"RK application"`
+	Keywords    []string          `json:"keywords" yaml:"keywords" example:""`
+	HomeUrl     string            `json:"homeUrl" yaml:"homeUrl" example:"https://example.com"`
+	DocsUrl     []string          `json:"docsUrl" yaml:"docsUrl" example:""`
+	Maintainers []string          `json:"maintainers" yaml:"maintainers" example:"rk-dev"`
+	UID         string            `json:"uid" yaml:"uid" example:"501"`
+	GID         string            `json:"gid" yaml:"gid" example:"20"`
+	Username    string            `json:"username" yaml:"username" example:"lark"`
+	StartTime   string            `json:"startTime" yaml:"startTime" example:"2022-03-15T20:43:05+08:00"`
+	UpTimeSec   int64             `json:"upTimeSec" yaml:"upTimeSec" example:"13"`
+	Region      string            `json:"region" yaml:"region" example:"us-east-1"`
+	AZ          string            `json:"az" yaml:"az" example:"us-east-1c"`
+	Realm       string            `json:"realm" yaml:"realm" example:"rookie-ninja"`
+	Domain      string            `json:"domain" yaml:"domain" example:"dev"`
+	CpuInfo     *rkos.CpuInfo     `json:"cpuInfo" yaml:"cpuInfo"`
+	MemInfo     *rkos.MemInfo     `json:"memInfo" yaml:"memInfo"`
+	NetInfo     *rkos.NetInfo     `json:"netInfo" yaml:"netInfo"`
+	OsInfo      *rkos.OsInfo      `json:"osInfo" yaml:"osInfo"`
+	GoEnvInfo   *rkos.GoEnvInfo   `json:"goEnvInfo" yaml:"goEnvInfo"`
+	CgroupInfo  *rkos.CgroupInfo  `json:"cgroupInfo" yaml:"cgroupInfo"`
+	ProcInfo    *rkos.ProcInfo    `json:"procInfo" yaml:"procInfo"`
+	HostInfo    *rkos.HostInfo    `json:"hostInfo" yaml:"hostInfo"`
+	RuntimeInfo *rkos.RuntimeInfo `json:"runtimeInfo" yaml:"runtimeInfo"`
+	BuildInfo   *rkos.BuildInfo   `json:"buildInfo" yaml:"buildInfo"`
+	EnvInfo     *rkos.EnvInfo     `json:"envInfo" yaml:"envInfo"`
 }
 
 // NewProcessInfo creates a new ProcessInfo instance
@@ -66,6 +96,10 @@ func NewProcessInfo() *ProcessInfo {
 		}
 	}
 
+	// region, az, realm and domain are read from environment variables first, then detected environment,
+	// detected environment is cached, so cloud metadata endpoint would be queried only once
+	envInfo := rkos.GetEnvInfo()
+
 	return &ProcessInfo{
 		AppName:     GlobalAppCtx.GetAppInfoEntry().AppName,
 		Version:     GlobalAppCtx.GetAppInfoEntry().Version,
@@ -79,14 +113,20 @@ func NewProcessInfo() *ProcessInfo {
 		GID:         u.Gid,
 		StartTime:   GlobalAppCtx.GetStartTime().Format(time.RFC3339),
 		UpTimeSec:   int64(GlobalAppCtx.GetUpTime().Seconds()),
-		Realm:       getDefaultIfEmptyString(os.Getenv("REALM"), ""),
-		Region:      getDefaultIfEmptyString(os.Getenv("REGION"), ""),
-		AZ:          getDefaultIfEmptyString(os.Getenv("AZ"), ""),
-		Domain:      getDefaultIfEmptyString(os.Getenv("DOMAIN"), ""),
+		Realm:       getDefaultIfEmptyString(os.Getenv("REALM"), envInfo.Realm),
+		Region:      getDefaultIfEmptyString(os.Getenv("REGION"), envInfo.Region),
+		AZ:          getDefaultIfEmptyString(os.Getenv("AZ"), envInfo.AZ),
+		Domain:      getDefaultIfEmptyString(os.Getenv("DOMAIN"), envInfo.Domain),
 		CpuInfo:     rkos.NewCpuInfo(),
 		MemInfo:     rkos.NewMemInfo(),
 		NetInfo:     rkos.NewNetInfo(),
 		OsInfo:      rkos.NewOsInfo(),
 		GoEnvInfo:   rkos.NewGoEnvInfo(),
+		CgroupInfo:  rkos.NewCgroupInfo(),
+		ProcInfo:    rkos.NewProcInfo(),
+		HostInfo:    rkos.NewHostInfo(),
+		RuntimeInfo: rkos.NewRuntimeInfo(),
+		BuildInfo:   rkos.NewBuildInfo(),
+		EnvInfo:     envInfo,
 	}
 }
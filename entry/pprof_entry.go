@@ -2,26 +2,89 @@ package rkentry
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"net/http"
+	"net/http/pprof"
 	"path"
+	"runtime"
 	"strings"
 )
 
+// BootPProf bootstrap config of pprof.
+// 1: Enabled: Enable pprof entry.
+// 2: Path: Path prefix of pprof handlers, default is /pprof/.
+// 3: BlockProfileRate: Rate passed to runtime.SetBlockProfileRate, block profile disabled if 0.
+// 4: MutexProfileFraction: Rate passed to runtime.SetMutexProfileFraction, mutex profile disabled if 0.
+// 5: Auth: Basic auth credentials as user:pass or tokens required to access pprof handlers.
+// 6: Snapshot: Capture profiles into directory periodically or by triggers.
 type BootPProf struct {
-	Enabled bool   `yaml:"enabled" json:"enabled"`
-	Path    string `yaml:"path" json:"path"`
+	Enabled              bool              `yaml:"enabled" json:"enabled"`
+	Path                 string            `yaml:"path" json:"path"`
+	BlockProfileRate     int               `yaml:"blockProfileRate" json:"blockProfileRate"`
+	MutexProfileFraction int               `yaml:"mutexProfileFraction" json:"mutexProfileFraction"`
+	Auth                 BootPProfAuth     `yaml:"auth" json:"auth"`
+	Snapshot             BootPProfSnapshot `yaml:"snapshot" json:"snapshot"`
 }
 
+// BootPProfAuth bootstrap config of pprof guard.
+// Request will be allowed if it matches any of basic credentials or tokens.
+// Token could be provided with X-API-Key header or Authorization header with Bearer scheme.
+type BootPProfAuth struct {
+	Enabled bool     `yaml:"enabled" json:"enabled"`
+	Basic   []string `yaml:"basic" json:"basic"`
+	Tokens  []string `yaml:"tokens" json:"tokens"`
+}
+
+// BootPProfEntry bootstrap config of PProfEntry used by RegisterPProfEntryYAML.
+type BootPProfEntry struct {
+	PProf BootPProf `yaml:"pprof" json:"pprof"`
+}
+
+// PProfEntry serves net/http/pprof handlers under Path.
 type PProfEntry struct {
-	entryName        string `json:"-" yaml:"-"`
-	entryType        string `json:"-" yaml:"-"`
-	entryDescription string `json:"-" yaml:"-"`
-	Path             string `json:"-" yaml:"-"`
+	entryName            string            `json:"-" yaml:"-"`
+	entryType            string            `json:"-" yaml:"-"`
+	entryDescription     string            `json:"-" yaml:"-"`
+	Path                 string            `json:"-" yaml:"-"`
+	BlockProfileRate     int               `json:"-" yaml:"-"`
+	MutexProfileFraction int               `json:"-" yaml:"-"`
+	authEnabled          bool              `json:"-" yaml:"-"`
+	basicCredentials     []string          `json:"-" yaml:"-"`
+	tokens               []string          `json:"-" yaml:"-"`
+	snapshotter          *pprofSnapshotter `json:"-" yaml:"-"`
+}
+
+// Bootstrap set block and mutex profile rate if configured.
+func (entry *PProfEntry) Bootstrap(ctx context.Context) {
+	if entry.BlockProfileRate > 0 {
+		runtime.SetBlockProfileRate(entry.BlockProfileRate)
+	}
+
+	if entry.MutexProfileFraction > 0 {
+		runtime.SetMutexProfileFraction(entry.MutexProfileFraction)
+	}
+
+	if entry.snapshotter != nil {
+		entry.snapshotter.start()
+	}
 }
 
-func (entry *PProfEntry) Bootstrap(ctx context.Context) {}
+// Interrupt disable block and mutex profile if enabled in Bootstrap and stop snapshots.
+func (entry *PProfEntry) Interrupt(ctx context.Context) {
+	if entry.snapshotter != nil {
+		entry.snapshotter.stop()
+	}
 
-func (entry *PProfEntry) Interrupt(ctx context.Context) {}
+	if entry.BlockProfileRate > 0 {
+		runtime.SetBlockProfileRate(0)
+	}
+
+	if entry.MutexProfileFraction > 0 {
+		runtime.SetMutexProfileFraction(0)
+	}
+}
 
 func (entry *PProfEntry) GetName() string {
 	return entry.entryName
@@ -43,10 +106,14 @@ func (entry *PProfEntry) String() string {
 // MarshalJSON Marshal entry
 func (entry *PProfEntry) MarshalJSON() ([]byte, error) {
 	m := map[string]interface{}{
-		"name":        entry.GetName(),
-		"type":        entry.GetType(),
-		"description": entry.GetDescription(),
-		"path":        entry.Path,
+		"name":                 entry.GetName(),
+		"type":                 entry.GetType(),
+		"description":          entry.GetDescription(),
+		"path":                 entry.Path,
+		"blockProfileRate":     entry.BlockProfileRate,
+		"mutexProfileFraction": entry.MutexProfileFraction,
+		"authEnabled":          entry.authEnabled,
+		"snapshotEnabled":      entry.snapshotter != nil,
 	}
 
 	return json.Marshal(m)
@@ -57,6 +124,101 @@ func (entry *PProfEntry) UnmarshalJSON([]byte) error {
 	return nil
 }
 
+// Handler returns http.Handler which should be mounted under Path.
+//
+// Serves index, profile, heap, goroutine, block, mutex, trace, cmdline, symbol, allocs and threadcreate.
+// Captured snapshots are listed with snapshots/ and downloaded with snapshots/<name> if enabled.
+func (entry *PProfEntry) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !entry.authorized(request) {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="pprof"`)
+			writeJSON(writer, http.StatusUnauthorized,
+				GlobalAppCtx.GetErrorBuilder().New(http.StatusUnauthorized, "unauthorized to access pprof"))
+			return
+		}
+
+		var name string
+		switch {
+		case strings.HasPrefix(request.URL.Path, entry.Path):
+			name = strings.TrimPrefix(request.URL.Path, entry.Path)
+		case request.URL.Path+"/" == entry.Path:
+			// relative links of index page only work with trailing slash
+			target := entry.Path
+			if len(request.URL.RawQuery) > 0 {
+				target += "?" + request.URL.RawQuery
+			}
+			http.Redirect(writer, request, target, http.StatusMovedPermanently)
+			return
+		default:
+			http.NotFound(writer, request)
+			return
+		}
+
+		switch name {
+		case "":
+			pprof.Index(writer, request)
+		case "profile":
+			pprof.Profile(writer, request)
+		case "trace":
+			pprof.Trace(writer, request)
+		case "cmdline":
+			pprof.Cmdline(writer, request)
+		case "symbol":
+			pprof.Symbol(writer, request)
+		case "heap", "goroutine", "block", "mutex", "allocs", "threadcreate":
+			pprof.Handler(name).ServeHTTP(writer, request)
+		case "snapshots", "snapshots/":
+			if entry.snapshotter == nil {
+				http.NotFound(writer, request)
+				return
+			}
+			entry.snapshotter.serveList(writer)
+		default:
+			if entry.snapshotter != nil && strings.HasPrefix(name, "snapshots/") {
+				entry.snapshotter.serveFile(writer, request, strings.TrimPrefix(name, "snapshots/"))
+				return
+			}
+			http.NotFound(writer, request)
+		}
+	})
+}
+
+// authorized validates basic credentials or tokens if auth enabled
+func (entry *PProfEntry) authorized(request *http.Request) bool {
+	if !entry.authEnabled {
+		return true
+	}
+
+	authHeader := request.Header.Get("Authorization")
+
+	// 1: basic auth
+	if strings.HasPrefix(authHeader, "Basic ") {
+		if raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authHeader, "Basic ")); err == nil {
+			for i := range entry.basicCredentials {
+				if subtle.ConstantTimeCompare(raw, []byte(entry.basicCredentials[i])) == 1 {
+					return true
+				}
+			}
+		}
+	}
+
+	// 2: token
+	token := request.Header.Get("X-API-Key")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		token = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+
+	if len(token) > 0 {
+		for i := range entry.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(entry.tokens[i])) == 1 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 type PProfEntryOption func(entry *PProfEntry)
 
 func WithNamePProfEntry(name string) PProfEntryOption {
@@ -72,10 +234,19 @@ func RegisterPProfEntry(boot *BootPProf, opts ...PProfEntryOption) *PProfEntry {
 	}
 
 	entry := &PProfEntry{
-		entryName:        "PProfEntry",
-		entryType:        PProfEntryType,
-		entryDescription: "Internal RK entry for pprof.",
-		Path:             boot.Path,
+		entryName:            "PProfEntry",
+		entryType:            PProfEntryType,
+		entryDescription:     "Internal RK entry for pprof.",
+		Path:                 boot.Path,
+		BlockProfileRate:     boot.BlockProfileRate,
+		MutexProfileFraction: boot.MutexProfileFraction,
+		authEnabled:          boot.Auth.Enabled,
+		basicCredentials:     boot.Auth.Basic,
+		tokens:               boot.Auth.Tokens,
+	}
+
+	if boot.Snapshot.Enabled {
+		entry.snapshotter = newPProfSnapshotter(&boot.Snapshot)
 	}
 
 	for i := range opts {
@@ -93,3 +264,18 @@ func RegisterPProfEntry(boot *BootPProf, opts ...PProfEntryOption) *PProfEntry {
 
 	return entry
 }
+
+// RegisterPProfEntryYAML register function
+func RegisterPProfEntryYAML(raw []byte) map[string]Entry {
+	boot := &BootPProfEntry{}
+	UnmarshalBootYAML(raw, boot)
+
+	res := map[string]Entry{}
+
+	if entry := RegisterPProfEntry(&boot.PProf); entry != nil {
+		GlobalAppCtx.AddEntry(entry)
+		res[entry.GetName()] = entry
+	}
+
+	return res
+}
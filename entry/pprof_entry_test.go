@@ -3,6 +3,9 @@ package rkentry
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
 	"testing"
 )
 
@@ -19,6 +22,37 @@ func TestRegisterPProfEntry(t *testing.T) {
 	assert.NotEmpty(t, entry.String())
 }
 
+func TestRegisterPProfEntryYAML(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	bootStr := `
+---
+pprof:
+  enabled: true
+  path: ut-path
+  blockProfileRate: 1
+  mutexProfileFraction: 1
+  auth:
+    enabled: true
+    basic: ["user:pass"]
+    tokens: ["ut-token"]
+`
+	entries := RegisterPProfEntryYAML([]byte(bootStr))
+	assert.Len(t, entries, 1)
+
+	entry := entries["PProfEntry"].(*PProfEntry)
+	assert.Equal(t, "/ut-path/", entry.Path)
+	assert.Equal(t, 1, entry.BlockProfileRate)
+	assert.Equal(t, 1, entry.MutexProfileFraction)
+	assert.True(t, entry.authEnabled)
+	assert.Equal(t, []string{"user:pass"}, entry.basicCredentials)
+	assert.Equal(t, []string{"ut-token"}, entry.tokens)
+	assert.NotNil(t, GlobalAppCtx.GetEntry(PProfEntryType, "PProfEntry"))
+
+	// disabled
+	assert.Empty(t, RegisterPProfEntryYAML([]byte("pprof:\n  enabled: false")))
+}
+
 func TestPProfEntry_Bootstrap_Interrupt(t *testing.T) {
 	defer assertNotPanic(t)
 
@@ -31,6 +65,21 @@ func TestPProfEntry_Bootstrap_Interrupt(t *testing.T) {
 	entry.Interrupt(context.TODO())
 }
 
+func TestPProfEntry_Bootstrap_WithProfileRates(t *testing.T) {
+	entry := RegisterPProfEntry(&BootPProf{
+		Enabled:              true,
+		Path:                 "ut-path",
+		BlockProfileRate:     1,
+		MutexProfileFraction: 1,
+	})
+
+	// rates are applied while running and reset after interrupted
+	entry.Bootstrap(context.TODO())
+	assert.Equal(t, 1, runtime.SetMutexProfileFraction(-1))
+	entry.Interrupt(context.TODO())
+	assert.Equal(t, 0, runtime.SetMutexProfileFraction(-1))
+}
+
 func TestPProfEntry_UnmarshalJSON(t *testing.T) {
 	entry := RegisterPProfEntry(&BootPProf{
 		Enabled: true,
@@ -38,3 +87,89 @@ func TestPProfEntry_UnmarshalJSON(t *testing.T) {
 	})
 	assert.Nil(t, entry.UnmarshalJSON(nil))
 }
+
+func TestPProfEntry_Handler(t *testing.T) {
+	entry := RegisterPProfEntry(&BootPProf{
+		Enabled: true,
+	})
+	handler := entry.Handler()
+
+	// index
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Contains(t, writer.Body.String(), "goroutine")
+
+	// index without trailing slash is redirected
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof", nil))
+	assert.Equal(t, http.StatusMovedPermanently, writer.Code)
+	assert.Equal(t, "/pprof/", writer.Header().Get("Location"))
+
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof?debug=1", nil))
+	assert.Equal(t, "/pprof/?debug=1", writer.Header().Get("Location"))
+
+	// named profiles
+	for _, name := range []string{"heap", "goroutine", "block", "mutex", "cmdline"} {
+		writer = httptest.NewRecorder()
+		handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/"+name, nil))
+		assert.Equal(t, http.StatusOK, writer.Code, name)
+	}
+
+	// unknown profile
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+
+	// path outside of prefix
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/other/heap", nil))
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+}
+
+func TestPProfEntry_Handler_WithAuth(t *testing.T) {
+	entry := RegisterPProfEntry(&BootPProf{
+		Enabled: true,
+		Auth: BootPProfAuth{
+			Enabled: true,
+			Basic:   []string{"user:pass"},
+			Tokens:  []string{"ut-token"},
+		},
+	})
+	handler := entry.Handler()
+
+	// without credentials
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/cmdline", nil))
+	assert.Equal(t, http.StatusUnauthorized, writer.Code)
+	assert.NotEmpty(t, writer.Header().Get("WWW-Authenticate"))
+
+	// with wrong basic auth
+	req := httptest.NewRequest(http.MethodGet, "/pprof/cmdline", nil)
+	req.SetBasicAuth("user", "wrong")
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, req)
+	assert.Equal(t, http.StatusUnauthorized, writer.Code)
+
+	// with basic auth
+	req = httptest.NewRequest(http.MethodGet, "/pprof/cmdline", nil)
+	req.SetBasicAuth("user", "pass")
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, req)
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	// with api key
+	req = httptest.NewRequest(http.MethodGet, "/pprof/cmdline", nil)
+	req.Header.Set("X-API-Key", "ut-token")
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, req)
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	// with bearer token
+	req = httptest.NewRequest(http.MethodGet, "/pprof/cmdline", nil)
+	req.Header.Set("Authorization", "Bearer ut-token")
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, req)
+	assert.Equal(t, http.StatusOK, writer.Code)
+}
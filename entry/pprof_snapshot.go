@@ -0,0 +1,394 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rookie-ninja/rk-entry/v2/os"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pprofSnapshotSuffix     = ".pprof"
+	pprofSnapshotTimeLayout = "20060102T150405.000"
+
+	// PProfSnapshotReasonSchedule snapshot captured by schedule
+	PProfSnapshotReasonSchedule = "schedule"
+	// PProfSnapshotReasonHeap snapshot captured since heap is above threshold
+	PProfSnapshotReasonHeap = "heap"
+	// PProfSnapshotReasonGoroutine snapshot captured since goroutine count is above threshold
+	PProfSnapshotReasonGoroutine = "goroutine"
+	// PProfSnapshotReasonManual snapshot captured by calling CaptureSnapshot
+	PProfSnapshotReasonManual = "manual"
+)
+
+// BootPProfSnapshot bootstrap config of continuous profiling.
+// 1: Enabled: Enable snapshots.
+// 2: Dir: Directory where profile files would be written to, default is pprof-snapshots.
+// 3: Profiles: Profiles to capture, one of cpu, heap, goroutine, allocs, block, mutex, threadcreate. Default is cpu, heap, goroutine.
+// 4: IntervalSec: Capture profiles periodically, disabled if 0.
+// 5: CpuDurationSec: Duration of cpu profile, default is 10 seconds.
+// 6: MaxFiles: Max number of profile files to keep, default is 100.
+// 7: MaxAgeHours: Max age of profile files to keep, disabled if 0.
+// 8: Trigger: Capture profiles if heap or goroutine count is above threshold.
+type BootPProfSnapshot struct {
+	Enabled        bool                     `yaml:"enabled" json:"enabled"`
+	Dir            string                   `yaml:"dir" json:"dir"`
+	Profiles       []string                 `yaml:"profiles" json:"profiles"`
+	IntervalSec    int                      `yaml:"intervalSec" json:"intervalSec"`
+	CpuDurationSec int                      `yaml:"cpuDurationSec" json:"cpuDurationSec"`
+	MaxFiles       int                      `yaml:"maxFiles" json:"maxFiles"`
+	MaxAgeHours    int                      `yaml:"maxAgeHours" json:"maxAgeHours"`
+	Trigger        BootPProfSnapshotTrigger `yaml:"trigger" json:"trigger"`
+}
+
+// BootPProfSnapshotTrigger bootstrap config of snapshot triggers.
+// 1: HeapMb: Capture if rkos.MemInfo.MemUsedMb is above, disabled if 0.
+// 2: Goroutines: Capture if rkos.GoEnvInfo.RoutinesCount is above, disabled if 0.
+// 3: CheckIntervalSec: Interval of checking thresholds, default is 10 seconds.
+// 4: CooldownSec: Min interval between two triggered captures, default is 300 seconds.
+type BootPProfSnapshotTrigger struct {
+	HeapMb           uint64 `yaml:"heapMb" json:"heapMb"`
+	Goroutines       int    `yaml:"goroutines" json:"goroutines"`
+	CheckIntervalSec int    `yaml:"checkIntervalSec" json:"checkIntervalSec"`
+	CooldownSec      int    `yaml:"cooldownSec" json:"cooldownSec"`
+}
+
+// PProfSnapshot describes a profile file captured by PProfEntry
+type PProfSnapshot struct {
+	Name      string    `json:"name" yaml:"name" example:"heap-20220315T204305.000-schedule.pprof"`
+	Profile   string    `json:"profile" yaml:"profile" example:"heap"`
+	Reason    string    `json:"reason" yaml:"reason" example:"schedule"`
+	SizeByte  int64     `json:"sizeByte" yaml:"sizeByte" example:"1024"`
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt" example:"2022-03-15T20:43:05+08:00"`
+}
+
+// pprofSnapshotter captures profiles into directory periodically or by triggers
+type pprofSnapshotter struct {
+	dir             string
+	profiles        []string
+	interval        time.Duration
+	cpuDuration     time.Duration
+	maxFiles        int
+	maxAge          time.Duration
+	heapMb          uint64
+	goroutines      int
+	checkInterval   time.Duration
+	cooldown        time.Duration
+	lastTriggeredAt time.Time
+	captureLock     sync.Mutex
+	quitChannel     chan struct{}
+	waitGroup       sync.WaitGroup
+	startOnce       sync.Once
+	stopOnce        sync.Once
+}
+
+// newPProfSnapshotter creates snapshotter with default values filled
+func newPProfSnapshotter(boot *BootPProfSnapshot) *pprofSnapshotter {
+	res := &pprofSnapshotter{
+		dir:           boot.Dir,
+		profiles:      boot.Profiles,
+		interval:      time.Duration(boot.IntervalSec) * time.Second,
+		cpuDuration:   time.Duration(boot.CpuDurationSec) * time.Second,
+		maxFiles:      boot.MaxFiles,
+		maxAge:        time.Duration(boot.MaxAgeHours) * time.Hour,
+		heapMb:        boot.Trigger.HeapMb,
+		goroutines:    boot.Trigger.Goroutines,
+		checkInterval: time.Duration(boot.Trigger.CheckIntervalSec) * time.Second,
+		cooldown:      time.Duration(boot.Trigger.CooldownSec) * time.Second,
+		quitChannel:   make(chan struct{}),
+	}
+
+	if len(res.dir) < 1 {
+		res.dir = "pprof-snapshots"
+	}
+
+	if len(res.profiles) < 1 {
+		res.profiles = []string{"cpu", "heap", "goroutine"}
+	}
+
+	if res.cpuDuration <= 0 {
+		res.cpuDuration = 10 * time.Second
+	}
+
+	if res.maxFiles < 1 {
+		res.maxFiles = 100
+	}
+
+	if res.checkInterval <= 0 {
+		res.checkInterval = 10 * time.Second
+	}
+
+	if res.cooldown <= 0 {
+		res.cooldown = 300 * time.Second
+	}
+
+	return res
+}
+
+// start background goroutines of schedule and triggers
+func (s *pprofSnapshotter) start() {
+	s.startOnce.Do(func() {
+		if s.interval > 0 {
+			s.waitGroup.Add(1)
+			go s.loop(s.interval, func() {
+				s.captureAndLog(PProfSnapshotReasonSchedule)
+			})
+		}
+
+		if s.heapMb > 0 || s.goroutines > 0 {
+			s.waitGroup.Add(1)
+			go s.loop(s.checkInterval, s.checkTriggers)
+		}
+	})
+}
+
+// stop background goroutines and wait for them to exit
+func (s *pprofSnapshotter) stop() {
+	s.stopOnce.Do(func() {
+		close(s.quitChannel)
+		s.waitGroup.Wait()
+	})
+}
+
+func (s *pprofSnapshotter) loop(interval time.Duration, f func()) {
+	defer s.waitGroup.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quitChannel:
+			return
+		case <-ticker.C:
+			f()
+		}
+	}
+}
+
+// checkTriggers captures snapshot if any threshold exceeded and not in cooldown
+func (s *pprofSnapshotter) checkTriggers() {
+	reason := ""
+
+	if s.heapMb > 0 && rkos.NewMemInfo().MemUsedMb > s.heapMb {
+		reason = PProfSnapshotReasonHeap
+	} else if s.goroutines > 0 && rkos.NewGoEnvInfo().RoutinesCount > s.goroutines {
+		reason = PProfSnapshotReasonGoroutine
+	}
+
+	if len(reason) < 1 || time.Since(s.lastTriggeredAt) < s.cooldown {
+		return
+	}
+
+	s.lastTriggeredAt = time.Now()
+	s.captureAndLog(reason)
+}
+
+func (s *pprofSnapshotter) captureAndLog(reason string) {
+	if _, err := s.capture(context.Background(), reason); err != nil {
+		GlobalAppCtx.GetLoggerEntryDefault().Warn("Failed to capture pprof snapshot",
+			zap.String("reason", reason),
+			zap.Error(err))
+	}
+}
+
+// capture writes configured profiles into directory and applies retention.
+// Profiles not yet written are skipped once ctx is done.
+func (s *pprofSnapshotter) capture(ctx context.Context, reason string) ([]string, error) {
+	s.captureLock.Lock()
+	defer s.captureLock.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0)
+	errs := make([]string, 0)
+	now := time.Now()
+
+	for _, profile := range s.profiles {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", profile, err))
+			continue
+		}
+
+		name := fmt.Sprintf("%s-%s-%s%s", profile, now.Format(pprofSnapshotTimeLayout), reason, pprofSnapshotSuffix)
+		if err := s.writeProfile(ctx, profile, filepath.Join(s.dir, name)); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", profile, err))
+			continue
+		}
+		res = append(res, name)
+	}
+
+	s.applyRetention()
+
+	if len(errs) > 0 {
+		return res, errors.New(strings.Join(errs, "; "))
+	}
+
+	return res, nil
+}
+
+// writeProfile writes single profile into file, file would be removed if failed.
+// CPU profile is stopped early if ctx is done, and ctx.Err() is returned.
+func (s *pprofSnapshotter) writeProfile(ctx context.Context, profile, filePath string) (err error) {
+	var p *pprof.Profile
+	if profile != "cpu" {
+		if p = pprof.Lookup(profile); p == nil {
+			return fmt.Errorf("unknown profile %s", profile)
+		}
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(filePath)
+		}
+	}()
+
+	if p != nil {
+		return p.WriteTo(file, 0)
+	}
+
+	if err = pprof.StartCPUProfile(file); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(s.cpuDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-s.quitChannel:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	pprof.StopCPUProfile()
+	return err
+}
+
+// applyRetention removes profile files which are too old or beyond max files
+func (s *pprofSnapshotter) applyRetention() {
+	snapshots := s.list()
+
+	for i := range snapshots {
+		if i >= s.maxFiles || (s.maxAge > 0 && time.Since(snapshots[i].CreatedAt) > s.maxAge) {
+			os.Remove(filepath.Join(s.dir, snapshots[i].Name))
+		}
+	}
+}
+
+// list returns profile files in directory sorted by newest first
+func (s *pprofSnapshotter) list() []*PProfSnapshot {
+	res := make([]*PProfSnapshot, 0)
+
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return res
+	}
+
+	for i := range files {
+		snapshot := parsePProfSnapshot(files[i])
+		if snapshot != nil {
+			res = append(res, snapshot)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.After(res[j].CreatedAt)
+	})
+
+	return res
+}
+
+// parsePProfSnapshot parse file name as profile-time-reason.pprof, returns nil if not matched
+func parsePProfSnapshot(file os.DirEntry) *PProfSnapshot {
+	if file.IsDir() || !strings.HasSuffix(file.Name(), pprofSnapshotSuffix) {
+		return nil
+	}
+
+	tokens := strings.SplitN(strings.TrimSuffix(file.Name(), pprofSnapshotSuffix), "-", 3)
+	if len(tokens) != 3 {
+		return nil
+	}
+
+	createdAt, err := time.ParseInLocation(pprofSnapshotTimeLayout, tokens[1], time.Local)
+	if err != nil {
+		return nil
+	}
+
+	info, err := file.Info()
+	if err != nil {
+		return nil
+	}
+
+	return &PProfSnapshot{
+		Name:      file.Name(),
+		Profile:   tokens[0],
+		Reason:    tokens[2],
+		SizeByte:  info.Size(),
+		CreatedAt: createdAt,
+	}
+}
+
+// serveList writes list of snapshots as JSON
+func (s *pprofSnapshotter) serveList(writer http.ResponseWriter) {
+	writeJSON(writer, http.StatusOK, s.list())
+}
+
+// serveFile writes profile file as attachment
+func (s *pprofSnapshotter) serveFile(writer http.ResponseWriter, request *http.Request, name string) {
+	if name != filepath.Base(name) || !strings.HasSuffix(name, pprofSnapshotSuffix) {
+		http.NotFound(writer, request)
+		return
+	}
+
+	filePath := filepath.Join(s.dir, name)
+	if !fileExists(filePath) {
+		http.NotFound(writer, request)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/octet-stream")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	http.ServeFile(writer, request, filePath)
+}
+
+// CaptureSnapshot captures configured profiles into snapshot directory immediately.
+// Returns names of captured files. Capture of CPU profile is cancelled once ctx is done.
+func (entry *PProfEntry) CaptureSnapshot(ctx context.Context) ([]string, error) {
+	if entry.snapshotter == nil {
+		return nil, errors.New("snapshot is not enabled")
+	}
+
+	return entry.snapshotter.capture(ctx, PProfSnapshotReasonManual)
+}
+
+// ListSnapshots returns captured profile files sorted by newest first.
+func (entry *PProfEntry) ListSnapshots() []*PProfSnapshot {
+	if entry.snapshotter == nil {
+		return []*PProfSnapshot{}
+	}
+
+	return entry.snapshotter.list()
+}
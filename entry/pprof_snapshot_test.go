@@ -0,0 +1,195 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewPProfSnapshotter_WithDefault(t *testing.T) {
+	s := newPProfSnapshotter(&BootPProfSnapshot{})
+	assert.Equal(t, "pprof-snapshots", s.dir)
+	assert.Equal(t, []string{"cpu", "heap", "goroutine"}, s.profiles)
+	assert.Equal(t, 10*time.Second, s.cpuDuration)
+	assert.Equal(t, 100, s.maxFiles)
+	assert.Equal(t, 10*time.Second, s.checkInterval)
+	assert.Equal(t, 300*time.Second, s.cooldown)
+}
+
+func TestPProfEntry_CaptureSnapshot(t *testing.T) {
+	// without snapshot enabled
+	entry := RegisterPProfEntry(&BootPProf{
+		Enabled: true,
+	})
+	_, err := entry.CaptureSnapshot(context.TODO())
+	assert.NotNil(t, err)
+	assert.Empty(t, entry.ListSnapshots())
+
+	dir := t.TempDir()
+	entry = RegisterPProfEntry(&BootPProf{
+		Enabled: true,
+		Snapshot: BootPProfSnapshot{
+			Enabled:  true,
+			Dir:      dir,
+			Profiles: []string{"cpu", "heap", "goroutine", "unknown"},
+		},
+	})
+	entry.snapshotter.cpuDuration = 10 * time.Millisecond
+
+	names, err := entry.CaptureSnapshot(context.TODO())
+	// unknown profile
+	assert.NotNil(t, err)
+	assert.Len(t, names, 3)
+	for i := range names {
+		assert.FileExists(t, filepath.Join(dir, names[i]))
+	}
+
+	snapshots := entry.ListSnapshots()
+	assert.Len(t, snapshots, 3)
+	assert.Equal(t, PProfSnapshotReasonManual, snapshots[0].Reason)
+	assert.True(t, snapshots[0].SizeByte > 0)
+}
+
+func TestPProfEntry_CaptureSnapshot_WithCancelledContext(t *testing.T) {
+	dir := t.TempDir()
+	entry := RegisterPProfEntry(&BootPProf{
+		Enabled: true,
+		Snapshot: BootPProfSnapshot{
+			Enabled:  true,
+			Dir:      dir,
+			Profiles: []string{"cpu", "heap"},
+		},
+	})
+	entry.snapshotter.cpuDuration = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	names, err := entry.CaptureSnapshot(ctx)
+	assert.True(t, time.Since(start) < 10*time.Second)
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+	assert.Empty(t, names)
+	assert.Empty(t, entry.ListSnapshots())
+}
+
+func TestPProfSnapshotter_Retention(t *testing.T) {
+	dir := t.TempDir()
+
+	// files not belong to snapshots should be ignored
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "other.txt"), []byte("other"), 0644))
+	// expired file
+	old := time.Now().Add(-2 * time.Hour).Format(pprofSnapshotTimeLayout)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "heap-"+old+"-schedule.pprof"), []byte("old"), 0644))
+
+	s := newPProfSnapshotter(&BootPProfSnapshot{
+		Dir:         dir,
+		Profiles:    []string{"heap"},
+		MaxFiles:    2,
+		MaxAgeHours: 1,
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := s.capture(context.TODO(), PProfSnapshotReasonSchedule)
+		assert.Nil(t, err)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	snapshots := s.list()
+	assert.Len(t, snapshots, 2)
+	for i := range snapshots {
+		assert.True(t, time.Since(snapshots[i].CreatedAt) < time.Hour)
+	}
+	assert.FileExists(t, filepath.Join(dir, "other.txt"))
+}
+
+func TestPProfSnapshotter_Triggers(t *testing.T) {
+	dir := t.TempDir()
+
+	s := newPProfSnapshotter(&BootPProfSnapshot{
+		Dir:      dir,
+		Profiles: []string{"goroutine"},
+		Trigger: BootPProfSnapshotTrigger{
+			Goroutines: 1,
+		},
+	})
+
+	s.checkTriggers()
+	snapshots := s.list()
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, PProfSnapshotReasonGoroutine, snapshots[0].Reason)
+
+	// within cooldown
+	s.checkTriggers()
+	assert.Len(t, s.list(), 1)
+}
+
+func TestPProfSnapshotter_Schedule(t *testing.T) {
+	dir := t.TempDir()
+
+	s := newPProfSnapshotter(&BootPProfSnapshot{
+		Dir:      dir,
+		Profiles: []string{"heap"},
+	})
+	s.interval = 10 * time.Millisecond
+
+	s.start()
+	assert.Eventually(t, func() bool {
+		return len(s.list()) > 0
+	}, time.Second, 10*time.Millisecond)
+	s.stop()
+	// stop twice should not panic
+	s.stop()
+}
+
+func TestPProfEntry_Handler_WithSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	entry := RegisterPProfEntry(&BootPProf{
+		Enabled: true,
+		Snapshot: BootPProfSnapshot{
+			Enabled:  true,
+			Dir:      dir,
+			Profiles: []string{"heap"},
+		},
+	})
+	handler := entry.Handler()
+
+	names, err := entry.CaptureSnapshot(context.TODO())
+	assert.Nil(t, err)
+
+	// list
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/snapshots", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+	snapshots := make([]*PProfSnapshot, 0)
+	assert.Nil(t, json.Unmarshal(writer.Body.Bytes(), &snapshots))
+	assert.Len(t, snapshots, 1)
+
+	// download
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/snapshots/"+names[0], nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Contains(t, writer.Header().Get("Content-Disposition"), names[0])
+	assert.NotEmpty(t, writer.Body.Bytes())
+
+	// missing file
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/snapshots/missing.pprof", nil))
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+
+	// path traversal
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/pprof/snapshots/..%2F..%2Fetc%2Fpasswd", nil))
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+}
@@ -0,0 +1,437 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go.uber.org/zap"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// all states exported by rk_entry_state, current state is 1 and others are 0
+var promEntryStates = []EntryState{
+	EntryStateRegistered,
+	EntryStateBootstrapping,
+	EntryStateReady,
+	EntryStateFailed,
+	EntryStateInterrupting,
+	EntryStateStopped,
+}
+
+// BootProm bootstrap config of prometheus.
+// 1: Enabled: Enable prometheus entry.
+// 2: Path: Path of metrics handler, default is /metrics.
+// 3: Namespace: Namespace of metrics registered by PromEntry, default is rk.
+// 4: Subsystem: Subsystem of metrics registered by PromEntry.
+// 5: Format: One of text and openmetrics, default is text. OpenMetrics includes exemplars.
+// 6: Pusher: Push metrics to pushgateway periodically.
+// 7: StatsD: Export metrics to statsd or dogstatsd agent periodically.
+type BootProm struct {
+	Enabled   bool           `yaml:"enabled" json:"enabled"`
+	Path      string         `yaml:"path" json:"path"`
+	Namespace string         `yaml:"namespace" json:"namespace"`
+	Subsystem string         `yaml:"subsystem" json:"subsystem"`
+	Format    string         `yaml:"format" json:"format"`
+	Pusher    BootPromPusher `yaml:"pusher" json:"pusher"`
+	StatsD    BootPromStatsD `yaml:"statsd" json:"statsd"`
+}
+
+// BootPromPusher bootstrap config of pushgateway pusher.
+// 1: Enabled: Enable pusher.
+// 2: JobName: Job name of pushed metrics, default is name of application.
+// 3: RemoteAddress: Address of pushgateway like http://localhost:9091, required if enabled.
+// 4: IntervalMs: Interval of push, default is 1000 milliseconds.
+// 5: BasicAuth: Basic auth credential as user:pass.
+type BootPromPusher struct {
+	Enabled       bool   `yaml:"enabled" json:"enabled"`
+	JobName       string `yaml:"jobName" json:"jobName"`
+	RemoteAddress string `yaml:"remoteAddress" json:"remoteAddress"`
+	IntervalMs    int64  `yaml:"intervalMs" json:"intervalMs"`
+	BasicAuth     string `yaml:"basicAuth" json:"basicAuth"`
+}
+
+// BootPromEntry bootstrap config of PromEntry used by RegisterPromEntryYAML.
+type BootPromEntry struct {
+	Prom BootProm `yaml:"prom" json:"prom"`
+}
+
+// PromEntry exposes metrics of process, go runtime, entries and loggers with prometheus text or OpenMetrics format.
+//
+// Collectors registered into Registry would be exposed with Handler as well.
+type PromEntry struct {
+	entryName        string               `json:"-" yaml:"-"`
+	entryType        string               `json:"-" yaml:"-"`
+	entryDescription string               `json:"-" yaml:"-"`
+	Path             string               `json:"-" yaml:"-"`
+	Namespace        string               `json:"-" yaml:"-"`
+	Subsystem        string               `json:"-" yaml:"-"`
+	Format           string               `json:"-" yaml:"-"`
+	Registry         *prometheus.Registry `json:"-" yaml:"-"`
+	Pusher           *PromPusher          `json:"-" yaml:"-"`
+	StatsD           *PromStatsDExporter  `json:"-" yaml:"-"`
+	registerOnce     sync.Once            `json:"-" yaml:"-"`
+}
+
+// PromEntryOption option for PromEntry
+type PromEntryOption func(entry *PromEntry)
+
+// WithNamePromEntry provide name.
+func WithNamePromEntry(name string) PromEntryOption {
+	return func(entry *PromEntry) {
+		entry.entryName = name
+	}
+}
+
+// WithRegistryPromEntry provide prometheus.Registry.
+func WithRegistryPromEntry(registry *prometheus.Registry) PromEntryOption {
+	return func(entry *PromEntry) {
+		if registry != nil {
+			entry.Registry = registry
+		}
+	}
+}
+
+// RegisterPromEntry create PromEntry with config, nil returned if not enabled
+func RegisterPromEntry(boot *BootProm, opts ...PromEntryOption) *PromEntry {
+	if !boot.Enabled {
+		return nil
+	}
+
+	entry := &PromEntry{
+		entryName:        "PromEntry",
+		entryType:        PromEntryType,
+		entryDescription: "Internal RK entry which exposes prometheus metrics.",
+		Path:             boot.Path,
+		Namespace:        boot.Namespace,
+		Subsystem:        boot.Subsystem,
+		Format:           strings.ToLower(boot.Format),
+		Registry:         prometheus.NewRegistry(),
+	}
+
+	for i := range opts {
+		opts[i](entry)
+	}
+
+	if len(entry.Path) < 1 {
+		entry.Path = "/metrics"
+	}
+	entry.Path = path.Join("/", entry.Path)
+
+	if len(entry.Namespace) < 1 {
+		entry.Namespace = "rk"
+	}
+
+	switch entry.Format {
+	case "":
+		entry.Format = PromFormatText
+	case PromFormatText, PromFormatOpenMetrics:
+	default:
+		ShutdownWithError(fmt.Errorf("prom format %s is not supported", boot.Format))
+	}
+
+	if boot.Pusher.Enabled {
+		entry.Pusher = NewPromPusher(&boot.Pusher, entry.Registry)
+	}
+
+	if boot.StatsD.Enabled {
+		entry.StatsD = NewPromStatsDExporter(&boot.StatsD, entry.Registry)
+	}
+
+	return entry
+}
+
+// RegisterPromEntryYAML register function
+func RegisterPromEntryYAML(raw []byte) map[string]Entry {
+	boot := &BootPromEntry{}
+	UnmarshalBootYAML(raw, boot)
+
+	res := map[string]Entry{}
+
+	if entry := RegisterPromEntry(&boot.Prom); entry != nil {
+		GlobalAppCtx.AddEntry(entry)
+		res[entry.GetName()] = entry
+	}
+
+	return res
+}
+
+// Bootstrap registers builtin collectors and starts pusher and statsd exporter if enabled.
+func (entry *PromEntry) Bootstrap(ctx context.Context) {
+	entry.registerOnce.Do(func() {
+		entry.Registry.MustRegister(
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			collectors.NewGoCollector(),
+			newRkCollector(entry.Namespace, entry.Subsystem))
+	})
+
+	if entry.Pusher != nil {
+		entry.Pusher.Start()
+	}
+
+	if entry.StatsD != nil {
+		entry.StatsD.Start()
+	}
+}
+
+// Interrupt stops pusher and statsd exporter if enabled.
+func (entry *PromEntry) Interrupt(ctx context.Context) {
+	if entry.Pusher != nil {
+		entry.Pusher.Stop()
+	}
+
+	if entry.StatsD != nil {
+		entry.StatsD.Stop()
+	}
+}
+
+// GetName returns name of entry.
+func (entry *PromEntry) GetName() string {
+	return entry.entryName
+}
+
+// GetType returns type of entry.
+func (entry *PromEntry) GetType() string {
+	return entry.entryType
+}
+
+// GetDescription returns description of entry.
+func (entry *PromEntry) GetDescription() string {
+	return entry.entryDescription
+}
+
+// String returns string of entry.
+func (entry *PromEntry) String() string {
+	bytes, _ := json.Marshal(entry)
+	return string(bytes)
+}
+
+// MarshalJSON Marshal entry
+func (entry *PromEntry) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"name":          entry.GetName(),
+		"type":          entry.GetType(),
+		"description":   entry.GetDescription(),
+		"path":          entry.Path,
+		"namespace":     entry.Namespace,
+		"subsystem":     entry.Subsystem,
+		"format":        entry.Format,
+		"pusherEnabled": entry.Pusher != nil,
+		"statsdEnabled": entry.StatsD != nil,
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON Unmarshal entry
+func (entry *PromEntry) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// RegisterCollectors registers collectors into Registry.
+func (entry *PromEntry) RegisterCollectors(collectors ...prometheus.Collector) error {
+	for i := range collectors {
+		if err := entry.Registry.Register(collectors[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler returns http.Handler which serves metrics in Registry, should be mounted under Path.
+//
+// OpenMetrics is served if requested by Accept header, or always if Format is openmetrics.
+func (entry *PromEntry) Handler() http.Handler {
+	handler := promhttp.HandlerFor(entry.Registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+
+	if entry.Format == PromFormatOpenMetrics {
+		return forceOpenMetrics(handler)
+	}
+
+	return handler
+}
+
+// rkCollector collects app info, entry state and logger write counts at scrape time
+type rkCollector struct {
+	appInfo       *prometheus.Desc
+	entryState    *prometheus.Desc
+	loggerWrite   *prometheus.Desc
+	loggerDropped *prometheus.Desc
+}
+
+func newRkCollector(namespace, subsystem string) *rkCollector {
+	return &rkCollector{
+		appInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "app_info"),
+			"Application info with name and version as labels, value is always 1.",
+			[]string{"name", "version"}, nil),
+		entryState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "entry_state"),
+			"Lifecycle state of entry, value is 1 for current state and 0 for others.",
+			[]string{"entry_name", "entry_type", "state"}, nil),
+		loggerWrite: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "logger_write_total"),
+			"Number of lines written by LoggerEntry.",
+			[]string{"entry_name", "level"}, nil),
+		loggerDropped: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "logger_dropped_total"),
+			"Number of lines dropped by sampling, rate limiting and sinks of LoggerEntry.",
+			[]string{"entry_name", "level", "reason"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *rkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.appInfo
+	ch <- c.entryState
+	ch <- c.loggerWrite
+	ch <- c.loggerDropped
+}
+
+// Collect implements prometheus.Collector
+func (c *rkCollector) Collect(ch chan<- prometheus.Metric) {
+	appInfo := GlobalAppCtx.GetAppInfoEntry()
+	ch <- prometheus.MustNewConstMetric(c.appInfo, prometheus.GaugeValue, 1, appInfo.AppName, appInfo.Version)
+
+	for _, status := range GlobalAppCtx.ListEntryStatus() {
+		for _, state := range promEntryStates {
+			value := 0.0
+			if status.State == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.entryState, prometheus.GaugeValue, value,
+				status.EntryName, status.EntryType, string(state))
+		}
+	}
+
+	for _, v := range GlobalAppCtx.ListEntriesByType(LoggerEntryType) {
+		logger, ok := v.(*LoggerEntry)
+		if !ok {
+			continue
+		}
+
+		for level, count := range logger.WriteCounts() {
+			ch <- prometheus.MustNewConstMetric(c.loggerWrite, prometheus.CounterValue, float64(count),
+				logger.GetName(), level)
+		}
+
+		for reason, counts := range logger.DroppedCounts() {
+			for level, count := range counts {
+				ch <- prometheus.MustNewConstMetric(c.loggerDropped, prometheus.CounterValue, float64(count),
+					logger.GetName(), level, reason)
+			}
+		}
+	}
+}
+
+// NewPromPusher creates PromPusher which pushes metrics in gatherer to pushgateway.
+// Shutdown with error if RemoteAddress is empty.
+func NewPromPusher(boot *BootPromPusher, gatherer prometheus.Gatherer) *PromPusher {
+	if len(strings.TrimSpace(boot.RemoteAddress)) < 1 {
+		ShutdownWithError(fmt.Errorf("remote address of prom pusher is empty"))
+	}
+
+	res := &PromPusher{
+		JobName:       boot.JobName,
+		RemoteAddress: boot.RemoteAddress,
+		Interval:      time.Duration(boot.IntervalMs) * time.Millisecond,
+	}
+
+	if len(res.JobName) < 1 {
+		res.JobName = GlobalAppCtx.GetAppInfoEntry().AppName
+	}
+
+	if res.Interval <= 0 {
+		res.Interval = time.Second
+	}
+
+	if !strings.HasPrefix(res.RemoteAddress, "http://") && !strings.HasPrefix(res.RemoteAddress, "https://") {
+		res.RemoteAddress = "http://" + res.RemoteAddress
+	}
+
+	res.pusher = push.New(res.RemoteAddress, res.JobName).Gatherer(gatherer)
+
+	if tokens := strings.SplitN(boot.BasicAuth, ":", 2); len(tokens) == 2 {
+		res.pusher = res.pusher.BasicAuth(tokens[0], tokens[1])
+	}
+
+	return res
+}
+
+// PromPusher pushes metrics to pushgateway periodically
+type PromPusher struct {
+	JobName       string        `json:"-" yaml:"-"`
+	RemoteAddress string        `json:"-" yaml:"-"`
+	Interval      time.Duration `json:"-" yaml:"-"`
+	pusher        *push.Pusher
+	lock          sync.Mutex
+	quitChannel   chan struct{}
+	waitGroup     sync.WaitGroup
+}
+
+// Start pushing periodically in background, noop if already started.
+func (p *PromPusher) Start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.quitChannel != nil {
+		return
+	}
+
+	p.quitChannel = make(chan struct{})
+	p.waitGroup.Add(1)
+
+	go func(quitChannel chan struct{}) {
+		defer p.waitGroup.Done()
+
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quitChannel:
+				return
+			case <-ticker.C:
+				if err := p.Push(); err != nil {
+					GlobalAppCtx.GetLoggerEntryDefault().Warn("Failed to push metrics to pushgateway",
+						zap.String("remoteAddress", p.RemoteAddress),
+						zap.Error(err))
+				}
+			}
+		}
+	}(p.quitChannel)
+}
+
+// Stop pushing and wait for background goroutine to exit.
+func (p *PromPusher) Stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.quitChannel == nil {
+		return
+	}
+
+	close(p.quitChannel)
+	p.waitGroup.Wait()
+	p.quitChannel = nil
+}
+
+// Push metrics to pushgateway immediately.
+func (p *PromPusher) Push() error {
+	return p.pusher.Push()
+}
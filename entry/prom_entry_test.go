@@ -0,0 +1,189 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegisterPromEntry(t *testing.T) {
+	// disabled
+	assert.Nil(t, RegisterPromEntry(&BootProm{}))
+
+	// default values
+	registry := prometheus.NewRegistry()
+	entry := RegisterPromEntry(&BootProm{Enabled: true}, WithNamePromEntry("ut-prom"), WithRegistryPromEntry(registry))
+	assert.Equal(t, "ut-prom", entry.GetName())
+	assert.Equal(t, PromEntryType, entry.GetType())
+	assert.NotEmpty(t, entry.GetDescription())
+	assert.Equal(t, "/metrics", entry.Path)
+	assert.Equal(t, "rk", entry.Namespace)
+	assert.Same(t, registry, entry.Registry)
+	assert.Nil(t, entry.Pusher)
+	assert.Contains(t, entry.String(), `"path":"/metrics"`)
+	assert.Nil(t, entry.UnmarshalJSON(nil))
+}
+
+func TestRegisterPromEntryYAML(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	bootStr := `
+---
+prom:
+  enabled: true
+  path: ut-metrics
+  namespace: ut_ns
+  subsystem: ut_sub
+  pusher:
+    enabled: true
+    jobName: ut-job
+    remoteAddress: localhost:9091
+    intervalMs: 100
+    basicAuth: "user:pass"
+  format: openmetrics
+  statsd:
+    enabled: true
+    address: localhost:8125
+    flavor: dogstatsd
+    prefix: ut
+    intervalMs: 100
+    tags:
+      env: ut
+`
+	entries := RegisterPromEntryYAML([]byte(bootStr))
+	assert.Len(t, entries, 1)
+
+	entry := entries["PromEntry"].(*PromEntry)
+	assert.Equal(t, "/ut-metrics", entry.Path)
+	assert.Equal(t, "ut_ns", entry.Namespace)
+	assert.Equal(t, "ut_sub", entry.Subsystem)
+	assert.Equal(t, "ut-job", entry.Pusher.JobName)
+	assert.Equal(t, "http://localhost:9091", entry.Pusher.RemoteAddress)
+	assert.Equal(t, 100*time.Millisecond, entry.Pusher.Interval)
+	assert.Equal(t, PromFormatOpenMetrics, entry.Format)
+	assert.Equal(t, PromStatsDFlavorDogStatsD, entry.StatsD.Flavor)
+	assert.Equal(t, "ut", entry.StatsD.Prefix)
+	assert.Equal(t, 100*time.Millisecond, entry.StatsD.Interval)
+	assert.Equal(t, map[string]string{"env": "ut"}, entry.StatsD.Tags)
+	assert.NotNil(t, GlobalAppCtx.GetEntry(PromEntryType, "PromEntry"))
+
+	// disabled
+	assert.Empty(t, RegisterPromEntryYAML([]byte("prom:\n  enabled: false")))
+}
+
+func TestPromEntry_Handler(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	logger := RegisterLoggerEntry(&BootLogger{
+		Logger: []*BootLoggerE{
+			{
+				Name: "ut-logger",
+				Zap: &rklogger.ZapConfigWrap{
+					OutputPaths: []string{"stdout"},
+					Encoding:    "console",
+				},
+			},
+		},
+	})[0]
+	logger.Info("ut-msg")
+
+	entry := RegisterPromEntry(&BootProm{Enabled: true, Subsystem: "ut"})
+	GlobalAppCtx.AddEntry(entry)
+	GlobalAppCtx.BootstrapEntry(context.TODO(), entry)
+	defer GlobalAppCtx.InterruptEntry(context.TODO(), entry)
+
+	// custom collector
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "ut_counter"})
+	counter.Inc()
+	assert.Nil(t, entry.RegisterCollectors(counter))
+	assert.NotNil(t, entry.RegisterCollectors(counter))
+
+	writer := httptest.NewRecorder()
+	entry.Handler().ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	body := writer.Body.String()
+	assert.Contains(t, body, `rk_ut_app_info{name="`+GlobalAppCtx.GetAppInfoEntry().AppName+`",version=`)
+	assert.Contains(t, body, `rk_ut_entry_state{entry_name="PromEntry",entry_type="PromEntry",state="ready"} 1`)
+	assert.Contains(t, body, `rk_ut_entry_state{entry_name="PromEntry",entry_type="PromEntry",state="failed"} 0`)
+	assert.Contains(t, body, `rk_ut_logger_write_total{entry_name="ut-logger",level="info"} 1`)
+	assert.Contains(t, body, `rk_ut_logger_dropped_total{entry_name="ut-logger",level="info",reason="sampled"} 0`)
+	assert.Contains(t, body, "go_goroutines")
+	assert.Contains(t, body, "ut_counter 1")
+}
+
+func TestPromPusher(t *testing.T) {
+	pushed := int32(0)
+	bodies := make(chan string, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		user, pass, _ := request.BasicAuth()
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+		assert.Equal(t, http.MethodPut, request.Method)
+		assert.True(t, strings.HasPrefix(request.URL.Path, "/metrics/job/ut-job"))
+
+		bytes, _ := io.ReadAll(request.Body)
+		if atomic.AddInt32(&pushed, 1) == 1 {
+			bodies <- string(bytes)
+		}
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "ut_gauge"})
+	registry.MustRegister(gauge)
+
+	pusher := NewPromPusher(&BootPromPusher{
+		Enabled:       true,
+		JobName:       "ut-job",
+		RemoteAddress: server.URL,
+		IntervalMs:    10,
+		BasicAuth:     "user:pass",
+	}, registry)
+
+	// push immediately
+	assert.Nil(t, pusher.Push())
+	assert.NotEmpty(t, <-bodies)
+
+	// push periodically
+	pusher.Start()
+	pusher.Start()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&pushed) > 2
+	}, time.Second, 10*time.Millisecond)
+	pusher.Stop()
+	pusher.Stop()
+}
+
+func TestPromPusher_Failed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	pusher := NewPromPusher(&BootPromPusher{RemoteAddress: server.URL}, prometheus.NewRegistry())
+	assert.Equal(t, time.Second, pusher.Interval)
+	assert.NotEmpty(t, pusher.JobName)
+	assert.NotNil(t, pusher.Push())
+}
+
+func TestNewPromPusher_WithEmptyAddress(t *testing.T) {
+	defer assertPanic(t)
+
+	NewPromPusher(&BootPromPusher{Enabled: true}, prometheus.NewRegistry())
+}
@@ -0,0 +1,343 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PromFormatText prometheus text format, OpenMetrics still served if requested by Accept header
+	PromFormatText = "text"
+	// PromFormatOpenMetrics OpenMetrics text format which includes exemplars
+	PromFormatOpenMetrics = "openmetrics"
+
+	// PromStatsDFlavorStatsD plain statsd, labels are appended to metric name
+	PromStatsDFlavorStatsD = "statsd"
+	// PromStatsDFlavorDogStatsD DogStatsD, labels are sent as tags
+	PromStatsDFlavorDogStatsD = "dogstatsd"
+
+	// OpenMetrics content type requested by handler if format is openmetrics
+	promOpenMetricsAccept = "application/openmetrics-text; version=0.0.1"
+	// max size of UDP packet which would not be fragmented in most networks
+	promStatsDMaxPacketSize = 1432
+)
+
+var promStatsDInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
+// BootPromStatsD bootstrap config of StatsD exporter.
+// 1: Enabled: Enable StatsD exporter.
+// 2: Address: UDP address of statsd agent, default is localhost:8125.
+// 3: Flavor: One of statsd and dogstatsd, default is statsd.
+// 4: Prefix: Prefix of metric names.
+// 5: IntervalMs: Interval of export, default is 10000 milliseconds.
+// 6: Tags: Tags attached to every metric, labels are appended to metric name with statsd flavor.
+type BootPromStatsD struct {
+	Enabled    bool              `yaml:"enabled" json:"enabled"`
+	Address    string            `yaml:"address" json:"address"`
+	Flavor     string            `yaml:"flavor" json:"flavor"`
+	Prefix     string            `yaml:"prefix" json:"prefix"`
+	IntervalMs int64             `yaml:"intervalMs" json:"intervalMs"`
+	Tags       map[string]string `yaml:"tags" json:"tags"`
+}
+
+// PromAddWithExemplar adds value to counter with exemplar labels like trace_id,
+// exemplar is ignored if counter does not support it or labels are empty.
+//
+// Exemplars are only exposed with OpenMetrics format.
+func PromAddWithExemplar(counter prometheus.Counter, value float64, exemplar prometheus.Labels) {
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok && len(exemplar) > 0 {
+		adder.AddWithExemplar(value, exemplar)
+		return
+	}
+
+	counter.Add(value)
+}
+
+// PromObserveWithExemplar observes value with exemplar labels like trace_id,
+// exemplar is ignored if observer does not support it or labels are empty.
+//
+// Exemplars are only exposed with OpenMetrics format.
+func PromObserveWithExemplar(observer prometheus.Observer, value float64, exemplar prometheus.Labels) {
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && len(exemplar) > 0 {
+		exemplarObserver.ObserveWithExemplar(value, exemplar)
+		return
+	}
+
+	observer.Observe(value)
+}
+
+// forceOpenMetrics serves OpenMetrics even if not requested by Accept header
+func forceOpenMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !strings.Contains(request.Header.Get("Accept"), "application/openmetrics-text") {
+			request = request.Clone(request.Context())
+			request.Header.Set("Accept", promOpenMetricsAccept)
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// NewPromStatsDExporter creates PromStatsDExporter which exports metrics in gatherer to statsd agent
+func NewPromStatsDExporter(boot *BootPromStatsD, gatherer prometheus.Gatherer) *PromStatsDExporter {
+	res := &PromStatsDExporter{
+		Address:  boot.Address,
+		Flavor:   strings.ToLower(boot.Flavor),
+		Prefix:   boot.Prefix,
+		Interval: time.Duration(boot.IntervalMs) * time.Millisecond,
+		Tags:     boot.Tags,
+		gatherer: gatherer,
+		counters: make(map[string]float64),
+	}
+
+	if len(res.Address) < 1 {
+		res.Address = "localhost:8125"
+	}
+
+	if res.Flavor != PromStatsDFlavorDogStatsD {
+		res.Flavor = PromStatsDFlavorStatsD
+	}
+
+	if res.Interval <= 0 {
+		res.Interval = 10 * time.Second
+	}
+
+	if res.Tags == nil {
+		res.Tags = make(map[string]string)
+	}
+
+	return res
+}
+
+// PromStatsDExporter exports metrics gathered from registry to statsd agent with UDP periodically.
+//
+// Gauges are sent as gauges, counters are sent as counters with delta since last export,
+// count and sum of histograms and summaries are sent as counters, quantiles of summaries are sent as gauges.
+type PromStatsDExporter struct {
+	Address     string            `json:"-" yaml:"-"`
+	Flavor      string            `json:"-" yaml:"-"`
+	Prefix      string            `json:"-" yaml:"-"`
+	Interval    time.Duration     `json:"-" yaml:"-"`
+	Tags        map[string]string `json:"-" yaml:"-"`
+	gatherer    prometheus.Gatherer
+	counters    map[string]float64
+	lock        sync.Mutex
+	quitChannel chan struct{}
+	waitGroup   sync.WaitGroup
+}
+
+// Start exporting periodically in background, noop if already started.
+func (e *PromStatsDExporter) Start() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.quitChannel != nil {
+		return
+	}
+
+	e.quitChannel = make(chan struct{})
+	e.waitGroup.Add(1)
+
+	go func(quitChannel chan struct{}) {
+		defer e.waitGroup.Done()
+
+		ticker := time.NewTicker(e.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quitChannel:
+				return
+			case <-ticker.C:
+				if err := e.Export(); err != nil {
+					GlobalAppCtx.GetLoggerEntryDefault().Warn("Failed to export metrics to statsd",
+						zap.String("address", e.Address),
+						zap.Error(err))
+				}
+			}
+		}
+	}(e.quitChannel)
+}
+
+// Stop exporting and wait for background goroutine to exit.
+func (e *PromStatsDExporter) Stop() {
+	e.lock.Lock()
+	quitChannel := e.quitChannel
+	e.quitChannel = nil
+	e.lock.Unlock()
+
+	if quitChannel == nil {
+		return
+	}
+
+	close(quitChannel)
+	e.waitGroup.Wait()
+}
+
+// Export gathers metrics and sends them to statsd agent immediately.
+func (e *PromStatsDExporter) Export() error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("udp", e.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	buf := &bytes.Buffer{}
+	for _, line := range e.lines(families) {
+		if buf.Len() > 0 && buf.Len()+len(line)+1 > promStatsDMaxPacketSize {
+			if _, err := conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lines converts metric families into statsd lines
+func (e *PromStatsDExporter) lines(families []*dto.MetricFamily) []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	res := make([]string, 0)
+
+	for _, family := range families {
+		name := family.GetName()
+
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				res = e.appendCounter(res, name, labels, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				res = e.appendLine(res, name, labels, metric.GetGauge().GetValue(), "g")
+			case dto.MetricType_UNTYPED:
+				res = e.appendLine(res, name, labels, metric.GetUntyped().GetValue(), "g")
+			case dto.MetricType_HISTOGRAM:
+				res = e.appendCounter(res, name+"_count", labels, float64(metric.GetHistogram().GetSampleCount()))
+				res = e.appendCounter(res, name+"_sum", labels, metric.GetHistogram().GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				res = e.appendCounter(res, name+"_count", labels, float64(metric.GetSummary().GetSampleCount()))
+				res = e.appendCounter(res, name+"_sum", labels, metric.GetSummary().GetSampleSum())
+				for _, quantile := range metric.GetSummary().GetQuantile() {
+					quantileLabels := map[string]string{"quantile": strconv.FormatFloat(quantile.GetQuantile(), 'f', -1, 64)}
+					for k, v := range labels {
+						quantileLabels[k] = v
+					}
+					res = e.appendLine(res, name, quantileLabels, quantile.GetValue(), "g")
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// appendCounter appends delta of counter since last export, counter reset is treated as a new counter
+func (e *PromStatsDExporter) appendCounter(res []string, name string, labels map[string]string, value float64) []string {
+	key := name + "{" + joinSortedLabels(labels, "=", ",") + "}"
+
+	delta := value
+	if last, ok := e.counters[key]; ok && value >= last {
+		delta = value - last
+	}
+	e.counters[key] = value
+
+	return e.appendLine(res, name, labels, delta, "c")
+}
+
+// appendLine appends line of name:value|type with tags or labels encoded as name based on flavor
+func (e *PromStatsDExporter) appendLine(res []string, name string, labels map[string]string, value float64, metricType string) []string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return res
+	}
+
+	if len(e.Prefix) > 0 {
+		name = e.Prefix + "." + name
+	}
+
+	tags := make(map[string]string)
+	for k, v := range e.Tags {
+		tags[k] = v
+	}
+	for k, v := range labels {
+		tags[k] = v
+	}
+
+	if e.Flavor == PromStatsDFlavorStatsD {
+		if len(tags) > 0 {
+			name = name + "." + joinSortedLabels(tags, ".", ".")
+		}
+		return append(res, fmt.Sprintf("%s:%s|%s", sanitizeStatsD(name), formatStatsDValue(value), metricType))
+	}
+
+	line := fmt.Sprintf("%s:%s|%s", sanitizeStatsD(name), formatStatsDValue(value), metricType)
+	if len(tags) > 0 {
+		line = line + "|#" + joinSortedLabels(tags, ":", ",")
+	}
+
+	return append(res, line)
+}
+
+// joinSortedLabels joins labels sorted by key, keys and values are sanitized as metric name,
+// so that separators like |, #, :, and , in label values would not break the line
+func joinSortedLabels(labels map[string]string, kvSep, sep string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, sanitizeStatsD(k)+kvSep+sanitizeStatsD(labels[k]))
+	}
+
+	return strings.Join(pairs, sep)
+}
+
+func sanitizeStatsD(name string) string {
+	return promStatsDInvalidChars.ReplaceAllString(name, "_")
+}
+
+func formatStatsDValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
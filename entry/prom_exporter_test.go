@@ -0,0 +1,224 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStatsDListener(t *testing.T) (*net.UDPConn, func() string) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	assert.Nil(t, err)
+
+	read := func() string {
+		buf := make([]byte, 65535)
+		lines := make([]string, 0)
+
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			lines = append(lines, string(buf[:n]))
+		}
+
+		return strings.Join(lines, "\n")
+	}
+
+	return conn, read
+}
+
+func TestPromAddWithExemplar_OpenMetrics(t *testing.T) {
+	entry := RegisterPromEntry(&BootProm{Enabled: true, Format: "OpenMetrics"})
+	assert.Equal(t, PromFormatOpenMetrics, entry.Format)
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "ut_counter_total"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "ut_histogram", Buckets: []float64{1}})
+	assert.Nil(t, entry.RegisterCollectors(counter, histogram))
+
+	PromAddWithExemplar(counter, 1, prometheus.Labels{"trace_id": "ut-trace"})
+	PromAddWithExemplar(counter, 1, nil)
+	PromObserveWithExemplar(histogram, 0.5, prometheus.Labels{"trace_id": "ut-trace"})
+	PromObserveWithExemplar(histogram, 0.5, nil)
+
+	// OpenMetrics is served without Accept header
+	writer := httptest.NewRecorder()
+	entry.Handler().ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, writer.Header().Get("Content-Type"), "application/openmetrics-text")
+
+	body := writer.Body.String()
+	assert.Contains(t, body, `ut_counter_total 2.0 # {trace_id="ut-trace"} 1.0`)
+	assert.Contains(t, body, `ut_histogram_bucket{le="1.0"} 2 # {trace_id="ut-trace"} 0.5`)
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
+
+func TestPromEntry_Handler_TextFormat(t *testing.T) {
+	entry := RegisterPromEntry(&BootProm{Enabled: true})
+	assert.Equal(t, PromFormatText, entry.Format)
+
+	// text format by default
+	writer := httptest.NewRecorder()
+	entry.Handler().ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, writer.Header().Get("Content-Type"), "text/plain")
+
+	// OpenMetrics if requested
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", promOpenMetricsAccept)
+	writer = httptest.NewRecorder()
+	entry.Handler().ServeHTTP(writer, req)
+	assert.Contains(t, writer.Header().Get("Content-Type"), "application/openmetrics-text")
+}
+
+func TestRegisterPromEntry_InvalidFormat(t *testing.T) {
+	defer assertPanic(t)
+	RegisterPromEntry(&BootProm{Enabled: true, Format: "invalid"})
+}
+
+func TestPromStatsDExporter_StatsD(t *testing.T) {
+	conn, read := newStatsDListener(t)
+	defer conn.Close()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ut_requests_total"}, []string{"code"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "ut_gauge"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "ut_latency"})
+	registry.MustRegister(counter, gauge, histogram)
+
+	counter.WithLabelValues("200").Add(3)
+	gauge.Set(1.5)
+	histogram.Observe(2)
+
+	exporter := NewPromStatsDExporter(&BootPromStatsD{
+		Address: conn.LocalAddr().String(),
+		Prefix:  "ut",
+		Tags:    map[string]string{"env": "test"},
+	}, registry)
+	assert.Equal(t, PromStatsDFlavorStatsD, exporter.Flavor)
+	assert.Equal(t, 10*time.Second, exporter.Interval)
+
+	assert.Nil(t, exporter.Export())
+	lines := read()
+	assert.Contains(t, lines, "ut.ut_requests_total.code.200.env.test:3|c")
+	assert.Contains(t, lines, "ut.ut_gauge.env.test:1.5|g")
+	assert.Contains(t, lines, "ut.ut_latency_count.env.test:1|c")
+	assert.Contains(t, lines, "ut.ut_latency_sum.env.test:2|c")
+
+	// counters are sent as delta
+	counter.WithLabelValues("200").Add(2)
+	assert.Nil(t, exporter.Export())
+	lines = read()
+	assert.Contains(t, lines, "ut.ut_requests_total.code.200.env.test:2|c")
+	assert.Contains(t, lines, "ut.ut_latency_count.env.test:0|c")
+}
+
+func TestPromStatsDExporter_DogStatsD(t *testing.T) {
+	conn, read := newStatsDListener(t)
+	defer conn.Close()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ut_requests_total"}, []string{"code"})
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "ut_summary", Objectives: map[float64]float64{0.5: 0.05}})
+	registry.MustRegister(counter, summary)
+
+	counter.WithLabelValues("200").Inc()
+	summary.Observe(1)
+
+	exporter := NewPromStatsDExporter(&BootPromStatsD{
+		Address:    conn.LocalAddr().String(),
+		Flavor:     "DogStatsD",
+		IntervalMs: 10,
+		Tags:       map[string]string{"env": "test"},
+	}, registry)
+
+	assert.Nil(t, exporter.Export())
+	lines := read()
+	assert.Contains(t, lines, "ut_requests_total:1|c|#code:200,env:test")
+	assert.Contains(t, lines, "ut_summary:1|g|#env:test,quantile:0.5")
+	assert.Contains(t, lines, "ut_summary_count:1|c|#env:test")
+
+	// export periodically
+	exporter.Start()
+	exporter.Start()
+	assert.Contains(t, read(), "ut_requests_total:0|c|#code:200,env:test")
+	exporter.Stop()
+	exporter.Stop()
+}
+
+func TestPromStatsDExporter_DogStatsD_WithSpecialChars(t *testing.T) {
+	conn, read := newStatsDListener(t)
+	defer conn.Close()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ut_requests_total"}, []string{"path"})
+	registry.MustRegister(counter)
+
+	counter.WithLabelValues("a|b,c#d:e").Inc()
+
+	exporter := NewPromStatsDExporter(&BootPromStatsD{
+		Address: conn.LocalAddr().String(),
+		Flavor:  "DogStatsD",
+		Tags:    map[string]string{"env:name": "ut|test"},
+	}, registry)
+
+	assert.Nil(t, exporter.Export())
+	assert.Contains(t, read(), "ut_requests_total:1|c|#env_name:ut_test,path:a_b_c_d_e")
+}
+
+func TestPromStatsDExporter_SplitPackets(t *testing.T) {
+	conn, _ := newStatsDListener(t)
+	defer conn.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "ut_gauge"}, []string{"id"})
+	registry.MustRegister(gauge)
+	for i := 0; i < 200; i++ {
+		gauge.WithLabelValues(strings.Repeat("x", i%10) + string(rune('a'+i%26)) + time.Duration(i).String()).Set(1)
+	}
+
+	exporter := NewPromStatsDExporter(&BootPromStatsD{Address: conn.LocalAddr().String()}, registry)
+	assert.Nil(t, exporter.Export())
+
+	packets := 0
+	buf := make([]byte, 65535)
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		assert.True(t, n <= promStatsDMaxPacketSize)
+		packets++
+	}
+	assert.True(t, packets > 1)
+}
+
+func TestPromEntry_WithStatsD(t *testing.T) {
+	conn, read := newStatsDListener(t)
+	defer conn.Close()
+
+	entry := RegisterPromEntry(&BootProm{
+		Enabled: true,
+		StatsD: BootPromStatsD{
+			Enabled:    true,
+			Address:    conn.LocalAddr().String(),
+			IntervalMs: 10,
+		},
+	})
+	assert.NotNil(t, entry.StatsD)
+
+	entry.Bootstrap(context.TODO())
+	assert.Contains(t, read(), "rk_app_info")
+	entry.Interrupt(context.TODO())
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-entry/v2/os"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// default windows of stats returned by SamplerEntry.Handler
+var defaultSamplerWindows = []string{"1m", "5m", "15m", "1h"}
+
+// default number of samples kept by SamplerEntry, one hour with default interval
+const defaultSamplerCapacity = 360
+
+// BootSampler bootstrap config of system info sampler.
+// 1: Enabled: Enable sampler entry.
+// 2: IntervalSec: Interval of sampling, default is 10 seconds.
+// 3: Capacity: Max number of samples to keep, default is 360 which is one hour with default interval.
+type BootSampler struct {
+	Enabled     bool `yaml:"enabled" json:"enabled"`
+	IntervalSec int  `yaml:"intervalSec" json:"intervalSec"`
+	Capacity    int  `yaml:"capacity" json:"capacity"`
+}
+
+// BootSamplerEntry bootstrap config of SamplerEntry used by RegisterSamplerEntryYAML.
+type BootSamplerEntry struct {
+	Sampler BootSampler `yaml:"sampler" json:"sampler"`
+}
+
+// SystemSample is a snapshot of rkos.CpuInfo, rkos.MemInfo and rkos.GoEnvInfo recorded by SamplerEntry
+type SystemSample struct {
+	Timestamp time.Time       `json:"timestamp" yaml:"timestamp" example:"2022-03-15T20:43:05+08:00"`
+	CpuInfo   *rkos.CpuInfo   `json:"cpuInfo" yaml:"cpuInfo"`
+	MemInfo   *rkos.MemInfo   `json:"memInfo" yaml:"memInfo"`
+	GoEnvInfo *rkos.GoEnvInfo `json:"goEnvInfo" yaml:"goEnvInfo"`
+}
+
+// SampleStat min, max and average of a field over samples in window
+type SampleStat struct {
+	Min float64 `json:"min" yaml:"min" example:"1"`
+	Max float64 `json:"max" yaml:"max" example:"3"`
+	Avg float64 `json:"avg" yaml:"avg" example:"2"`
+}
+
+// SamplerStats stats of samples in window
+type SamplerStats struct {
+	Window            string      `json:"window" yaml:"window" example:"5m"`
+	Samples           int         `json:"samples" yaml:"samples" example:"30"`
+	From              time.Time   `json:"from" yaml:"from" example:"2022-03-15T20:38:05+08:00"`
+	To                time.Time   `json:"to" yaml:"to" example:"2022-03-15T20:43:05+08:00"`
+	MemUsedMb         *SampleStat `json:"memUsedMb" yaml:"memUsedMb"`
+	MemAllocByte      *SampleStat `json:"memAllocByte" yaml:"memAllocByte"`
+	SysAllocByte      *SampleStat `json:"sysAllocByte" yaml:"sysAllocByte"`
+	MemUsedPercentage *SampleStat `json:"memUsedPercentage" yaml:"memUsedPercentage"`
+	RoutinesCount     *SampleStat `json:"routinesCount" yaml:"routinesCount"`
+}
+
+// samplerResp response of SamplerEntry.Handler
+type samplerResp struct {
+	IntervalSec int             `json:"intervalSec" yaml:"intervalSec" example:"10"`
+	Capacity    int             `json:"capacity" yaml:"capacity" example:"360"`
+	Samples     []*SystemSample `json:"samples" yaml:"samples"`
+	Stats       []*SamplerStats `json:"stats" yaml:"stats"`
+}
+
+// SamplerEntry records system info periodically into a bounded ring buffer.
+//
+// Sampling starts in Bootstrap and stops in Interrupt, the oldest sample is dropped if buffer is full.
+type SamplerEntry struct {
+	entryName        string          `json:"-" yaml:"-"`
+	entryType        string          `json:"-" yaml:"-"`
+	entryDescription string          `json:"-" yaml:"-"`
+	Interval         time.Duration   `json:"-" yaml:"-"`
+	Capacity         int             `json:"-" yaml:"-"`
+	samples          []*SystemSample `json:"-" yaml:"-"`
+	head             int             `json:"-" yaml:"-"`
+	size             int             `json:"-" yaml:"-"`
+	lock             sync.RWMutex    `json:"-" yaml:"-"`
+	quitChannel      chan struct{}   `json:"-" yaml:"-"`
+	waitGroup        sync.WaitGroup  `json:"-" yaml:"-"`
+	startOnce        sync.Once       `json:"-" yaml:"-"`
+	stopOnce         sync.Once       `json:"-" yaml:"-"`
+}
+
+// SamplerEntryOption option for SamplerEntry
+type SamplerEntryOption func(entry *SamplerEntry)
+
+// WithNameSamplerEntry provide name.
+func WithNameSamplerEntry(name string) SamplerEntryOption {
+	return func(entry *SamplerEntry) {
+		entry.entryName = name
+	}
+}
+
+// RegisterSamplerEntry create SamplerEntry with config, nil returned if not enabled
+func RegisterSamplerEntry(boot *BootSampler, opts ...SamplerEntryOption) *SamplerEntry {
+	if !boot.Enabled {
+		return nil
+	}
+
+	entry := &SamplerEntry{
+		entryName:        "SamplerEntry",
+		entryType:        SamplerEntryType,
+		entryDescription: "Internal RK entry which records system info periodically.",
+		Interval:         time.Duration(boot.IntervalSec) * time.Second,
+		Capacity:         boot.Capacity,
+		quitChannel:      make(chan struct{}),
+	}
+
+	for i := range opts {
+		opts[i](entry)
+	}
+
+	if entry.Interval <= 0 {
+		entry.Interval = 10 * time.Second
+	}
+
+	if entry.Capacity < 1 {
+		entry.Capacity = defaultSamplerCapacity
+	}
+
+	entry.samples = make([]*SystemSample, entry.Capacity)
+
+	return entry
+}
+
+// RegisterSamplerEntryYAML register function
+func RegisterSamplerEntryYAML(raw []byte) map[string]Entry {
+	boot := &BootSamplerEntry{}
+	UnmarshalBootYAML(raw, boot)
+
+	res := map[string]Entry{}
+
+	if entry := RegisterSamplerEntry(&boot.Sampler); entry != nil {
+		GlobalAppCtx.AddEntry(entry)
+		res[entry.GetName()] = entry
+	}
+
+	return res
+}
+
+// Bootstrap records a sample and starts sampling in background.
+func (entry *SamplerEntry) Bootstrap(ctx context.Context) {
+	entry.startOnce.Do(func() {
+		entry.Sample()
+
+		entry.waitGroup.Add(1)
+		go func() {
+			defer entry.waitGroup.Done()
+
+			ticker := time.NewTicker(entry.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-entry.quitChannel:
+					return
+				case <-ticker.C:
+					entry.Sample()
+				}
+			}
+		}()
+	})
+}
+
+// Interrupt stops sampling and waits for background goroutine to exit.
+func (entry *SamplerEntry) Interrupt(ctx context.Context) {
+	entry.stopOnce.Do(func() {
+		close(entry.quitChannel)
+		entry.waitGroup.Wait()
+	})
+}
+
+// GetName returns name of entry.
+func (entry *SamplerEntry) GetName() string {
+	return entry.entryName
+}
+
+// GetType returns type of entry.
+func (entry *SamplerEntry) GetType() string {
+	return entry.entryType
+}
+
+// GetDescription returns description of entry.
+func (entry *SamplerEntry) GetDescription() string {
+	return entry.entryDescription
+}
+
+// String returns string of entry.
+func (entry *SamplerEntry) String() string {
+	bytes, _ := json.Marshal(entry)
+	return string(bytes)
+}
+
+// MarshalJSON Marshal entry
+func (entry *SamplerEntry) MarshalJSON() ([]byte, error) {
+	entry.lock.RLock()
+	size := entry.size
+	entry.lock.RUnlock()
+
+	m := map[string]interface{}{
+		"name":        entry.GetName(),
+		"type":        entry.GetType(),
+		"description": entry.GetDescription(),
+		"intervalSec": int(entry.Interval.Seconds()),
+		"capacity":    entry.Capacity,
+		"samples":     size,
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON Unmarshal entry
+func (entry *SamplerEntry) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// Sample records a sample immediately.
+func (entry *SamplerEntry) Sample() *SystemSample {
+	sample := &SystemSample{
+		Timestamp: time.Now(),
+		CpuInfo:   rkos.NewCpuInfo(),
+		MemInfo:   rkos.NewMemInfo(),
+		GoEnvInfo: rkos.NewGoEnvInfo(),
+	}
+
+	entry.add(sample)
+	return sample
+}
+
+// add sample into ring buffer, the oldest sample would be overwritten if full.
+// Buffer is allocated with default capacity if entry was not created by RegisterSamplerEntry.
+func (entry *SamplerEntry) add(sample *SystemSample) {
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+
+	if len(entry.samples) < 1 {
+		if entry.Capacity < 1 {
+			entry.Capacity = defaultSamplerCapacity
+		}
+		entry.samples = make([]*SystemSample, entry.Capacity)
+	}
+
+	capacity := len(entry.samples)
+	entry.samples[(entry.head+entry.size)%capacity] = sample
+	if entry.size < capacity {
+		entry.size++
+	} else {
+		entry.head = (entry.head + 1) % capacity
+	}
+}
+
+// History returns samples recorded within window from oldest to newest, all samples returned if window is not positive.
+func (entry *SamplerEntry) History(window time.Duration) []*SystemSample {
+	entry.lock.RLock()
+	defer entry.lock.RUnlock()
+
+	res := make([]*SystemSample, 0, entry.size)
+	since := time.Now().Add(-window)
+
+	for i := 0; i < entry.size; i++ {
+		sample := entry.samples[(entry.head+i)%len(entry.samples)]
+		if window > 0 && sample.Timestamp.Before(since) {
+			continue
+		}
+		res = append(res, sample)
+	}
+
+	return res
+}
+
+// Stats returns min, max and average of samples recorded within window.
+func (entry *SamplerEntry) Stats(window time.Duration) *SamplerStats {
+	samples := entry.History(window)
+
+	res := &SamplerStats{
+		Window:            window.String(),
+		Samples:           len(samples),
+		MemUsedMb:         newSampleStat(samples, func(s *SystemSample) float64 { return float64(s.MemInfo.MemUsedMb) }),
+		MemAllocByte:      newSampleStat(samples, func(s *SystemSample) float64 { return float64(s.MemInfo.MemAllocByte) }),
+		SysAllocByte:      newSampleStat(samples, func(s *SystemSample) float64 { return float64(s.MemInfo.SysAllocByte) }),
+		MemUsedPercentage: newSampleStat(samples, func(s *SystemSample) float64 { return s.MemInfo.MemUsedPercentage }),
+		RoutinesCount:     newSampleStat(samples, func(s *SystemSample) float64 { return float64(s.GoEnvInfo.RoutinesCount) }),
+	}
+
+	if len(samples) > 0 {
+		res.From = samples[0].Timestamp
+		res.To = samples[len(samples)-1].Timestamp
+	}
+
+	return res
+}
+
+// Handler returns http.Handler which serves samples and stats as JSON.
+//
+// Query parameter window filters samples like window=1h, all samples returned if missing.
+// Query parameter stats is comma separated windows of stats, default is 1m,5m,15m,1h.
+func (entry *SamplerEntry) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var window time.Duration
+		if raw := request.URL.Query().Get("window"); len(raw) > 0 {
+			var err error
+			if window, err = time.ParseDuration(raw); err != nil {
+				writeJSON(writer, http.StatusBadRequest,
+					GlobalAppCtx.GetErrorBuilder().New(http.StatusBadRequest, "invalid window", err))
+				return
+			}
+		}
+
+		windows := defaultSamplerWindows
+		if raw := request.URL.Query().Get("stats"); len(raw) > 0 {
+			windows = strings.Split(raw, ",")
+		}
+
+		resp := &samplerResp{
+			IntervalSec: int(entry.Interval.Seconds()),
+			Capacity:    entry.Capacity,
+			Samples:     entry.History(window),
+			Stats:       make([]*SamplerStats, 0),
+		}
+
+		for _, raw := range windows {
+			statsWindow, err := time.ParseDuration(strings.TrimSpace(raw))
+			if err != nil {
+				writeJSON(writer, http.StatusBadRequest,
+					GlobalAppCtx.GetErrorBuilder().New(http.StatusBadRequest, "invalid stats window", err))
+				return
+			}
+			resp.Stats = append(resp.Stats, entry.Stats(statsWindow))
+		}
+
+		writeJSON(writer, http.StatusOK, resp)
+	})
+}
+
+// newSampleStat calculates min, max and average of field
+func newSampleStat(samples []*SystemSample, field func(*SystemSample) float64) *SampleStat {
+	res := &SampleStat{}
+	if len(samples) < 1 {
+		return res
+	}
+
+	res.Min, res.Max = math.MaxFloat64, -math.MaxFloat64
+	sum := 0.0
+	for _, sample := range samples {
+		value := field(sample)
+		sum += value
+		res.Min = math.Min(res.Min, value)
+		res.Max = math.Max(res.Max, value)
+	}
+	res.Avg = math.Round(sum/float64(len(samples))*100) / 100
+
+	return res
+}
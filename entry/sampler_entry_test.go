@@ -0,0 +1,178 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-entry/v2/os"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSystemSampleMock(timestamp time.Time, memUsedMb uint64, routines int) *SystemSample {
+	return &SystemSample{
+		Timestamp: timestamp,
+		CpuInfo:   &rkos.CpuInfo{},
+		MemInfo:   &rkos.MemInfo{MemUsedMb: memUsedMb},
+		GoEnvInfo: &rkos.GoEnvInfo{RoutinesCount: routines},
+	}
+}
+
+func TestRegisterSamplerEntry(t *testing.T) {
+	// disabled
+	assert.Nil(t, RegisterSamplerEntry(&BootSampler{}))
+
+	// default values
+	entry := RegisterSamplerEntry(&BootSampler{Enabled: true}, WithNameSamplerEntry("ut-sampler"))
+	assert.Equal(t, "ut-sampler", entry.GetName())
+	assert.Equal(t, SamplerEntryType, entry.GetType())
+	assert.NotEmpty(t, entry.GetDescription())
+	assert.Equal(t, 10*time.Second, entry.Interval)
+	assert.Equal(t, 360, entry.Capacity)
+	assert.Contains(t, entry.String(), `"capacity":360`)
+	assert.Nil(t, entry.UnmarshalJSON(nil))
+}
+
+func TestRegisterSamplerEntryYAML(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	bootStr := `
+---
+sampler:
+  enabled: true
+  intervalSec: 1
+  capacity: 5
+`
+	entries := RegisterSamplerEntryYAML([]byte(bootStr))
+	assert.Len(t, entries, 1)
+
+	entry := entries["SamplerEntry"].(*SamplerEntry)
+	assert.Equal(t, time.Second, entry.Interval)
+	assert.Equal(t, 5, entry.Capacity)
+	assert.NotNil(t, GlobalAppCtx.GetEntry(SamplerEntryType, "SamplerEntry"))
+
+	// disabled
+	assert.Empty(t, RegisterSamplerEntryYAML([]byte("sampler:\n  enabled: false")))
+}
+
+func TestSamplerEntry_Bootstrap_Interrupt(t *testing.T) {
+	defer assertNotPanic(t)
+
+	entry := RegisterSamplerEntry(&BootSampler{Enabled: true, Capacity: 5})
+	entry.Interval = 10 * time.Millisecond
+
+	entry.Bootstrap(context.TODO())
+	assert.Eventually(t, func() bool {
+		return len(entry.History(0)) > 1
+	}, time.Second, 10*time.Millisecond)
+	entry.Interrupt(context.TODO())
+
+	// stopped
+	size := len(entry.History(0))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, size, len(entry.History(0)))
+
+	// interrupt twice
+	entry.Interrupt(context.TODO())
+}
+
+func TestSamplerEntry_RingBuffer(t *testing.T) {
+	entry := RegisterSamplerEntry(&BootSampler{Enabled: true, Capacity: 3})
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		entry.add(newSystemSampleMock(now.Add(time.Duration(i-4)*time.Minute), uint64(i), i))
+	}
+
+	// the oldest samples are dropped, order is kept
+	history := entry.History(0)
+	assert.Len(t, history, 3)
+	assert.Equal(t, uint64(2), history[0].MemInfo.MemUsedMb)
+	assert.Equal(t, uint64(4), history[2].MemInfo.MemUsedMb)
+
+	// filter by window
+	history = entry.History(90 * time.Second)
+	assert.Len(t, history, 2)
+	assert.Equal(t, uint64(3), history[0].MemInfo.MemUsedMb)
+}
+
+func TestSamplerEntry_RingBuffer_WithoutCapacity(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// zero value
+	entry := &SamplerEntry{}
+	assert.Empty(t, entry.History(0))
+	entry.add(newSystemSampleMock(time.Now(), 1, 1))
+	assert.Len(t, entry.History(0), 1)
+	assert.Equal(t, defaultSamplerCapacity, entry.Capacity)
+
+	// negative capacity
+	entry = &SamplerEntry{Capacity: -1}
+	entry.add(newSystemSampleMock(time.Now(), 1, 1))
+	assert.Len(t, entry.History(0), 1)
+}
+
+func TestSamplerEntry_Stats(t *testing.T) {
+	entry := RegisterSamplerEntry(&BootSampler{Enabled: true, Capacity: 10})
+
+	// empty
+	stats := entry.Stats(time.Minute)
+	assert.Zero(t, stats.Samples)
+	assert.Equal(t, &SampleStat{}, stats.MemUsedMb)
+
+	now := time.Now()
+	entry.add(newSystemSampleMock(now.Add(-10*time.Minute), 100, 100))
+	entry.add(newSystemSampleMock(now.Add(-2*time.Minute), 10, 5))
+	entry.add(newSystemSampleMock(now.Add(-time.Minute), 20, 10))
+	entry.add(newSystemSampleMock(now, 30, 6))
+
+	stats = entry.Stats(5 * time.Minute)
+	assert.Equal(t, "5m0s", stats.Window)
+	assert.Equal(t, 3, stats.Samples)
+	assert.Equal(t, &SampleStat{Min: 10, Max: 30, Avg: 20}, stats.MemUsedMb)
+	assert.Equal(t, &SampleStat{Min: 5, Max: 10, Avg: 7}, stats.RoutinesCount)
+	assert.Equal(t, now.Add(-2*time.Minute), stats.From)
+	assert.Equal(t, now, stats.To)
+}
+
+func TestSamplerEntry_Handler(t *testing.T) {
+	entry := RegisterSamplerEntry(&BootSampler{Enabled: true, Capacity: 10})
+	entry.Sample()
+	handler := entry.Handler()
+
+	// default
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/sampler", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	resp := &samplerResp{}
+	assert.Nil(t, json.Unmarshal(writer.Body.Bytes(), resp))
+	assert.Len(t, resp.Samples, 1)
+	assert.Len(t, resp.Stats, len(defaultSamplerWindows))
+	assert.Equal(t, 10, resp.Capacity)
+
+	// with window and stats
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/sampler?window=1h&stats=30s,2h", nil))
+	assert.Equal(t, http.StatusOK, writer.Code)
+	resp = &samplerResp{}
+	assert.Nil(t, json.Unmarshal(writer.Body.Bytes(), resp))
+	assert.Len(t, resp.Stats, 2)
+	assert.Equal(t, "2h0m0s", resp.Stats[1].Window)
+
+	// invalid window
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/sampler?window=invalid", nil))
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+
+	writer = httptest.NewRecorder()
+	handler.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/sampler?stats=invalid", nil))
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+}
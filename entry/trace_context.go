@@ -0,0 +1,89 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-query"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// TraceIdKey field key of trace id in logs and events
+	TraceIdKey = "trace_id"
+	// SpanIdKey field key of span id in logs and events
+	SpanIdKey = "span_id"
+	// RequestIdKey field key of request id in logs
+	RequestIdKey = "request_id"
+)
+
+// requestIdCtxKey key of request id in context
+type requestIdCtxKey struct{}
+
+// ContextWithRequestId returns copy of ctx with request id, which would be logged by LoggerEntry.WithContext
+// and EventEntry.CreateEventWithContext.
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, requestIdCtxKey{}, requestId)
+}
+
+// GetRequestIdFromContext returns request id in ctx, empty string returned if missing.
+func GetRequestIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if v, ok := ctx.Value(requestIdCtxKey{}).(string); ok {
+		return v
+	}
+
+	return ""
+}
+
+// TraceFieldsFromContext returns trace_id and span_id of active span in ctx and request_id in ctx as zap fields.
+//
+// Fields are omitted if span is not valid or request id is missing.
+func TraceFieldsFromContext(ctx context.Context) []zap.Field {
+	res := make([]zap.Field, 0)
+
+	if ctx == nil {
+		return res
+	}
+
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		res = append(res,
+			zap.String(TraceIdKey, spanCtx.TraceID().String()),
+			zap.String(SpanIdKey, spanCtx.SpanID().String()))
+	}
+
+	if requestId := GetRequestIdFromContext(ctx); len(requestId) > 0 {
+		res = append(res, zap.String(RequestIdKey, requestId))
+	}
+
+	return res
+}
+
+// InjectTraceToEvent sets trace id and span id of active span and request id in ctx into event.
+//
+// Span id is added as pair of span_id since rkquery.Event has no field of it.
+func InjectTraceToEvent(ctx context.Context, event rkquery.Event) {
+	if ctx == nil || event == nil {
+		return
+	}
+
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		event.SetTraceId(spanCtx.TraceID().String())
+		event.AddPair(SpanIdKey, spanCtx.SpanID().String())
+	}
+
+	if requestId := GetRequestIdFromContext(ctx); len(requestId) > 0 {
+		event.SetRequestId(requestId)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-query"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+)
+
+func newTraceContextForTest() context.Context {
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	return trace.ContextWithSpanContext(context.Background(), spanCtx)
+}
+
+func TestContextWithRequestId(t *testing.T) {
+	// nil context
+	assert.Empty(t, GetRequestIdFromContext(nil))
+	assert.Equal(t, "ut-request", GetRequestIdFromContext(ContextWithRequestId(nil, "ut-request")))
+
+	// missing
+	assert.Empty(t, GetRequestIdFromContext(context.Background()))
+
+	// happy case
+	ctx := ContextWithRequestId(context.Background(), "ut-request")
+	assert.Equal(t, "ut-request", GetRequestIdFromContext(ctx))
+}
+
+func TestTraceFieldsFromContext(t *testing.T) {
+	// nil and empty context
+	assert.Empty(t, TraceFieldsFromContext(nil))
+	assert.Empty(t, TraceFieldsFromContext(context.Background()))
+
+	// request id only
+	fields := TraceFieldsFromContext(ContextWithRequestId(context.Background(), "ut-request"))
+	assert.Equal(t, []zap.Field{zap.String(RequestIdKey, "ut-request")}, fields)
+
+	// span and request id
+	fields = TraceFieldsFromContext(ContextWithRequestId(newTraceContextForTest(), "ut-request"))
+	assert.Equal(t, []zap.Field{
+		zap.String(TraceIdKey, "0102030405060708090a0b0c0d0e0f10"),
+		zap.String(SpanIdKey, "0102030405060708"),
+		zap.String(RequestIdKey, "ut-request"),
+	}, fields)
+}
+
+func TestInjectTraceToEvent(t *testing.T) {
+	factory := rkquery.NewEventFactory(rkquery.WithZapLogger(zap.NewNop()))
+
+	// nil
+	assert.NotPanics(t, func() {
+		InjectTraceToEvent(nil, factory.CreateEvent())
+		InjectTraceToEvent(context.Background(), nil)
+	})
+
+	// nothing in context
+	event := factory.CreateEvent()
+	InjectTraceToEvent(context.Background(), event)
+	assert.Empty(t, event.GetTraceId())
+	assert.Empty(t, event.GetRequestId())
+
+	// span and request id
+	event = factory.CreateEvent()
+	InjectTraceToEvent(ContextWithRequestId(newTraceContextForTest(), "ut-request"), event)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", event.GetTraceId())
+	assert.Equal(t, "0102030405060708", event.GetValueFromPair(SpanIdKey))
+	assert.Equal(t, "ut-request", event.GetRequestId())
+}
+
+func TestLoggerEntry_WithContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	entry := &LoggerEntry{Logger: zap.New(core)}
+
+	// nothing in context
+	assert.Equal(t, entry.Logger, entry.WithContext(context.Background()))
+
+	entry.WithContext(ContextWithRequestId(newTraceContextForTest(), "ut-request")).Info("ut-message")
+
+	assert.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", fields[TraceIdKey])
+	assert.Equal(t, "0102030405060708", fields[SpanIdKey])
+	assert.Equal(t, "ut-request", fields[RequestIdKey])
+}
+
+func TestEventEntry_CreateEventWithContext(t *testing.T) {
+	entry := NewEventEntryNoop()
+	ctx := ContextWithRequestId(newTraceContextForTest(), "ut-request")
+
+	event := entry.CreateEventWithContext(ctx, rkquery.WithOperation("ut-op"))
+	assert.Equal(t, "ut-op", event.GetOperation())
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", event.GetTraceId())
+	assert.Equal(t, "0102030405060708", event.GetValueFromPair(SpanIdKey))
+	assert.Equal(t, "ut-request", event.GetRequestId())
+
+	event = entry.CreateEventThreadSafeWithContext(ctx)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", event.GetTraceId())
+	assert.Equal(t, "ut-request", event.GetRequestId())
+}
@@ -0,0 +1,507 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// TracerExporterOtlpHttp exports spans to OTLP collector with HTTP
+	TracerExporterOtlpHttp = "otlp-http"
+	// TracerExporterOtlpGrpc exports spans to OTLP collector with gRPC
+	TracerExporterOtlpGrpc = "otlp-grpc"
+	// TracerExporterStdout writes spans to stdout as JSON
+	TracerExporterStdout = "stdout"
+	// TracerExporterFile writes spans to file as JSON
+	TracerExporterFile = "file"
+	// TracerExporterNoop drops spans, trace id and span id are still generated
+	TracerExporterNoop = "noop"
+
+	// TracerSamplerAlwaysOn samples every span
+	TracerSamplerAlwaysOn = "always_on"
+	// TracerSamplerAlwaysOff samples nothing
+	TracerSamplerAlwaysOff = "always_off"
+	// TracerSamplerTraceIdRatio samples spans with ratio
+	TracerSamplerTraceIdRatio = "traceidratio"
+	// TracerSamplerParentBasedAlwaysOn follows parent span, samples every root span
+	TracerSamplerParentBasedAlwaysOn = "parentbased_always_on"
+	// TracerSamplerParentBasedAlwaysOff follows parent span, samples no root span
+	TracerSamplerParentBasedAlwaysOff = "parentbased_always_off"
+	// TracerSamplerParentBasedTraceIdRatio follows parent span, samples root spans with ratio
+	TracerSamplerParentBasedTraceIdRatio = "parentbased_traceidratio"
+
+	// TracerPropagatorTraceContext W3C trace context
+	TracerPropagatorTraceContext = "tracecontext"
+	// TracerPropagatorBaggage W3C baggage
+	TracerPropagatorBaggage = "baggage"
+	// TracerPropagatorB3 B3 single header
+	TracerPropagatorB3 = "b3"
+	// TracerPropagatorB3Multi B3 multiple headers
+	TracerPropagatorB3Multi = "b3multi"
+)
+
+// BootTracer bootstrap config of tracer.
+// 1: Enabled: Enable tracer entry.
+// 2: Exporter: Exporter of spans, noop is used by default.
+// 3: Sampler: Sampler of spans, parentbased_always_on is used by default.
+// 4: Propagators: Any of tracecontext, baggage, b3 and b3multi, default is tracecontext and baggage.
+// 5: Resource: Attributes added to resource besides attributes derived from application and process.
+type BootTracer struct {
+	Enabled     bool               `yaml:"enabled" json:"enabled"`
+	Exporter    BootTracerExporter `yaml:"exporter" json:"exporter"`
+	Sampler     BootTracerSampler  `yaml:"sampler" json:"sampler"`
+	Propagators []string           `yaml:"propagators" json:"propagators"`
+	Resource    map[string]string  `yaml:"resource" json:"resource"`
+}
+
+// BootTracerExporter bootstrap config of span exporter.
+// 1: Type: One of otlp-http, otlp-grpc, stdout, file and noop, default is noop.
+// 2: Endpoint: Endpoint of OTLP collector like localhost:4318, default endpoint of OTLP exporter is used if empty.
+// 3: UrlPath: Url path of OTLP HTTP exporter, default is /v1/traces.
+// 4: Insecure: Disable TLS of OTLP exporter.
+// 5: Headers: Headers sent to OTLP collector.
+// 6: TimeoutMs: Timeout of each export to OTLP collector.
+// 7: Path: Path of file for file exporter.
+// 8: Pretty: Pretty print spans for stdout and file exporter.
+type BootTracerExporter struct {
+	Type      string            `yaml:"type" json:"type"`
+	Endpoint  string            `yaml:"endpoint" json:"endpoint"`
+	UrlPath   string            `yaml:"urlPath" json:"urlPath"`
+	Insecure  bool              `yaml:"insecure" json:"insecure"`
+	Headers   map[string]string `yaml:"headers" json:"headers"`
+	TimeoutMs int64             `yaml:"timeoutMs" json:"timeoutMs"`
+	Path      string            `yaml:"path" json:"path"`
+	Pretty    bool              `yaml:"pretty" json:"pretty"`
+}
+
+// BootTracerSampler bootstrap config of sampler.
+// 1: Type: One of always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off
+// and parentbased_traceidratio, default is parentbased_always_on.
+// 2: Ratio: Ratio of traceidratio and parentbased_traceidratio sampler between 0 and 1.
+type BootTracerSampler struct {
+	Type  string  `yaml:"type" json:"type"`
+	Ratio float64 `yaml:"ratio" json:"ratio"`
+}
+
+// BootTracerEntry bootstrap config of TracerEntry used by RegisterTracerEntryYAML.
+type BootTracerEntry struct {
+	Tracer BootTracer `yaml:"tracer" json:"tracer"`
+}
+
+// TracerEntry creates OpenTelemetry TracerProvider and propagator.
+//
+// TracerProvider is created and set as global one of OpenTelemetry with propagator while bootstrapping,
+// spans are flushed and TracerProvider is shut down while interrupting.
+type TracerEntry struct {
+	entryName        string                        `json:"-" yaml:"-"`
+	entryType        string                        `json:"-" yaml:"-"`
+	entryDescription string                        `json:"-" yaml:"-"`
+	ExporterType     string                        `json:"-" yaml:"-"`
+	SamplerType      string                        `json:"-" yaml:"-"`
+	Propagators      []string                      `json:"-" yaml:"-"`
+	Exporter         sdktrace.SpanExporter         `json:"-" yaml:"-"`
+	Resource         *resource.Resource            `json:"-" yaml:"-"`
+	Provider         *sdktrace.TracerProvider      `json:"-" yaml:"-"`
+	Propagator       propagation.TextMapPropagator `json:"-" yaml:"-"`
+	sampler          sdktrace.Sampler              `json:"-" yaml:"-"`
+	closer           io.Closer                     `json:"-" yaml:"-"`
+}
+
+// TracerEntryOption option for TracerEntry
+type TracerEntryOption func(entry *TracerEntry)
+
+// WithNameTracerEntry provide name.
+func WithNameTracerEntry(name string) TracerEntryOption {
+	return func(entry *TracerEntry) {
+		entry.entryName = name
+	}
+}
+
+// WithExporterTracerEntry provide sdktrace.SpanExporter, exporter in config would be ignored.
+func WithExporterTracerEntry(exporter sdktrace.SpanExporter) TracerEntryOption {
+	return func(entry *TracerEntry) {
+		if exporter != nil {
+			entry.Exporter = exporter
+		}
+	}
+}
+
+// RegisterTracerEntry create TracerEntry with config, nil returned if not enabled
+func RegisterTracerEntry(boot *BootTracer, opts ...TracerEntryOption) *TracerEntry {
+	if !boot.Enabled {
+		return nil
+	}
+
+	entry := &TracerEntry{
+		entryName:        "TracerEntry",
+		entryType:        TracerEntryType,
+		entryDescription: "Internal RK entry which creates OpenTelemetry tracer provider.",
+		ExporterType:     strings.ToLower(boot.Exporter.Type),
+		SamplerType:      strings.ToLower(boot.Sampler.Type),
+		Propagators:      boot.Propagators,
+	}
+
+	for i := range opts {
+		opts[i](entry)
+	}
+
+	if entry.Exporter == nil {
+		entry.Exporter = entry.newExporter(&boot.Exporter)
+	} else {
+		entry.ExporterType = fmt.Sprintf("%T", entry.Exporter)
+	}
+
+	entry.Resource = newTracerResource(boot.Resource)
+	entry.Propagator = entry.newPropagator()
+	entry.sampler = entry.newSampler(&boot.Sampler)
+
+	return entry
+}
+
+// RegisterTracerEntryYAML register function
+func RegisterTracerEntryYAML(raw []byte) map[string]Entry {
+	boot := &BootTracerEntry{}
+	UnmarshalBootYAML(raw, boot)
+
+	res := map[string]Entry{}
+
+	if entry := RegisterTracerEntry(&boot.Tracer); entry != nil {
+		GlobalAppCtx.AddEntry(entry)
+		res[entry.GetName()] = entry
+	}
+
+	return res
+}
+
+// newExporter creates span exporter with config
+func (entry *TracerEntry) newExporter(boot *BootTracerExporter) sdktrace.SpanExporter {
+	if len(entry.ExporterType) < 1 {
+		entry.ExporterType = TracerExporterNoop
+	}
+
+	timeout := time.Duration(boot.TimeoutMs) * time.Millisecond
+
+	switch entry.ExporterType {
+	case TracerExporterOtlpHttp:
+		opts := make([]otlptracehttp.Option, 0)
+		if len(boot.Endpoint) > 0 {
+			opts = append(opts, otlptracehttp.WithEndpoint(boot.Endpoint))
+		}
+		if len(boot.UrlPath) > 0 {
+			opts = append(opts, otlptracehttp.WithURLPath(boot.UrlPath))
+		}
+		if boot.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(boot.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(boot.Headers))
+		}
+		if timeout > 0 {
+			opts = append(opts, otlptracehttp.WithTimeout(timeout))
+		}
+
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			ShutdownWithError(err)
+		}
+		return exporter
+	case TracerExporterOtlpGrpc:
+		opts := make([]otlptracegrpc.Option, 0)
+		if len(boot.Endpoint) > 0 {
+			opts = append(opts, otlptracegrpc.WithEndpoint(boot.Endpoint))
+		}
+		if boot.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(boot.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(boot.Headers))
+		}
+		if timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(timeout))
+		}
+
+		exporter, err := otlptracegrpc.New(context.Background(), opts...)
+		if err != nil {
+			ShutdownWithError(err)
+		}
+		return exporter
+	case TracerExporterStdout:
+		return newStdoutTraceExporter(os.Stdout, boot.Pretty)
+	case TracerExporterFile:
+		if len(boot.Path) < 1 {
+			ShutdownWithError(fmt.Errorf("path of tracer file exporter is empty"))
+		}
+
+		if err := os.MkdirAll(filepath.Dir(boot.Path), os.ModePerm); err != nil {
+			ShutdownWithError(err)
+		}
+
+		file, err := os.OpenFile(boot.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			ShutdownWithError(err)
+		}
+		entry.closer = file
+
+		return newStdoutTraceExporter(file, boot.Pretty)
+	case TracerExporterNoop:
+		return &noopSpanExporter{}
+	default:
+		ShutdownWithError(fmt.Errorf("tracer exporter %s is not supported", boot.Type))
+	}
+
+	return nil
+}
+
+// newSampler creates sampler with config
+func (entry *TracerEntry) newSampler(boot *BootTracerSampler) sdktrace.Sampler {
+	if len(entry.SamplerType) < 1 {
+		entry.SamplerType = TracerSamplerParentBasedAlwaysOn
+	}
+
+	if strings.HasSuffix(entry.SamplerType, TracerSamplerTraceIdRatio) && (boot.Ratio < 0 || boot.Ratio > 1) {
+		ShutdownWithError(fmt.Errorf("tracer sampler ratio %v is not between 0 and 1", boot.Ratio))
+	}
+
+	switch entry.SamplerType {
+	case TracerSamplerAlwaysOn:
+		return sdktrace.AlwaysSample()
+	case TracerSamplerAlwaysOff:
+		return sdktrace.NeverSample()
+	case TracerSamplerTraceIdRatio:
+		return sdktrace.TraceIDRatioBased(boot.Ratio)
+	case TracerSamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case TracerSamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	case TracerSamplerParentBasedTraceIdRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(boot.Ratio))
+	default:
+		ShutdownWithError(fmt.Errorf("tracer sampler %s is not supported", boot.Type))
+	}
+
+	return nil
+}
+
+// newPropagator creates composite propagator of Propagators
+func (entry *TracerEntry) newPropagator() propagation.TextMapPropagator {
+	if len(entry.Propagators) < 1 {
+		entry.Propagators = []string{TracerPropagatorTraceContext, TracerPropagatorBaggage}
+	}
+
+	propagators := make([]propagation.TextMapPropagator, 0)
+	for i := range entry.Propagators {
+		switch strings.ToLower(entry.Propagators[i]) {
+		case TracerPropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case TracerPropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case TracerPropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case TracerPropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		default:
+			ShutdownWithError(fmt.Errorf("tracer propagator %s is not supported", entry.Propagators[i]))
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...)
+}
+
+// Bootstrap creates TracerProvider which starts batch processor, and sets TracerProvider and propagator
+// as global ones of OpenTelemetry.
+func (entry *TracerEntry) Bootstrap(ctx context.Context) {
+	if entry.Provider == nil {
+		entry.Provider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(entry.Exporter),
+			sdktrace.WithSampler(entry.sampler),
+			sdktrace.WithResource(entry.Resource))
+	}
+
+	otel.SetTracerProvider(entry.Provider)
+	otel.SetTextMapPropagator(entry.Propagator)
+}
+
+// Interrupt flushes spans and shuts down TracerProvider, exporter is shut down directly if not bootstrapped.
+func (entry *TracerEntry) Interrupt(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var err error
+	if entry.Provider != nil {
+		err = entry.Provider.Shutdown(ctx)
+	} else {
+		err = entry.Exporter.Shutdown(ctx)
+	}
+
+	if err != nil {
+		GlobalAppCtx.GetLoggerEntryDefault().Warn("Failed to shutdown tracer provider",
+			zap.String("exporter", entry.ExporterType),
+			zap.Error(err))
+	}
+
+	if entry.closer != nil {
+		entry.closer.Close()
+	}
+}
+
+// GetName returns name of entry.
+func (entry *TracerEntry) GetName() string {
+	return entry.entryName
+}
+
+// GetType returns type of entry.
+func (entry *TracerEntry) GetType() string {
+	return entry.entryType
+}
+
+// GetDescription returns description of entry.
+func (entry *TracerEntry) GetDescription() string {
+	return entry.entryDescription
+}
+
+// String returns string of entry.
+func (entry *TracerEntry) String() string {
+	bytes, _ := json.Marshal(entry)
+	return string(bytes)
+}
+
+// MarshalJSON Marshal entry
+func (entry *TracerEntry) MarshalJSON() ([]byte, error) {
+	resourceAttrs := make(map[string]string)
+	for _, kv := range entry.Resource.Attributes() {
+		resourceAttrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	m := map[string]interface{}{
+		"name":        entry.GetName(),
+		"type":        entry.GetType(),
+		"description": entry.GetDescription(),
+		"exporter":    entry.ExporterType,
+		"sampler":     entry.SamplerType,
+		"propagators": entry.Propagators,
+		"resource":    resourceAttrs,
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON Unmarshal entry
+func (entry *TracerEntry) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// Tracer returns trace.Tracer with name from TracerProvider, noop tracer is returned if not bootstrapped.
+func (entry *TracerEntry) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	if entry.Provider == nil {
+		return trace.NewNoopTracerProvider().Tracer(name, opts...)
+	}
+
+	return entry.Provider.Tracer(name, opts...)
+}
+
+// newTracerResource creates resource with attributes derived from application and process,
+// attributes in config override derived ones.
+func newTracerResource(attrs map[string]string) *resource.Resource {
+	info := NewProcessInfo()
+
+	kvs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(info.AppName),
+		semconv.ServiceVersionKey.String(info.Version),
+		semconv.ProcessPIDKey.Int(os.Getpid()),
+		semconv.ProcessRuntimeNameKey.String("go"),
+		semconv.ProcessRuntimeVersionKey.String(info.GoEnvInfo.Version),
+		semconv.HostNameKey.String(info.OsInfo.Hostname),
+		semconv.HostArchKey.String(info.OsInfo.Arch),
+		semconv.OSTypeKey.String(info.OsInfo.Os),
+	}
+
+	appendIfNotEmpty := func(key attribute.Key, value string) {
+		if len(value) > 0 {
+			kvs = append(kvs, key.String(value))
+		}
+	}
+
+	appendIfNotEmpty(semconv.CloudRegionKey, info.Region)
+	appendIfNotEmpty(semconv.CloudAvailabilityZoneKey, info.AZ)
+	appendIfNotEmpty(semconv.DeploymentEnvironmentKey, info.Domain)
+	appendIfNotEmpty(semconv.ServiceNamespaceKey, info.Realm)
+
+	if env := info.EnvInfo; env != nil {
+		appendIfNotEmpty(semconv.CloudProviderKey, env.Cloud)
+		appendIfNotEmpty(semconv.HostIDKey, env.InstanceId)
+		appendIfNotEmpty(semconv.HostTypeKey, env.InstanceType)
+		appendIfNotEmpty(semconv.ContainerIDKey, env.ContainerId)
+
+		if k8s := env.Kubernetes; k8s != nil {
+			appendIfNotEmpty(semconv.K8SPodNameKey, k8s.PodName)
+			appendIfNotEmpty(semconv.K8SNamespaceNameKey, k8s.PodNamespace)
+			appendIfNotEmpty(semconv.K8SNodeNameKey, k8s.NodeName)
+		}
+	}
+
+	// sort keys of config so that resource is stable
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		kvs = append(kvs, attribute.String(k, attrs[k]))
+	}
+
+	// attributes with same key are deduplicated, last one wins
+	return resource.NewWithAttributes(semconv.SchemaURL, kvs...)
+}
+
+// newStdoutTraceExporter creates exporter which writes spans as JSON
+func newStdoutTraceExporter(writer io.Writer, pretty bool) sdktrace.SpanExporter {
+	opts := []stdouttrace.Option{stdouttrace.WithWriter(writer)}
+	if pretty {
+		opts = append(opts, stdouttrace.WithPrettyPrint())
+	}
+
+	exporter, err := stdouttrace.New(opts...)
+	if err != nil {
+		ShutdownWithError(err)
+	}
+
+	return exporter
+}
+
+// noopSpanExporter drops all spans
+type noopSpanExporter struct{}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *noopSpanExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (e *noopSpanExporter) Shutdown(context.Context) error {
+	return nil
+}
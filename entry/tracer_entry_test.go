@@ -0,0 +1,283 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkentry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegisterTracerEntry(t *testing.T) {
+	// disabled
+	assert.Nil(t, RegisterTracerEntry(&BootTracer{Enabled: false}))
+
+	// with default
+	entry := RegisterTracerEntry(&BootTracer{Enabled: true}, WithNameTracerEntry("ut-tracer"))
+	defer entry.Interrupt(context.TODO())
+
+	assert.Equal(t, "ut-tracer", entry.GetName())
+	assert.Equal(t, TracerEntryType, entry.GetType())
+	assert.NotEmpty(t, entry.GetDescription())
+	assert.NotEmpty(t, entry.String())
+	assert.Nil(t, entry.UnmarshalJSON(nil))
+	assert.Equal(t, TracerExporterNoop, entry.ExporterType)
+	assert.Equal(t, TracerSamplerParentBasedAlwaysOn, entry.SamplerType)
+	assert.Equal(t, []string{TracerPropagatorTraceContext, TracerPropagatorBaggage}, entry.Propagators)
+	assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, entry.Propagator.Fields())
+
+	// provider is created while bootstrapping
+	assert.Nil(t, entry.Provider)
+	_, span := entry.Tracer("ut").Start(context.TODO(), "ut-span")
+	assert.False(t, span.SpanContext().IsValid())
+
+	entry.Bootstrap(context.TODO())
+	assert.NotNil(t, entry.Provider)
+}
+
+func TestTracerEntry_Interrupt_WithoutBootstrap(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "ut.log")
+
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled:  true,
+		Exporter: BootTracerExporter{Type: TracerExporterFile, Path: filePath},
+	})
+
+	defer assertNotPanic(t)
+	entry.Interrupt(context.TODO())
+	assert.Nil(t, entry.Provider)
+}
+
+func TestRegisterTracerEntryYAML(t *testing.T) {
+	defer GlobalAppCtx.clearEntries()
+
+	bootStr := `
+---
+tracer:
+  enabled: true
+  exporter:
+    type: noop
+  sampler:
+    type: traceidratio
+    ratio: 0.5
+  propagators: [b3]
+  resource:
+    team: ut-team
+`
+	entries := RegisterTracerEntryYAML([]byte(bootStr))
+	assert.Len(t, entries, 1)
+
+	entry := entries["TracerEntry"].(*TracerEntry)
+	defer entry.Interrupt(context.TODO())
+
+	assert.Equal(t, TracerExporterNoop, entry.ExporterType)
+	assert.Equal(t, TracerSamplerTraceIdRatio, entry.SamplerType)
+	assert.Equal(t, []string{"b3"}, entry.Propagators)
+	assert.NotNil(t, GlobalAppCtx.GetEntry(TracerEntryType, "TracerEntry"))
+
+	m := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(entry.String()), &m))
+	assert.Equal(t, "ut-team", m["resource"].(map[string]interface{})["team"])
+
+	// disabled
+	assert.Empty(t, RegisterTracerEntryYAML([]byte("tracer:\n  enabled: false")))
+}
+
+func TestRegisterTracerEntry_Invalid(t *testing.T) {
+	// invalid exporter
+	func() {
+		defer assertPanic(t)
+		RegisterTracerEntry(&BootTracer{Enabled: true, Exporter: BootTracerExporter{Type: "invalid"}})
+	}()
+
+	// file exporter without path
+	func() {
+		defer assertPanic(t)
+		RegisterTracerEntry(&BootTracer{Enabled: true, Exporter: BootTracerExporter{Type: TracerExporterFile}})
+	}()
+
+	// invalid sampler
+	func() {
+		defer assertPanic(t)
+		RegisterTracerEntry(&BootTracer{Enabled: true, Sampler: BootTracerSampler{Type: "invalid"}})
+	}()
+
+	// invalid ratio
+	func() {
+		defer assertPanic(t)
+		RegisterTracerEntry(&BootTracer{Enabled: true, Sampler: BootTracerSampler{Type: TracerSamplerParentBasedTraceIdRatio, Ratio: 2}})
+	}()
+
+	// invalid propagator
+	func() {
+		defer assertPanic(t)
+		RegisterTracerEntry(&BootTracer{Enabled: true, Propagators: []string{"invalid"}})
+	}()
+}
+
+func TestTracerEntry_WithExporter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled:  true,
+		Resource: map[string]string{"team": "ut-team"},
+	}, WithExporterTracerEntry(exporter))
+	assert.Equal(t, "*tracetest.InMemoryExporter", entry.ExporterType)
+
+	entry.Bootstrap(context.TODO())
+	assert.Equal(t, entry.Provider, otel.GetTracerProvider())
+
+	_, span := entry.Tracer("ut").Start(context.TODO(), "ut-span")
+	span.End()
+
+	// spans in InMemoryExporter are cleared after shutdown
+	assert.Nil(t, entry.Provider.ForceFlush(context.TODO()))
+	defer entry.Interrupt(context.TODO())
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "ut-span", spans[0].Name)
+
+	attrs := map[string]string{}
+	for _, kv := range spans[0].Resource.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, GlobalAppCtx.GetAppInfoEntry().AppName, attrs["service.name"])
+	assert.Equal(t, GlobalAppCtx.GetAppInfoEntry().Version, attrs["service.version"])
+	assert.Equal(t, "go", attrs["process.runtime.name"])
+	assert.Equal(t, "ut-team", attrs["team"])
+	assert.NotEmpty(t, attrs["process.pid"])
+}
+
+func TestTracerEntry_Sampler(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled: true,
+		Sampler: BootTracerSampler{Type: TracerSamplerAlwaysOff},
+	}, WithExporterTracerEntry(exporter))
+	entry.Bootstrap(context.TODO())
+
+	_, span := entry.Tracer("ut").Start(context.TODO(), "ut-span")
+	assert.False(t, span.SpanContext().IsSampled())
+	assert.True(t, span.SpanContext().HasTraceID())
+	span.End()
+
+	assert.Nil(t, entry.Provider.ForceFlush(context.TODO()))
+	assert.Empty(t, exporter.GetSpans())
+	entry.Interrupt(context.TODO())
+}
+
+func TestTracerEntry_Propagators(t *testing.T) {
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled:     true,
+		Exporter:    BootTracerExporter{Type: TracerExporterNoop},
+		Propagators: []string{"tracecontext", "B3", "b3multi"},
+	})
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+
+	ctx, span := entry.Tracer("ut").Start(context.TODO(), "ut-span")
+	defer span.End()
+
+	header := http.Header{}
+	entry.Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+
+	traceId := span.SpanContext().TraceID().String()
+	assert.Contains(t, header.Get("traceparent"), traceId)
+	assert.True(t, strings.HasPrefix(header.Get("b3"), traceId))
+	assert.Equal(t, traceId, header.Get("X-B3-TraceId"))
+
+	// extract from B3 header only
+	header = http.Header{}
+	header.Set("X-B3-TraceId", traceId)
+	header.Set("X-B3-SpanId", span.SpanContext().SpanID().String())
+	header.Set("X-B3-Sampled", "1")
+	extracted := entry.Propagator.Extract(context.TODO(), propagation.HeaderCarrier(header))
+
+	_, child := entry.Tracer("ut").Start(extracted, "ut-child")
+	defer child.End()
+	assert.Equal(t, traceId, child.SpanContext().TraceID().String())
+}
+
+func TestTracerEntry_FileExporter(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "trace", "ut.log")
+
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled: true,
+		Exporter: BootTracerExporter{
+			Type: TracerExporterFile,
+			Path: filePath,
+		},
+	})
+	entry.Bootstrap(context.TODO())
+
+	_, span := entry.Tracer("ut").Start(context.TODO(), "ut-span")
+	span.End()
+	entry.Interrupt(context.TODO())
+
+	bytes, err := os.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(bytes), `"Name":"ut-span"`)
+}
+
+func TestTracerEntry_OtlpHttpExporter(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/ut/traces", request.URL.Path)
+		assert.Equal(t, "ut-value", request.Header.Get("ut-key"))
+		atomic.AddInt32(&received, 1)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled: true,
+		Exporter: BootTracerExporter{
+			Type:      "OTLP-HTTP",
+			Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+			UrlPath:   "/ut/traces",
+			Insecure:  true,
+			Headers:   map[string]string{"ut-key": "ut-value"},
+			TimeoutMs: 1000,
+		},
+	})
+	assert.Equal(t, TracerExporterOtlpHttp, entry.ExporterType)
+	entry.Bootstrap(context.TODO())
+
+	_, span := entry.Tracer("ut").Start(context.TODO(), "ut-span")
+	span.End()
+	entry.Interrupt(context.TODO())
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&received))
+}
+
+func TestTracerEntry_OtlpGrpcExporter(t *testing.T) {
+	entry := RegisterTracerEntry(&BootTracer{
+		Enabled: true,
+		Exporter: BootTracerExporter{
+			Type:      TracerExporterOtlpGrpc,
+			Endpoint:  "localhost:4317",
+			Insecure:  true,
+			Headers:   map[string]string{"ut-key": "ut-value"},
+			TimeoutMs: 100,
+		},
+	})
+	assert.Equal(t, TracerExporterOtlpGrpc, entry.ExporterType)
+
+	defer assertNotPanic(t)
+	entry.Interrupt(context.TODO())
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Aggregate combines errors into one ErrorInterface built by builder.
+//
+// Code and msg are always used as top level error.
+// If builder implements MultiErrorBuilder like ErrorBuilderAMZN, code and msg become the first element
+// and every error becomes an element after it.
+// Otherwise, details of every error are flattened into details, message of error would be used as detail if it has no details.
+// Errors are kept as causes in both cases, so errors.Is and errors.As work with every aggregated error.
+func Aggregate(builder ErrorBuilder, code int, msg string, errs ...ErrorInterface) ErrorInterface {
+	if builder == nil {
+		builder = NewErrorBuilderGoogle()
+	}
+
+	list := make([]ErrorInterface, 0, len(errs))
+	for i := range errs {
+		if errs[i] != nil {
+			list = append(list, errs[i])
+		}
+	}
+
+	if len(list) < 1 {
+		return builder.New(code, msg)
+	}
+
+	var res ErrorInterface
+	if multiBuilder, ok := builder.(MultiErrorBuilder); ok {
+		multi := multiBuilder.NewMulti(code, msg)
+		for _, e := range list {
+			multi.Append(e.Code(), e.Message(), e.Details()...)
+		}
+		res = multi
+	} else {
+		details := make([]interface{}, 0)
+		for _, e := range list {
+			if len(e.Details()) > 0 {
+				details = append(details, e.Details()...)
+			} else {
+				details = append(details, e.Message())
+			}
+		}
+		res = builder.New(code, msg, details...)
+	}
+
+	causes := make([]error, 0, len(list))
+	for i := range list {
+		causes = append(causes, list[i])
+	}
+	appendCauses(res, causes...)
+
+	return res
+}
+
+// appendCauses appends causes into chain of builtin errors
+func appendCauses(err ErrorInterface, causes ...error) {
+	var chain **errorChain
+
+	switch v := err.(type) {
+	case *ErrorGoogle:
+		chain = &v.chain
+	case *ErrorAMZN:
+		chain = &v.chain
+	case *ErrorProblem:
+		chain = &v.chain
+	default:
+		return
+	}
+
+	if *chain == nil {
+		*chain = newErrorChain(1, nil)
+	}
+	(*chain).causes = append((*chain).causes, causes...)
+}
+
+// ValidationErrorBuilder converts field level validation failures into ErrorInterface.
+//
+// If Builder implements MultiErrorBuilder like ErrorBuilderAMZN, every field violation becomes an element after
+// the first element with Code and Message,
+// otherwise, violations are attached as a single BadRequest detail.
+type ValidationErrorBuilder struct {
+	Builder ErrorBuilder
+	Code    int
+	Message string
+}
+
+// NewValidationErrorBuilder creates ValidationErrorBuilder with http.StatusBadRequest and default message.
+func NewValidationErrorBuilder(builder ErrorBuilder) *ValidationErrorBuilder {
+	return &ValidationErrorBuilder{
+		Builder: builder,
+		Code:    http.StatusBadRequest,
+		Message: "Request validation failed",
+	}
+}
+
+// New creates ErrorInterface with field violations.
+func (b *ValidationErrorBuilder) New(violations ...*FieldViolation) ErrorInterface {
+	builder := b.Builder
+	if builder == nil {
+		builder = NewErrorBuilderGoogle()
+	}
+
+	code := b.Code
+	if code < 1 {
+		code = http.StatusBadRequest
+	}
+
+	if _, ok := builder.(MultiErrorBuilder); ok && len(violations) > 0 {
+		errs := make([]ErrorInterface, 0, len(violations))
+		for i := range violations {
+			errs = append(errs, builder.New(code,
+				fmt.Sprintf("%s: %s", violations[i].Field, violations[i].Description),
+				&BadRequest{FieldViolations: []*FieldViolation{violations[i]}}))
+		}
+		return Aggregate(builder, code, b.Message, errs...)
+	}
+
+	return builder.New(code, b.Message, &BadRequest{
+		FieldViolations: violations,
+	})
+}
+
+// FromError converts struct validation error into ErrorInterface with field violations.
+// See FieldViolationsFromError for supported errors.
+func (b *ValidationErrorBuilder) FromError(err error) ErrorInterface {
+	violations := FieldViolationsFromError(err)
+	res := b.New(violations...)
+	if err != nil {
+		appendCauses(res, err)
+	}
+
+	return res
+}
+
+// fieldError is implemented by field level error of validators like github.com/go-playground/validator.
+type fieldError interface {
+	Namespace() string
+	Field() string
+	Error() string
+}
+
+// fieldViolationsProvider could be implemented by custom validation errors.
+type fieldViolationsProvider interface {
+	FieldViolations() []*FieldViolation
+}
+
+// FieldViolationsFromError extracts field violations from validation error.
+//
+// Supported errors:
+// 1: Error implements FieldViolations() []*FieldViolation
+// 2: Slice of errors which implement Namespace(), Field() and Error(), like validator.ValidationErrors
+// 3: Error implements Unwrap() []error, like errors created by errors.Join
+// 4: Others are converted into a single violation with empty field
+func FieldViolationsFromError(err error) []*FieldViolation {
+	res := make([]*FieldViolation, 0)
+	if err == nil {
+		return res
+	}
+
+	if v, ok := err.(fieldViolationsProvider); ok {
+		return v.FieldViolations()
+	}
+
+	if v, ok := err.(fieldError); ok {
+		field := v.Namespace()
+		if len(field) < 1 {
+			field = v.Field()
+		}
+		return append(res, &FieldViolation{Field: field, Description: v.Error()})
+	}
+
+	if v, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range v.Unwrap() {
+			res = append(res, FieldViolationsFromError(e)...)
+		}
+		return res
+	}
+
+	value := reflect.ValueOf(err)
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			if e, ok := value.Index(i).Interface().(error); ok {
+				res = append(res, FieldViolationsFromError(e)...)
+			}
+		}
+		return res
+	}
+
+	return append(res, &FieldViolation{Description: err.Error()})
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"testing"
+)
+
+// mockFieldError mimics validator.FieldError
+type mockFieldError struct {
+	namespace string
+	field     string
+}
+
+func (e *mockFieldError) Namespace() string { return e.namespace }
+func (e *mockFieldError) Field() string     { return e.field }
+func (e *mockFieldError) Error() string     { return e.field + " is invalid" }
+
+// mockValidationErrors mimics validator.ValidationErrors
+type mockValidationErrors []*mockFieldError
+
+func (e mockValidationErrors) Error() string { return "validation failed" }
+
+func TestErrorAMZN_Append(t *testing.T) {
+	cause := errors.New("ut-cause")
+	err := NewErrorBuilderAMZN().New(http.StatusBadRequest, "first").(*ErrorAMZN)
+	err.Append(http.StatusNotFound, "second", cause)
+
+	assert.Equal(t, 2, err.Len())
+	assert.Equal(t, http.StatusBadRequest, err.Code())
+	assert.Equal(t, "first", err.Message())
+	assert.Equal(t, "second", err.Resp.Errors[1].Err.Message)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Resp.Errors[1].Err.Status)
+	assert.True(t, errors.Is(err, cause))
+
+	// append into error without chain
+	empty := &ErrorAMZN{}
+	empty.Append(0, "", cause)
+	assert.Equal(t, http.StatusInternalServerError, empty.Code())
+	assert.True(t, errors.Is(empty, cause))
+}
+
+func TestErrorElementAMZN_YAML(t *testing.T) {
+	bytes, err := yaml.Marshal(NewErrorBuilderAMZN().New(http.StatusBadRequest, "ut"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(bytes), "error:")
+	assert.Contains(t, string(bytes), "message: ut")
+}
+
+func TestAggregate(t *testing.T) {
+	first := NewErrorBuilderGoogle().New(http.StatusBadRequest, "first", "detail-1")
+	second := NewErrorBuilderGoogle().New(http.StatusNotFound, "second")
+
+	// with amazon style, code and msg are kept as first element and every error becomes element
+	res := Aggregate(NewErrorBuilderAMZN(), http.StatusUnprocessableEntity, "failed", first, nil, second)
+	amzn := res.(*ErrorAMZN)
+	assert.Equal(t, 3, amzn.Len())
+	assert.Equal(t, http.StatusUnprocessableEntity, amzn.Code())
+	assert.Equal(t, "failed", amzn.Message())
+	assert.Empty(t, amzn.Details())
+	assert.Equal(t, "first", amzn.Resp.Errors[1].Err.Message)
+	assert.Equal(t, []interface{}{"detail-1"}, amzn.Resp.Errors[1].Err.Details)
+	assert.Equal(t, http.StatusNotFound, amzn.Resp.Errors[2].Err.Code)
+	assert.True(t, errors.Is(res, first))
+	assert.True(t, errors.Is(res, second))
+
+	// with google and problem style, details are flattened
+	for _, builder := range []ErrorBuilder{nil, NewErrorBuilderGoogle(), NewErrorBuilderProblem()} {
+		res = Aggregate(builder, http.StatusBadRequest, "failed", first, second)
+		assert.Equal(t, http.StatusBadRequest, res.Code())
+		assert.Equal(t, "failed", res.Message())
+		assert.Equal(t, []interface{}{"detail-1", "second"}, res.Details())
+		assert.True(t, errors.Is(res, second))
+	}
+
+	// without errors
+	res = Aggregate(NewErrorBuilderAMZN(), http.StatusBadRequest, "failed")
+	assert.Equal(t, "failed", res.Message())
+
+	// no throwaway error is built
+	builder := &countingBuilder{ErrorBuilder: NewErrorBuilderGoogle()}
+	Aggregate(builder, http.StatusBadRequest, "failed", first, second)
+	assert.Equal(t, 1, builder.count)
+}
+
+// countingBuilder counts calls of New
+type countingBuilder struct {
+	ErrorBuilder
+	count int
+}
+
+func (b *countingBuilder) New(code int, msg string, details ...interface{}) ErrorInterface {
+	b.count++
+	return b.ErrorBuilder.New(code, msg, details...)
+}
+
+func TestValidationErrorBuilder(t *testing.T) {
+	violations := []*FieldViolation{
+		{Field: "user.email", Description: "invalid"},
+		{Field: "user.name", Description: "required"},
+	}
+
+	// google style, single BadRequest detail
+	res := NewValidationErrorBuilder(NewErrorBuilderGoogle()).New(violations...)
+	assert.Equal(t, http.StatusBadRequest, res.Code())
+	assert.Len(t, res.Details(), 1)
+	assert.Len(t, res.Details()[0].(*BadRequest).FieldViolations, 2)
+
+	// amazon style, one element per field
+	res = NewValidationErrorBuilder(NewErrorBuilderAMZN()).New(violations...)
+	amzn := res.(*ErrorAMZN)
+	assert.Equal(t, 3, amzn.Len())
+	assert.Equal(t, "Request validation failed", amzn.Message())
+	assert.Equal(t, "user.email: invalid", amzn.Resp.Errors[1].Err.Message)
+	assert.Equal(t, "user.name: required", amzn.Resp.Errors[2].Err.Message)
+
+	// amazon style without violations
+	res = NewValidationErrorBuilder(NewErrorBuilderAMZN()).New()
+	assert.Equal(t, 1, res.(*ErrorAMZN).Len())
+
+	// default values
+	res = (&ValidationErrorBuilder{}).New(violations...)
+	assert.Equal(t, http.StatusBadRequest, res.Code())
+	assert.IsType(t, &ErrorGoogle{}, res)
+}
+
+func TestValidationErrorBuilder_FromError(t *testing.T) {
+	validationErr := mockValidationErrors{
+		{namespace: "User.Email", field: "Email"},
+		{field: "Name"},
+	}
+
+	res := NewValidationErrorBuilder(NewErrorBuilderProblem()).FromError(validationErr)
+	badRequest := res.Details()[0].(*BadRequest)
+	assert.Equal(t, "User.Email", badRequest.FieldViolations[0].Field)
+	assert.Equal(t, "Email is invalid", badRequest.FieldViolations[0].Description)
+	assert.Equal(t, "Name", badRequest.FieldViolations[1].Field)
+
+	var target mockValidationErrors
+	assert.True(t, errors.As(res, &target))
+
+	bytes, _ := json.Marshal(res)
+	assert.Contains(t, string(bytes), "fieldViolations")
+}
+
+func TestFieldViolationsFromError(t *testing.T) {
+	assert.Empty(t, FieldViolationsFromError(nil))
+
+	// plain error
+	violations := FieldViolationsFromError(errors.New("ut"))
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "ut", violations[0].Description)
+
+	// single field error
+	violations = FieldViolationsFromError(&mockFieldError{field: "Name"})
+	assert.Equal(t, "Name", violations[0].Field)
+
+	// provider
+	violations = FieldViolationsFromError(&mockViolationsError{})
+	assert.Len(t, violations, 1)
+}
+
+type mockViolationsError struct{}
+
+func (e *mockViolationsError) Error() string { return "" }
+
+func (e *mockViolationsError) FieldViolations() []*FieldViolation {
+	return []*FieldViolation{{Field: "f", Description: "d"}}
+}
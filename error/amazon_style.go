@@ -2,6 +2,7 @@ package rkerror
 
 import (
 	"encoding/json"
+	"google.golang.org/grpc/status"
 	"net/http"
 )
 
@@ -12,13 +13,33 @@ func NewErrorBuilderAMZN() ErrorBuilder {
 type ErrorBuilderAMZN struct{}
 
 func (e *ErrorBuilderAMZN) New(code int, msg string, details ...interface{}) ErrorInterface {
-	resp := &ErrorAMZN{}
+	return newErrorAMZN(code, msg, details...)
+}
+
+// NewMulti implements MultiErrorBuilder, code, msg and details become the first element
+func (e *ErrorBuilderAMZN) NewMulti(code int, msg string, details ...interface{}) MultiError {
+	return newErrorAMZN(code, msg, details...)
+}
+
+// newErrorAMZN creates error with one element, stack trace is captured from caller of builder
+func newErrorAMZN(code int, msg string, details ...interface{}) *ErrorAMZN {
+	resp := &ErrorAMZN{
+		chain: newErrorChain(2, details),
+	}
 	resp.Resp.Errors = make([]*ErrorElementAMZN, 0)
+	resp.Resp.Errors = append(resp.Resp.Errors, newErrorElementAMZN(code, msg, details...))
+
+	return resp
+}
+
+// newErrorElementAMZN creates element with error details flattened to string and policy applied
+func newErrorElementAMZN(code int, msg string, details ...interface{}) *ErrorElementAMZN {
+	details = redactDetails(details)
 
 	element := &ErrorElementAMZN{}
 	element.Err.Code = code
 	element.Err.Status = http.StatusText(code)
-	element.Err.Message = msg
+	element.Err.Message = Redact(msg)
 	element.Err.Details = make([]interface{}, 0)
 
 	if code < 1 {
@@ -35,9 +56,7 @@ func (e *ErrorBuilderAMZN) New(code int, msg string, details ...interface{}) Err
 		}
 	}
 
-	resp.Resp.Errors = append(resp.Resp.Errors, element)
-
-	return resp
+	return element
 }
 
 func (e *ErrorBuilderAMZN) NewCustom() ErrorInterface {
@@ -48,6 +67,7 @@ type ErrorAMZN struct {
 	Resp struct {
 		Errors []*ErrorElementAMZN `json:"errors" yaml:"errors"`
 	} `json:"response" yaml:"response"`
+	chain *errorChain `json:"-" yaml:"-"`
 }
 
 type ErrorElementAMZN struct {
@@ -56,9 +76,26 @@ type ErrorElementAMZN struct {
 		Status  string        `json:"status" yaml:"status" example:"Internal Server Error"`
 		Message string        `json:"message" yaml:"message" example:"Internal error occurs"`
 		Details []interface{} `json:"details" yaml:"details"`
-	} `json:"error" json:"error"`
+	} `json:"error" yaml:"error"`
 }
 
+// Append adds a new element into errors, errors passed as details are kept as causes.
+// Code, Message and Details still read the first element.
+func (err *ErrorAMZN) Append(code int, msg string, details ...interface{}) {
+	err.Resp.Errors = append(err.Resp.Errors, newErrorElementAMZN(code, msg, details...))
+
+	if err.chain == nil {
+		err.chain = newErrorChain(1, nil)
+	}
+	err.chain.causes = append(err.chain.causes, newErrorChain(1, details).causes...)
+}
+
+// Len returns number of elements.
+func (err *ErrorAMZN) Len() int {
+	return len(err.Resp.Errors)
+}
+
+// Code returns code of the first element.
 func (err *ErrorAMZN) Code() int {
 	res := 0
 
@@ -69,6 +106,7 @@ func (err *ErrorAMZN) Code() int {
 	return res
 }
 
+// Message returns message of the first element.
 func (err *ErrorAMZN) Message() string {
 	res := ""
 
@@ -79,6 +117,7 @@ func (err *ErrorAMZN) Message() string {
 	return res
 }
 
+// Details returns details of the first element.
 func (err *ErrorAMZN) Details() []interface{} {
 	res := make([]interface{}, 0)
 
@@ -89,6 +128,57 @@ func (err *ErrorAMZN) Details() []interface{} {
 	return res
 }
 
+// ContentType returns application/json
+func (err *ErrorAMZN) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Unwrap returns first error passed as detail.
+func (err *ErrorAMZN) Unwrap() error {
+	return err.chain.unwrap()
+}
+
+// Is reports whether any error passed as detail matches target, or target is ErrorDefinition with same reason.
+func (err *ErrorAMZN) Is(target error) bool {
+	return err.chain.is(err, target)
+}
+
+// As finds first error passed as detail that matches target.
+func (err *ErrorAMZN) As(target interface{}) bool {
+	return err.chain.as(target)
+}
+
+// StackTrace returns stack trace captured in development mode.
+func (err *ErrorAMZN) StackTrace() []string {
+	return err.chain.stackTrace()
+}
+
+// sanitize implements sanitizable, internal details of all elements share the same correlation id
+func (err *ErrorAMZN) sanitize(s *detailsSanitizer) ErrorInterface {
+	found := false
+	for i := range err.Resp.Errors {
+		found = found || hasInternalDetail(err.Resp.Errors[i].Err.Details)
+	}
+	if !found {
+		return err
+	}
+
+	res := *err
+	res.Resp.Errors = make([]*ErrorElementAMZN, 0, len(err.Resp.Errors))
+	for i := range err.Resp.Errors {
+		element := *err.Resp.Errors[i]
+		element.Err.Details = s.apply(element.Err.Details)
+		res.Resp.Errors = append(res.Resp.Errors, &element)
+	}
+
+	return &res
+}
+
+// GRPCStatus converts error into gRPC status, status.FromError would use it automatically
+func (err *ErrorAMZN) GRPCStatus() *status.Status {
+	return ToGrpcStatus(err)
+}
+
 // Error returns string of error
 func (err *ErrorAMZN) Error() string {
 	res := "{}"
@@ -0,0 +1,273 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultCatalog global catalog used by Register and NewFromCatalog
+var DefaultCatalog = NewCatalog()
+
+// placeholderRegex matches placeholder like {name} in message template
+var placeholderRegex = regexp.MustCompile(`\{([A-Za-z0-9_.-]+)\}`)
+
+// ErrorDefinition defines a named error with stable reason.
+//
+// Message and Translations are templates, placeholders like {userId} are replaced by args while building error.
+// Translations is keyed by locale like zh-CN or zh.
+type ErrorDefinition struct {
+	Reason       string            `json:"reason" yaml:"reason" example:"USER_NOT_FOUND"`
+	Code         int               `json:"code" yaml:"code" example:"404"`
+	Message      string            `json:"message" yaml:"message" example:"user {userId} not found"`
+	Description  string            `json:"description,omitempty" yaml:"description,omitempty" example:"User does not exist"`
+	Translations map[string]string `json:"translations,omitempty" yaml:"translations,omitempty"`
+}
+
+// Format returns message in locale with placeholders replaced.
+// Falls back to language without region and then default message if locale not found.
+func (def *ErrorDefinition) Format(locale string, args map[string]interface{}) string {
+	return formatTemplate(def.template(locale), args)
+}
+
+// New builds ErrorInterface with builder, ErrorInfo with reason, domain and args would be attached as first detail.
+func (def *ErrorDefinition) New(builder ErrorBuilder, locale string, args map[string]interface{}, details ...interface{}) ErrorInterface {
+	return def.newWithDomain(builder, "", locale, args, details...)
+}
+
+func (def *ErrorDefinition) newWithDomain(builder ErrorBuilder, domain, locale string, args map[string]interface{}, details ...interface{}) ErrorInterface {
+	if builder == nil {
+		builder = NewErrorBuilderGoogle()
+	}
+
+	info := &ErrorInfo{
+		Reason: def.Reason,
+		Domain: domain,
+	}
+
+	if len(args) > 0 {
+		info.Metadata = make(map[string]string, len(args))
+		for k, v := range args {
+			info.Metadata[k] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	return builder.New(def.Code, def.Format(locale, args), append([]interface{}{info}, details...)...)
+}
+
+// template returns message template of locale
+func (def *ErrorDefinition) template(locale string) string {
+	if len(locale) > 0 && len(def.Translations) > 0 {
+		if v, ok := def.Translations[locale]; ok {
+			return v
+		}
+
+		// zh-CN or zh_CN => zh
+		if i := strings.IndexAny(locale, "-_"); i > 0 {
+			if v, ok := def.Translations[locale[:i]]; ok {
+				return v
+			}
+		}
+	}
+
+	return def.Message
+}
+
+// formatTemplate replaces placeholders with args, placeholders without arg are kept as it is
+func formatTemplate(template string, args map[string]interface{}) string {
+	if len(args) < 1 {
+		return template
+	}
+
+	return placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		key := placeholder[1 : len(placeholder)-1]
+		if v, ok := args[key]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return placeholder
+	})
+}
+
+// copy returns copy of definition, Translations is copied as well
+func (def *ErrorDefinition) copy() *ErrorDefinition {
+	cp := *def
+	if def.Translations != nil {
+		cp.Translations = make(map[string]string, len(def.Translations))
+		for k, v := range def.Translations {
+			cp.Translations[k] = v
+		}
+	}
+
+	return &cp
+}
+
+// Catalog is registry of ErrorDefinition keyed by reason.
+// Domain would be filled into ErrorInfo of every error built from catalog.
+type Catalog struct {
+	Domain      string
+	definitions map[string]*ErrorDefinition
+	lock        sync.RWMutex
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{
+		definitions: make(map[string]*ErrorDefinition),
+	}
+}
+
+// Register adds copies of definitions into catalog, code defaults to 500 if not set.
+// Returns error if any reason is empty or already registered, nothing is registered in that case.
+func (c *Catalog) Register(defs ...*ErrorDefinition) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	copies := make([]*ErrorDefinition, 0, len(defs))
+	seen := make(map[string]bool, len(defs))
+
+	for _, def := range defs {
+		if def == nil || len(def.Reason) < 1 {
+			return fmt.Errorf("reason of error definition is empty")
+		}
+
+		if _, ok := c.definitions[def.Reason]; ok || seen[def.Reason] {
+			return fmt.Errorf("error definition %s already registered", def.Reason)
+		}
+		seen[def.Reason] = true
+
+		cp := def.copy()
+		if cp.Code < 1 {
+			cp.Code = http.StatusInternalServerError
+		}
+
+		copies = append(copies, cp)
+	}
+
+	for _, def := range copies {
+		c.definitions[def.Reason] = def
+	}
+
+	return nil
+}
+
+// Get returns copy of ErrorDefinition by reason, nil if not exist.
+func (c *Catalog) Get(reason string) *ErrorDefinition {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if def, ok := c.definitions[reason]; ok {
+		return def.copy()
+	}
+
+	return nil
+}
+
+// List returns copies of definitions sorted by code and then reason.
+func (c *Catalog) List() []*ErrorDefinition {
+	c.lock.RLock()
+	res := make([]*ErrorDefinition, 0, len(c.definitions))
+	for _, def := range c.definitions {
+		res = append(res, def.copy())
+	}
+	c.lock.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Code != res[j].Code {
+			return res[i].Code < res[j].Code
+		}
+		return res[i].Reason < res[j].Reason
+	})
+
+	return res
+}
+
+// New resolves definition by reason and builds ErrorInterface with builder.
+// Returns internal error with reason as message if reason not registered.
+func (c *Catalog) New(builder ErrorBuilder, reason, locale string, args map[string]interface{}, details ...interface{}) ErrorInterface {
+	def := c.Get(reason)
+	if def == nil {
+		def = &ErrorDefinition{
+			Reason:  reason,
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("unknown error reason %s", reason),
+		}
+	}
+
+	return def.newWithDomain(builder, c.Domain, locale, args, details...)
+}
+
+// WriteJSON writes definitions as JSON array.
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	bytes, err := json.MarshalIndent(c.List(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
+
+// WriteMarkdown writes definitions as Markdown table with translations listed in sections.
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	builder := &strings.Builder{}
+	defs := c.List()
+
+	builder.WriteString("# Errors\n\n")
+	if len(c.Domain) > 0 {
+		builder.WriteString(fmt.Sprintf("Domain: `%s`\n\n", c.Domain))
+	}
+
+	builder.WriteString("| Reason | Code | Status | Message | Description |\n")
+	builder.WriteString("|--------|------|--------|---------|-------------|\n")
+	for _, def := range defs {
+		builder.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s |\n",
+			def.Reason, def.Code, http.StatusText(def.Code), escapeMarkdown(def.Message), escapeMarkdown(def.Description)))
+	}
+
+	for _, def := range defs {
+		if len(def.Translations) < 1 {
+			continue
+		}
+
+		locales := make([]string, 0, len(def.Translations))
+		for k := range def.Translations {
+			locales = append(locales, k)
+		}
+		sort.Strings(locales)
+
+		builder.WriteString(fmt.Sprintf("\n## %s\n\n", def.Reason))
+		builder.WriteString("| Locale | Message |\n")
+		builder.WriteString("|--------|---------|\n")
+		for _, locale := range locales {
+			builder.WriteString(fmt.Sprintf("| %s | %s |\n", locale, escapeMarkdown(def.Translations[locale])))
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// escapeMarkdown escapes characters which break Markdown table
+func escapeMarkdown(in string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(in)
+}
+
+// Register adds definitions into DefaultCatalog.
+func Register(defs ...*ErrorDefinition) error {
+	return DefaultCatalog.Register(defs...)
+}
+
+// NewFromCatalog builds ErrorInterface from definition registered in DefaultCatalog.
+func NewFromCatalog(builder ErrorBuilder, reason, locale string, args map[string]interface{}, details ...interface{}) ErrorInterface {
+	return DefaultCatalog.New(builder, reason, locale, args, details...)
+}
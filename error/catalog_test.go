@@ -0,0 +1,179 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	catalog := NewCatalog()
+	catalog.Domain = "example.com"
+
+	assert.Nil(t, catalog.Register(&ErrorDefinition{
+		Reason:      "USER_NOT_FOUND",
+		Code:        http.StatusNotFound,
+		Message:     "user {userId} not found",
+		Description: "User does not exist | deleted",
+		Translations: map[string]string{
+			"zh":    "用户 {userId} 不存在",
+			"fr-CA": "utilisateur {userId} introuvable",
+		},
+	}, &ErrorDefinition{
+		Reason:  "INTERNAL",
+		Message: "internal error",
+	}))
+
+	return catalog
+}
+
+func TestCatalog_Register(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	// duplicate
+	assert.NotNil(t, catalog.Register(&ErrorDefinition{Reason: "USER_NOT_FOUND"}))
+	// empty reason
+	assert.NotNil(t, catalog.Register(&ErrorDefinition{}))
+	assert.NotNil(t, catalog.Register(nil))
+
+	// default code
+	assert.Equal(t, http.StatusInternalServerError, catalog.Get("INTERNAL").Code)
+	assert.Nil(t, catalog.Get("non-exist"))
+
+	list := catalog.List()
+	assert.Len(t, list, 2)
+	assert.Equal(t, "USER_NOT_FOUND", list[0].Reason)
+}
+
+func TestCatalog_Register_WithCopy(t *testing.T) {
+	catalog := NewCatalog()
+
+	def := &ErrorDefinition{
+		Reason:       "UT",
+		Message:      "ut",
+		Translations: map[string]string{"zh": "ut-zh"},
+	}
+	assert.Nil(t, catalog.Register(def))
+
+	// caller's definition is untouched
+	assert.Equal(t, 0, def.Code)
+	def.Message = "changed"
+	def.Translations["zh"] = "changed"
+
+	registered := catalog.Get("UT")
+	assert.Equal(t, http.StatusInternalServerError, registered.Code)
+	assert.Equal(t, "ut", registered.Message)
+	assert.Equal(t, "ut-zh", registered.Translations["zh"])
+}
+
+func TestCatalog_Get_WithCopy(t *testing.T) {
+	catalog := NewCatalog()
+	assert.Nil(t, catalog.Register(&ErrorDefinition{
+		Reason:       "UT",
+		Message:      "ut",
+		Translations: map[string]string{"zh": "ut-zh"},
+	}))
+
+	// definitions returned by Get and List are copies
+	got := catalog.Get("UT")
+	got.Message = "changed"
+	got.Translations["zh"] = "changed"
+
+	listed := catalog.List()[0]
+	listed.Code = http.StatusBadRequest
+	listed.Translations["zh"] = "changed"
+
+	registered := catalog.Get("UT")
+	assert.Equal(t, "ut", registered.Message)
+	assert.Equal(t, http.StatusInternalServerError, registered.Code)
+	assert.Equal(t, "ut-zh", registered.Translations["zh"])
+}
+
+func TestCatalog_Register_WithInvalidBatch(t *testing.T) {
+	catalog := NewCatalog()
+
+	// invalid definition at the end
+	assert.NotNil(t, catalog.Register(&ErrorDefinition{Reason: "UT-1"}, &ErrorDefinition{Reason: "UT-2"}, nil))
+	assert.Empty(t, catalog.List())
+
+	// duplicate reason in batch
+	assert.NotNil(t, catalog.Register(&ErrorDefinition{Reason: "UT-1"}, &ErrorDefinition{Reason: "UT-1"}))
+	assert.Empty(t, catalog.List())
+
+	assert.Nil(t, catalog.Register(&ErrorDefinition{Reason: "UT-1"}, &ErrorDefinition{Reason: "UT-2"}))
+	assert.Len(t, catalog.List(), 2)
+}
+
+func TestErrorDefinition_Format(t *testing.T) {
+	def := newTestCatalog(t).Get("USER_NOT_FOUND")
+	args := map[string]interface{}{"userId": 1}
+
+	assert.Equal(t, "user 1 not found", def.Format("", args))
+	assert.Equal(t, "user {userId} not found", def.Format("", nil))
+	assert.Equal(t, "用户 1 不存在", def.Format("zh", args))
+	assert.Equal(t, "用户 1 不存在", def.Format("zh-CN", args))
+	assert.Equal(t, "用户 1 不存在", def.Format("zh_TW", args))
+	assert.Equal(t, "utilisateur 1 introuvable", def.Format("fr-CA", args))
+	assert.Equal(t, "user 1 not found", def.Format("fr", args))
+	assert.Equal(t, "user {userId} not found", def.Format("", map[string]interface{}{"other": 1}))
+}
+
+func TestCatalog_New(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	err := catalog.New(NewErrorBuilderProblem(), "USER_NOT_FOUND", "zh-CN", map[string]interface{}{"userId": 1}, "ut-detail")
+	assert.Equal(t, http.StatusNotFound, err.Code())
+	assert.Equal(t, "用户 1 不存在", err.Message())
+	assert.Len(t, err.Details(), 2)
+
+	info := err.Details()[0].(*ErrorInfo)
+	assert.Equal(t, "USER_NOT_FOUND", info.Reason)
+	assert.Equal(t, "example.com", info.Domain)
+	assert.Equal(t, "1", info.Metadata["userId"])
+
+	// unknown reason
+	err = catalog.New(nil, "UNKNOWN", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Code())
+	assert.Contains(t, err.Message(), "UNKNOWN")
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	defer func() {
+		DefaultCatalog = NewCatalog()
+	}()
+
+	assert.Nil(t, Register(&ErrorDefinition{Reason: "UT", Code: http.StatusBadRequest, Message: "ut"}))
+	err := NewFromCatalog(NewErrorBuilderGoogle(), "UT", "", nil)
+	assert.Equal(t, http.StatusBadRequest, err.Code())
+	assert.Equal(t, "ut", err.Message())
+}
+
+func TestCatalog_WriteJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, newTestCatalog(t).WriteJSON(buf))
+
+	defs := make([]*ErrorDefinition, 0)
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &defs))
+	assert.Len(t, defs, 2)
+	assert.Equal(t, "USER_NOT_FOUND", defs[0].Reason)
+	assert.Len(t, defs[0].Translations, 2)
+}
+
+func TestCatalog_WriteMarkdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, newTestCatalog(t).WriteMarkdown(buf))
+
+	doc := buf.String()
+	assert.Contains(t, doc, "Domain: `example.com`")
+	assert.Contains(t, doc, "| USER_NOT_FOUND | 404 | Not Found | user {userId} not found | User does not exist \\| deleted |")
+	assert.Contains(t, doc, "| INTERNAL | 500 | Internal Server Error | internal error |  |")
+	assert.Contains(t, doc, "## USER_NOT_FOUND")
+	assert.Contains(t, doc, "| zh | 用户 {userId} 不存在 |")
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+)
+
+// development is 1 if stack trace should be captured while building error
+var development int32
+
+// SetDevelopment enables or disables development mode.
+// Stack trace would be captured while building error in development mode.
+func SetDevelopment(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&development, 1)
+	} else {
+		atomic.StoreInt32(&development, 0)
+	}
+}
+
+// IsDevelopment returns true if development mode is enabled.
+func IsDevelopment() bool {
+	return atomic.LoadInt32(&development) == 1
+}
+
+// errorChain keeps errors passed as details and optional stack trace.
+// It is never serialized.
+type errorChain struct {
+	causes []error
+	stack  []uintptr
+}
+
+// newErrorChain collects errors from details and captures stack trace in development mode.
+// skip is number of frames to skip of caller of newErrorChain.
+func newErrorChain(skip int, details []interface{}) *errorChain {
+	res := &errorChain{
+		causes: make([]error, 0),
+	}
+
+	for i := range details {
+		detail := details[i]
+		if v, ok := detail.(*InternalDetail); ok && v != nil {
+			detail = v.Value
+		}
+
+		if v, ok := detail.(error); ok && v != nil {
+			res.causes = append(res.causes, v)
+		}
+	}
+
+	if IsDevelopment() {
+		pcs := make([]uintptr, 32)
+		n := runtime.Callers(skip+2, pcs)
+		res.stack = pcs[:n]
+	}
+
+	return res
+}
+
+// unwrap returns first cause
+func (c *errorChain) unwrap() error {
+	if c == nil || len(c.causes) < 1 {
+		return nil
+	}
+
+	return c.causes[0]
+}
+
+// is reports whether any cause matches target or target is ErrorDefinition with same reason of err
+func (c *errorChain) is(err ErrorInterface, target error) bool {
+	if def, ok := target.(*ErrorDefinition); ok && def != nil {
+		if reason := Reason(err); len(reason) > 0 && reason == def.Reason {
+			return true
+		}
+	}
+
+	if c == nil {
+		return false
+	}
+
+	for i := range c.causes {
+		if errors.Is(c.causes[i], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// as finds first cause that matches target
+func (c *errorChain) as(target interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	for i := range c.causes {
+		if errors.As(c.causes[i], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stackTrace formats captured stack as function file:line
+func (c *errorChain) stackTrace() []string {
+	res := make([]string, 0)
+	if c == nil || len(c.stack) < 1 {
+		return res
+	}
+
+	frames := runtime.CallersFrames(c.stack)
+	for {
+		frame, more := frames.Next()
+		res = append(res, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+
+	return res
+}
+
+// Reason returns reason of first ErrorInfo in details of err, empty string if not exist.
+func Reason(err ErrorInterface) string {
+	if err == nil {
+		return ""
+	}
+
+	for _, detail := range err.Details() {
+		if v, ok := detail.(*ErrorInfo); ok {
+			return v.Reason
+		}
+	}
+
+	return ""
+}
+
+// Error returns reason of definition, so that ErrorDefinition could be used as sentinel with errors.Is.
+func (def *ErrorDefinition) Error() string {
+	return def.Reason
+}
+
+// FromError converts arbitrary error into ErrorInterface built by DefaultBuilder.
+func FromError(err error) ErrorInterface {
+	return FromErrorWithBuilder(DefaultBuilder(), err)
+}
+
+// FromErrorWithBuilder converts arbitrary error into ErrorInterface built by builder.
+// Original error is kept as cause, so errors.Is and errors.As still work with returned error.
+//
+// 1: nil returns nil
+// 2: ErrorInterface in chain is returned as it is
+// 3: ErrorDefinition is built with builder
+// 4: gRPC status is converted with FromGrpcStatus
+// 5: context and os errors are mapped to HTTP code, others are treated as internal error
+//
+// Message of 5 is status text of code, original error is passed as InternalDetail.
+func FromErrorWithBuilder(builder ErrorBuilder, err error) ErrorInterface {
+	if err == nil {
+		return nil
+	}
+
+	if builder == nil {
+		builder = NewErrorBuilderGoogle()
+	}
+
+	var errInterface ErrorInterface
+	if errors.As(err, &errInterface) {
+		return errInterface
+	}
+
+	var def *ErrorDefinition
+	if errors.As(err, &def) {
+		return def.New(builder, "", nil, err)
+	}
+
+	if s, ok := status.FromError(err); ok {
+		res := FromGrpcStatus(s, builder)
+		// keep original error as cause
+		return builder.New(res.Code(), res.Message(), append(res.Details(), err)...)
+	}
+
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		code = http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		code = 499
+	case errors.Is(err, os.ErrNotExist):
+		code = http.StatusNotFound
+	case errors.Is(err, os.ErrExist):
+		code = http.StatusConflict
+	case errors.Is(err, os.ErrPermission):
+		code = http.StatusForbidden
+	}
+
+	// text of error may contain sensitive data like file path or SQL, hide it from client in production mode
+	return builder.New(code, statusText(code), Internal(err))
+}
+
+// statusText returns text of HTTP code, including 499 which is not defined in net/http
+func statusText(code int) string {
+	if code == 499 {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(code)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/fs"
+	"net/http"
+	"os"
+	"testing"
+)
+
+type customErr struct {
+	msg string
+}
+
+func (e *customErr) Error() string {
+	return e.msg
+}
+
+func allBuilders() []ErrorBuilder {
+	return []ErrorBuilder{NewErrorBuilderGoogle(), NewErrorBuilderAMZN(), NewErrorBuilderProblem()}
+}
+
+func TestError_Unwrap_Is_As(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	custom := &customErr{msg: "custom"}
+
+	for _, builder := range allBuilders() {
+		err := builder.New(http.StatusInternalServerError, "ut", "string-detail", fmt.Errorf("wrapped: %w", sentinel), custom)
+
+		assert.True(t, errors.Is(err, sentinel))
+		assert.False(t, errors.Is(err, os.ErrNotExist))
+		assert.Equal(t, "wrapped: sentinel", errors.Unwrap(err).Error())
+
+		var target *customErr
+		assert.True(t, errors.As(err, &target))
+		assert.Equal(t, "custom", target.msg)
+
+		// causes are serialized as string only
+		assert.Contains(t, err.Error(), "wrapped: sentinel")
+		assert.NotContains(t, err.Error(), "chain")
+	}
+
+	// error without chain
+	err := &ErrorGoogle{}
+	assert.Nil(t, err.Unwrap())
+	assert.False(t, errors.Is(err, sentinel))
+	assert.Empty(t, err.StackTrace())
+}
+
+func TestError_Is_WithDefinition(t *testing.T) {
+	catalog := NewCatalog()
+	userNotFound := &ErrorDefinition{Reason: "USER_NOT_FOUND", Code: http.StatusNotFound}
+	other := &ErrorDefinition{Reason: "OTHER"}
+	assert.Nil(t, catalog.Register(userNotFound, other))
+
+	for _, builder := range allBuilders() {
+		err := catalog.New(builder, "USER_NOT_FOUND", "", nil)
+		assert.True(t, errors.Is(err, userNotFound))
+		assert.False(t, errors.Is(err, other))
+		assert.Equal(t, "USER_NOT_FOUND", Reason(err))
+	}
+
+	// wrapped by fmt.Errorf
+	err := fmt.Errorf("handler: %w", userNotFound.New(nil, "", nil))
+	assert.True(t, errors.Is(err, userNotFound))
+	assert.Equal(t, "", Reason(nil))
+}
+
+func TestError_StackTrace(t *testing.T) {
+	// production mode
+	assert.False(t, IsDevelopment())
+	assert.Empty(t, NewErrorBuilderGoogle().New(http.StatusInternalServerError, "").(*ErrorGoogle).StackTrace())
+
+	SetDevelopment(true)
+	defer SetDevelopment(false)
+
+	for _, builder := range allBuilders() {
+		err := builder.New(http.StatusInternalServerError, "")
+		stack := err.(interface{ StackTrace() []string }).StackTrace()
+		assert.NotEmpty(t, stack)
+		assert.Contains(t, stack[0], "TestError_StackTrace")
+
+		// stack trace is not serialized
+		bytes, _ := json.Marshal(err)
+		assert.NotContains(t, string(bytes), "TestError_StackTrace")
+	}
+}
+
+func TestFromError(t *testing.T) {
+	assert.Nil(t, FromError(nil))
+
+	// ErrorInterface in chain
+	origin := NewErrorBuilderAMZN().New(http.StatusConflict, "")
+	assert.Equal(t, origin, FromError(fmt.Errorf("wrapped: %w", origin)))
+
+	// definition
+	def := &ErrorDefinition{Reason: "UT", Code: http.StatusTeapot, Message: "ut"}
+	err := FromError(def)
+	assert.Equal(t, http.StatusTeapot, err.Code())
+	assert.True(t, errors.Is(err, def))
+
+	// grpc status
+	err = FromError(status.Error(codes.NotFound, "not found"))
+	assert.Equal(t, http.StatusNotFound, err.Code())
+	assert.Equal(t, "not found", err.Message())
+
+	// context errors
+	err = FromError(context.DeadlineExceeded)
+	assert.Equal(t, http.StatusGatewayTimeout, err.Code())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, http.StatusText(http.StatusGatewayTimeout), err.Message())
+	assert.Equal(t, 499, FromError(context.Canceled).Code())
+	assert.Equal(t, "Client Closed Request", FromError(context.Canceled).Message())
+
+	// os errors
+	_, openErr := os.Open("/non-exist-file")
+	err = FromError(openErr)
+	assert.Equal(t, http.StatusNotFound, err.Code())
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.Equal(t, http.StatusText(http.StatusNotFound), err.Message())
+	assert.NotContains(t, err.Message(), "/non-exist-file")
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, http.StatusConflict, FromError(os.ErrExist).Code())
+	assert.Equal(t, http.StatusForbidden, FromError(os.ErrPermission).Code())
+
+	// others
+	err = FromErrorWithBuilder(NewErrorBuilderProblem(), errors.New("select * from users"))
+	assert.IsType(t, &ErrorProblem{}, err)
+	assert.Equal(t, http.StatusInternalServerError, err.Code())
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), err.Message())
+
+	// nil builder
+	assert.IsType(t, &ErrorGoogle{}, FromErrorWithBuilder(nil, errors.New("ut")))
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"time"
+)
+
+const typeUrlPrefix = "type.googleapis.com/"
+
+// Detail is typed error detail which matches message defined in google.rpc error_details.
+// It could be passed to ErrorBuilder.New as details and will be serialized with @type member,
+// and converted into google.rpc message while converting to gRPC status.
+// Referred: https://github.com/googleapis/googleapis/blob/master/google/rpc/error_details.proto
+type Detail interface {
+	// ToProto converts detail into google.rpc message
+	ToProto() proto.Message
+}
+
+// FieldViolation describes a single bad request field.
+type FieldViolation struct {
+	Field       string `json:"field" yaml:"field" example:"user.email"`
+	Description string `json:"description" yaml:"description" example:"email is invalid"`
+}
+
+// BadRequest describes violations in a client request, matches google.rpc.BadRequest.
+type BadRequest struct {
+	FieldViolations []*FieldViolation `json:"fieldViolations" yaml:"fieldViolations"`
+}
+
+// ToProto converts to errdetails.BadRequest
+func (d *BadRequest) ToProto() proto.Message {
+	res := &errdetails.BadRequest{}
+	for i := range d.FieldViolations {
+		res.FieldViolations = append(res.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       d.FieldViolations[i].Field,
+			Description: d.FieldViolations[i].Description,
+		})
+	}
+
+	return res
+}
+
+// MarshalJSON marshal with @type
+func (d *BadRequest) MarshalJSON() ([]byte, error) {
+	type alias BadRequest
+	return marshalDetail(d, (*alias)(d))
+}
+
+// RetryInfo describes when the clients can retry a failed request, matches google.rpc.RetryInfo.
+type RetryInfo struct {
+	RetryDelay time.Duration `json:"-" yaml:"retryDelay" example:"1s"`
+}
+
+// ToProto converts to errdetails.RetryInfo
+func (d *RetryInfo) ToProto() proto.Message {
+	return &errdetails.RetryInfo{
+		RetryDelay: durationpb.New(d.RetryDelay),
+	}
+}
+
+// MarshalJSON marshal with @type, retryDelay is formatted as seconds with s suffix like 1.5s
+func (d *RetryInfo) MarshalJSON() ([]byte, error) {
+	type retryInfo struct {
+		RetryDelay string `json:"retryDelay"`
+	}
+
+	return marshalDetail(d, &retryInfo{
+		RetryDelay: formatProtoDuration(d.RetryDelay),
+	})
+}
+
+// ErrorInfo describes the cause of the error with structured details, matches google.rpc.ErrorInfo.
+type ErrorInfo struct {
+	Reason   string            `json:"reason" yaml:"reason" example:"USER_NOT_FOUND"`
+	Domain   string            `json:"domain" yaml:"domain" example:"example.com"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// ToProto converts to errdetails.ErrorInfo
+func (d *ErrorInfo) ToProto() proto.Message {
+	return &errdetails.ErrorInfo{
+		Reason:   d.Reason,
+		Domain:   d.Domain,
+		Metadata: d.Metadata,
+	}
+}
+
+// MarshalJSON marshal with @type
+func (d *ErrorInfo) MarshalJSON() ([]byte, error) {
+	type alias ErrorInfo
+	return marshalDetail(d, (*alias)(d))
+}
+
+// QuotaViolation describes a single quota violation.
+type QuotaViolation struct {
+	Subject     string `json:"subject" yaml:"subject" example:"clientip:127.0.0.1"`
+	Description string `json:"description" yaml:"description" example:"Daily Limit exceeded"`
+}
+
+// QuotaFailure describes how a quota check failed, matches google.rpc.QuotaFailure.
+type QuotaFailure struct {
+	Violations []*QuotaViolation `json:"violations" yaml:"violations"`
+}
+
+// ToProto converts to errdetails.QuotaFailure
+func (d *QuotaFailure) ToProto() proto.Message {
+	res := &errdetails.QuotaFailure{}
+	for i := range d.Violations {
+		res.Violations = append(res.Violations, &errdetails.QuotaFailure_Violation{
+			Subject:     d.Violations[i].Subject,
+			Description: d.Violations[i].Description,
+		})
+	}
+
+	return res
+}
+
+// MarshalJSON marshal with @type
+func (d *QuotaFailure) MarshalJSON() ([]byte, error) {
+	type alias QuotaFailure
+	return marshalDetail(d, (*alias)(d))
+}
+
+// marshalDetail marshal body and prepend @type member with full name of google.rpc message
+func marshalDetail(d Detail, body interface{}) ([]byte, error) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal(bytes, &m); err != nil {
+		return nil, err
+	}
+
+	typeUrl, _ := json.Marshal(typeUrlPrefix + string(proto.MessageName(d.ToProto())))
+	m["@type"] = typeUrl
+
+	return json.Marshal(m)
+}
+
+// formatProtoDuration formats duration as JSON representation of google.protobuf.Duration
+func formatProtoDuration(d time.Duration) string {
+	bytes, _ := json.Marshal(d.Seconds())
+	return string(bytes) + "s"
+}
+
+// detailFromProto converts google.rpc message into Detail, returns nil if not supported
+func detailFromProto(msg proto.Message) Detail {
+	switch v := msg.(type) {
+	case *errdetails.BadRequest:
+		res := &BadRequest{FieldViolations: make([]*FieldViolation, 0)}
+		for _, violation := range v.GetFieldViolations() {
+			res.FieldViolations = append(res.FieldViolations, &FieldViolation{
+				Field:       violation.GetField(),
+				Description: violation.GetDescription(),
+			})
+		}
+		return res
+	case *errdetails.RetryInfo:
+		return &RetryInfo{RetryDelay: v.GetRetryDelay().AsDuration()}
+	case *errdetails.ErrorInfo:
+		return &ErrorInfo{Reason: v.GetReason(), Domain: v.GetDomain(), Metadata: v.GetMetadata()}
+	case *errdetails.RequestInfo:
+		return &CorrelationInfo{CorrelationId: v.GetRequestId()}
+	case *errdetails.QuotaFailure:
+		res := &QuotaFailure{Violations: make([]*QuotaViolation, 0)}
+		for _, violation := range v.GetViolations() {
+			res.Violations = append(res.Violations, &QuotaViolation{
+				Subject:     violation.GetSubject(),
+				Description: violation.GetDescription(),
+			})
+		}
+		return res
+	}
+
+	return nil
+}
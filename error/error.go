@@ -6,6 +6,13 @@
 // Package rkerror defines RK style API errors.
 package rkerror
 
+const (
+	// ContentTypeJSON media type of google and amazon style errors
+	ContentTypeJSON = "application/json"
+	// ContentTypeProblemJSON media type of RFC 7807 style errors
+	ContentTypeProblemJSON = "application/problem+json"
+)
+
 type ErrorInterface interface {
 	Error() string
 
@@ -21,3 +28,37 @@ type ErrorBuilder interface {
 
 	NewCustom() ErrorInterface
 }
+
+// MultiError is implemented by ErrorInterface which could hold multiple error elements, like ErrorAMZN.
+type MultiError interface {
+	ErrorInterface
+
+	// Append adds a new element
+	Append(code int, msg string, details ...interface{})
+
+	// Len returns number of elements
+	Len() int
+}
+
+// MultiErrorBuilder could be implemented by ErrorBuilder which creates MultiError, like ErrorBuilderAMZN.
+type MultiErrorBuilder interface {
+	ErrorBuilder
+
+	// NewMulti creates MultiError with code, msg and details as the first element
+	NewMulti(code int, msg string, details ...interface{}) MultiError
+}
+
+// ContentTyper could be implemented by ErrorInterface in order to tell media type of serialized error.
+type ContentTyper interface {
+	ContentType() string
+}
+
+// ContentType returns media type which should be used while writing error into HTTP response.
+// Returns application/json if error does not implement ContentTyper.
+func ContentType(err ErrorInterface) string {
+	if v, ok := err.(ContentTyper); ok {
+		return v.ContentType()
+	}
+
+	return ContentTypeJSON
+}
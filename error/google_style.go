@@ -2,6 +2,7 @@ package rkerror
 
 import (
 	"encoding/json"
+	"google.golang.org/grpc/status"
 	"net/http"
 )
 
@@ -12,11 +13,15 @@ func NewErrorBuilderGoogle() ErrorBuilder {
 type ErrorBuilderGoogle struct{}
 
 func (e *ErrorBuilderGoogle) New(code int, msg string, details ...interface{}) ErrorInterface {
-	resp := &ErrorGoogle{}
+	resp := &ErrorGoogle{
+		chain: newErrorChain(1, details),
+	}
+
+	details = redactDetails(details)
 
 	resp.Err.Code = code
 	resp.Err.Status = http.StatusText(code)
-	resp.Err.Message = msg
+	resp.Err.Message = Redact(msg)
 	resp.Err.Details = make([]interface{}, 0)
 
 	if code < 1 {
@@ -49,6 +54,7 @@ type ErrorGoogle struct {
 		Message string        `json:"message" yaml:"message" example:"Internal error occurs"`
 		Details []interface{} `json:"details" yaml:"details"`
 	} `json:"error" yaml:"error"`
+	chain *errorChain `json:"-" yaml:"-"`
 }
 
 func (err *ErrorGoogle) Code() int {
@@ -63,6 +69,47 @@ func (err *ErrorGoogle) Details() []interface{} {
 	return err.Err.Details
 }
 
+// ContentType returns application/json
+func (err *ErrorGoogle) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Unwrap returns first error passed as detail.
+func (err *ErrorGoogle) Unwrap() error {
+	return err.chain.unwrap()
+}
+
+// Is reports whether any error passed as detail matches target, or target is ErrorDefinition with same reason.
+func (err *ErrorGoogle) Is(target error) bool {
+	return err.chain.is(err, target)
+}
+
+// As finds first error passed as detail that matches target.
+func (err *ErrorGoogle) As(target interface{}) bool {
+	return err.chain.as(target)
+}
+
+// StackTrace returns stack trace captured in development mode.
+func (err *ErrorGoogle) StackTrace() []string {
+	return err.chain.stackTrace()
+}
+
+// sanitize implements sanitizable
+func (err *ErrorGoogle) sanitize(s *detailsSanitizer) ErrorInterface {
+	if !hasInternalDetail(err.Err.Details) {
+		return err
+	}
+
+	res := *err
+	res.Err.Details = s.apply(err.Err.Details)
+	return &res
+}
+
+// GRPCStatus converts error into gRPC status, status.FromError would use it automatically
+func (err *ErrorGoogle) GRPCStatus() *status.Status {
+	return ToGrpcStatus(err)
+}
+
 // Error returns string of error
 func (err *ErrorGoogle) Error() string {
 	res := "{}"
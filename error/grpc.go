@@ -0,0 +1,174 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"net/http"
+)
+
+// httpToGrpc mapping of HTTP status code to gRPC code
+// Referred: https://cloud.google.com/apis/design/errors#handling_errors
+var httpToGrpc = map[int]codes.Code{
+	http.StatusOK:                           codes.OK,
+	http.StatusBadRequest:                   codes.InvalidArgument,
+	http.StatusUnauthorized:                 codes.Unauthenticated,
+	http.StatusForbidden:                    codes.PermissionDenied,
+	http.StatusNotFound:                     codes.NotFound,
+	http.StatusConflict:                     codes.Aborted,
+	http.StatusPreconditionFailed:           codes.FailedPrecondition,
+	http.StatusRequestedRangeNotSatisfiable: codes.OutOfRange,
+	http.StatusTooManyRequests:              codes.ResourceExhausted,
+	499:                                     codes.Canceled,
+	http.StatusInternalServerError:          codes.Internal,
+	http.StatusNotImplemented:               codes.Unimplemented,
+	http.StatusServiceUnavailable:           codes.Unavailable,
+	http.StatusGatewayTimeout:               codes.DeadlineExceeded,
+}
+
+// grpcToHttp mapping of gRPC code to HTTP status code
+// Referred: https://cloud.google.com/apis/design/errors#handling_errors
+var grpcToHttp = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HttpCodeToGrpcCode converts HTTP status code to gRPC code.
+// Unknown 2xx is treated as codes.OK, unknown 4xx as codes.FailedPrecondition and others as codes.Unknown.
+func HttpCodeToGrpcCode(code int) codes.Code {
+	if v, ok := httpToGrpc[code]; ok {
+		return v
+	}
+
+	switch {
+	case code >= 200 && code < 300:
+		return codes.OK
+	case code >= 400 && code < 500:
+		return codes.FailedPrecondition
+	}
+
+	return codes.Unknown
+}
+
+// GrpcCodeToHttpCode converts gRPC code to HTTP status code.
+func GrpcCodeToHttpCode(code codes.Code) int {
+	if v, ok := grpcToHttp[code]; ok {
+		return v
+	}
+
+	return http.StatusInternalServerError
+}
+
+// ToGrpcStatus converts ErrorInterface into gRPC status.
+//
+// Detail and proto.Message in details are attached as google.rpc messages,
+// other details are attached as google.rpc.DebugInfo with string value.
+// Policy is applied with Sanitize since status is expected to be returned to client,
+// internal details are logged once and converting the same err again returns the same correlation id.
+func ToGrpcStatus(err ErrorInterface) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+
+	// status is returned to client, apply policy to internal details
+	err = Sanitize(err)
+	s := status.New(HttpCodeToGrpcCode(err.Code()), err.Message())
+
+	msgs := make([]protoadapt.MessageV1, 0)
+	for _, detail := range err.Details() {
+		var msg proto.Message
+		switch v := detail.(type) {
+		case Detail:
+			msg = v.ToProto()
+		case proto.Message:
+			msg = v
+		case json.RawMessage:
+			msg = &errdetails.DebugInfo{Detail: string(v)}
+		default:
+			msg = &errdetails.DebugInfo{Detail: fmt.Sprintf("%v", v)}
+		}
+		msgs = append(msgs, protoadapt.MessageV1Of(msg))
+	}
+
+	if len(msgs) < 1 {
+		return s
+	}
+
+	if withDetails, e := s.WithDetails(msgs...); e == nil {
+		return withDetails
+	}
+
+	return s
+}
+
+// FromGrpcStatus converts gRPC status into ErrorInterface built by builder.
+//
+// google.rpc messages supported by Detail are converted back, google.rpc.DebugInfo is converted into string,
+// other messages are kept as JSON with @type member.
+func FromGrpcStatus(s *status.Status, builder ErrorBuilder) ErrorInterface {
+	if builder == nil {
+		builder = NewErrorBuilderGoogle()
+	}
+
+	if s == nil {
+		return builder.New(http.StatusInternalServerError, "")
+	}
+
+	details := make([]interface{}, 0)
+	for _, any := range s.Proto().GetDetails() {
+		msg, err := any.UnmarshalNew()
+		if err != nil {
+			continue
+		}
+
+		if detail := detailFromProto(msg); detail != nil {
+			details = append(details, detail)
+			continue
+		}
+
+		if v, ok := msg.(*errdetails.DebugInfo); ok {
+			details = append(details, v.GetDetail())
+			continue
+		}
+
+		if bytes, err := protojson.Marshal(any); err == nil {
+			details = append(details, json.RawMessage(bytes))
+		}
+	}
+
+	return builder.New(GrpcCodeToHttpCode(s.Code()), s.Message(), details...)
+}
+
+// GrpcCode returns gRPC code of ErrorInterface.
+func GrpcCode(err ErrorInterface) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	return HttpCodeToGrpcCode(err.Code())
+}
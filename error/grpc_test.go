@@ -0,0 +1,117 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHttpCodeToGrpcCode(t *testing.T) {
+	assert.Equal(t, codes.OK, HttpCodeToGrpcCode(http.StatusOK))
+	assert.Equal(t, codes.OK, HttpCodeToGrpcCode(http.StatusCreated))
+	assert.Equal(t, codes.InvalidArgument, HttpCodeToGrpcCode(http.StatusBadRequest))
+	assert.Equal(t, codes.NotFound, HttpCodeToGrpcCode(http.StatusNotFound))
+	assert.Equal(t, codes.FailedPrecondition, HttpCodeToGrpcCode(http.StatusTeapot))
+	assert.Equal(t, codes.Unavailable, HttpCodeToGrpcCode(http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Unknown, HttpCodeToGrpcCode(http.StatusBadGateway))
+}
+
+func TestGrpcCodeToHttpCode(t *testing.T) {
+	assert.Equal(t, http.StatusOK, GrpcCodeToHttpCode(codes.OK))
+	assert.Equal(t, http.StatusConflict, GrpcCodeToHttpCode(codes.AlreadyExists))
+	assert.Equal(t, http.StatusUnauthorized, GrpcCodeToHttpCode(codes.Unauthenticated))
+	assert.Equal(t, http.StatusInternalServerError, GrpcCodeToHttpCode(codes.Code(100)))
+
+	// round trip of codes in table, FailedPrecondition and OutOfRange are rendered as 400 over REST
+	for httpCode, grpcCode := range httpToGrpc {
+		if grpcCode == codes.FailedPrecondition || grpcCode == codes.OutOfRange {
+			continue
+		}
+		assert.Equal(t, httpCode, GrpcCodeToHttpCode(grpcCode))
+	}
+}
+
+func TestToGrpcStatus(t *testing.T) {
+	assert.Equal(t, codes.OK, ToGrpcStatus(nil).Code())
+
+	err := NewErrorBuilderGoogle().New(http.StatusBadRequest, "invalid request",
+		&BadRequest{FieldViolations: []*FieldViolation{{Field: "email", Description: "invalid"}}},
+		&RetryInfo{RetryDelay: 1500 * time.Millisecond},
+		&ErrorInfo{Reason: "INVALID_EMAIL", Domain: "example.com", Metadata: map[string]string{"k": "v"}},
+		&QuotaFailure{Violations: []*QuotaViolation{{Subject: "ip", Description: "exceeded"}}},
+		&errdetails.Help{},
+		errors.New("ut-error"))
+
+	s := ToGrpcStatus(err)
+	assert.Equal(t, codes.InvalidArgument, s.Code())
+	assert.Equal(t, "invalid request", s.Message())
+	assert.Len(t, s.Details(), 6)
+	assert.IsType(t, &errdetails.BadRequest{}, s.Details()[0])
+	assert.IsType(t, &errdetails.DebugInfo{}, s.Details()[5])
+
+	// status.FromError should work with GRPCStatus()
+	fromErr, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, fromErr.Code())
+
+	for _, builder := range []ErrorBuilder{NewErrorBuilderAMZN(), NewErrorBuilderProblem()} {
+		fromErr, ok = status.FromError(builder.New(http.StatusNotFound, ""))
+		assert.True(t, ok)
+		assert.Equal(t, codes.NotFound, fromErr.Code())
+	}
+	assert.Equal(t, codes.NotFound, GrpcCode(NewErrorBuilderGoogle().New(http.StatusNotFound, "")))
+	assert.Equal(t, codes.OK, GrpcCode(nil))
+}
+
+func TestFromGrpcStatus(t *testing.T) {
+	assert.Equal(t, http.StatusInternalServerError, FromGrpcStatus(nil, nil).Code())
+
+	origin := NewErrorBuilderGoogle().New(http.StatusTooManyRequests, "slow down",
+		&RetryInfo{RetryDelay: time.Second},
+		&QuotaFailure{Violations: []*QuotaViolation{{Subject: "ip", Description: "exceeded"}}},
+		&BadRequest{FieldViolations: []*FieldViolation{{Field: "f", Description: "d"}}},
+		&ErrorInfo{Reason: "QUOTA"},
+		&errdetails.Help{Links: []*errdetails.Help_Link{{Url: "https://example.com"}}},
+		"ut-detail")
+
+	err := FromGrpcStatus(ToGrpcStatus(origin), NewErrorBuilderProblem())
+	assert.IsType(t, &ErrorProblem{}, err)
+	assert.Equal(t, http.StatusTooManyRequests, err.Code())
+	assert.Equal(t, "slow down", err.Message())
+	assert.Len(t, err.Details(), 6)
+	assert.Equal(t, time.Second, err.Details()[0].(*RetryInfo).RetryDelay)
+	assert.Equal(t, "ip", err.Details()[1].(*QuotaFailure).Violations[0].Subject)
+	assert.Equal(t, "f", err.Details()[2].(*BadRequest).FieldViolations[0].Field)
+	assert.Equal(t, "QUOTA", err.Details()[3].(*ErrorInfo).Reason)
+	assert.Contains(t, string(err.Details()[4].(json.RawMessage)), "google.rpc.Help")
+	assert.Equal(t, "ut-detail", err.Details()[5])
+}
+
+func TestDetail_MarshalJSON(t *testing.T) {
+	bytes, err := json.Marshal(&RetryInfo{RetryDelay: 1500 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"1.5s"}`, string(bytes))
+
+	bytes, err = json.Marshal(&ErrorInfo{Reason: "R", Domain: "D"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"@type":"type.googleapis.com/google.rpc.ErrorInfo","reason":"R","domain":"D"}`, string(bytes))
+
+	bytes, err = json.Marshal(&BadRequest{FieldViolations: []*FieldViolation{{Field: "f", Description: "d"}}})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"f","description":"d"}]}`, string(bytes))
+
+	bytes, err = json.Marshal(&QuotaFailure{})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"@type":"type.googleapis.com/google.rpc.QuotaFailure","violations":null}`, string(bytes))
+}
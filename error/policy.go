@@ -0,0 +1,269 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"regexp"
+	"sync"
+)
+
+// RedactReplacement replaces secrets matched by redact patterns
+const RedactReplacement = "[REDACTED]"
+
+// DefaultRedactPatterns redacts values of common secret keys and bearer tokens.
+// The first group is kept and the rest of match is replaced with RedactReplacement.
+var DefaultRedactPatterns = []string{
+	`(?i)((?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)\s*[=:]\s*["']?)[^\s,;&"']+`,
+	`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`,
+}
+
+var (
+	policyLock     sync.RWMutex
+	sanitizeLock   sync.Mutex
+	redactPatterns = mustCompilePatterns(DefaultRedactPatterns)
+	internalLogger InternalLogger
+)
+
+// InternalLogger logs internal details which are hidden from client with correlation id.
+type InternalLogger func(correlationId string, code int, msg string, details []string)
+
+// InternalDetail marks detail as internal.
+//
+// Internal details are resolved by Sanitize when error is written to response.
+// In production mode, internal details are logged with InternalLogger and replaced by CorrelationInfo in response.
+// In development mode, internal details are serialized as normal details.
+type InternalDetail struct {
+	Value interface{}
+	// correlationId is assigned by Sanitize once internal detail is logged, guarded by sanitizeLock
+	correlationId string
+}
+
+// MarshalJSON marshals value in development mode, or RedactReplacement otherwise,
+// so that internal detail is not exposed if error is serialized without Sanitize.
+func (d *InternalDetail) MarshalJSON() ([]byte, error) {
+	if IsDevelopment() {
+		return json.Marshal(d.Value)
+	}
+
+	return json.Marshal(RedactReplacement)
+}
+
+// Internal marks detail as internal, see InternalDetail for details.
+func Internal(detail interface{}) *InternalDetail {
+	return &InternalDetail{Value: detail}
+}
+
+// CorrelationInfo replaces internal details in response, it could be used to find internal details in logs.
+type CorrelationInfo struct {
+	CorrelationId string `json:"correlationId" yaml:"correlationId" example:"2f1b6c1d0a8e4f7f9b3c5d7e9f1a3b5c"`
+}
+
+// ToProto converts to errdetails.RequestInfo with correlation id as request id
+func (d *CorrelationInfo) ToProto() proto.Message {
+	return &errdetails.RequestInfo{
+		RequestId: d.CorrelationId,
+	}
+}
+
+// SetRedactPatterns replaces redact patterns applied to message and details of every error.
+// The first group of pattern is kept if exists, rest of match is replaced with RedactReplacement.
+func SetRedactPatterns(patterns ...string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for i := range patterns {
+		regex, err := regexp.Compile(patterns[i])
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, regex)
+	}
+
+	policyLock.Lock()
+	defer policyLock.Unlock()
+
+	redactPatterns = compiled
+	return nil
+}
+
+// SetInternalLogger sets logger of internal details.
+func SetInternalLogger(logger InternalLogger) {
+	policyLock.Lock()
+	defer policyLock.Unlock()
+
+	internalLogger = logger
+}
+
+// Redact applies redact patterns to input.
+func Redact(in string) string {
+	policyLock.RLock()
+	defer policyLock.RUnlock()
+
+	for _, regex := range redactPatterns {
+		if regex.NumSubexp() > 0 {
+			in = regex.ReplaceAllString(in, "${1}"+RedactReplacement)
+		} else {
+			in = regex.ReplaceAllString(in, RedactReplacement)
+		}
+	}
+
+	return in
+}
+
+// redactDetails applies redaction to details while building error.
+//
+// 1: error is converted to string with redaction
+// 2: string is redacted
+// 3: internal details are redacted and kept as InternalDetail, they are resolved by Sanitize
+func redactDetails(details []interface{}) []interface{} {
+	res := make([]interface{}, 0, len(details))
+
+	for i := range details {
+		if v, ok := details[i].(*InternalDetail); ok {
+			if v != nil {
+				res = append(res, &InternalDetail{Value: sanitizeDetail(v.Value)})
+			}
+			continue
+		}
+
+		res = append(res, sanitizeDetail(details[i]))
+	}
+
+	return res
+}
+
+// Sanitize applies policy to err right before it is written to response.
+//
+// In production mode, internal details are logged with InternalLogger and replaced by CorrelationInfo.
+// In development mode, internal details are replaced by their values.
+//
+// Internal details are logged once, sanitizing the same err again, for example, GRPCStatus called
+// multiple times by gRPC, returns the same correlation id without logging.
+//
+// err is returned as it is if no internal details found or style is not builtin,
+// otherwise a copy of err is returned.
+func Sanitize(err ErrorInterface) ErrorInterface {
+	v, ok := err.(sanitizable)
+	if !ok {
+		return err
+	}
+
+	s := &detailsSanitizer{
+		development: IsDevelopment(),
+	}
+
+	sanitizeLock.Lock()
+	res := v.sanitize(s)
+	sanitizeLock.Unlock()
+
+	if len(s.internal) > 0 {
+		policyLock.RLock()
+		logger := internalLogger
+		policyLock.RUnlock()
+
+		if logger != nil {
+			logger(s.correlationId, err.Code(), err.Message(), s.internal)
+		}
+	}
+
+	return res
+}
+
+// sanitizable is implemented by builtin styles which could be sanitized by Sanitize
+type sanitizable interface {
+	sanitize(s *detailsSanitizer) ErrorInterface
+}
+
+// detailsSanitizer resolves internal details of one error, all internal details share the same correlation id.
+// Internal details logged by previous Sanitize are not logged again and their correlation id is reused.
+type detailsSanitizer struct {
+	development   bool
+	correlationId string
+	internal      []string
+}
+
+// apply returns copy of details with internal details resolved, details is returned as it is if nothing to resolve
+func (s *detailsSanitizer) apply(details []interface{}) []interface{} {
+	if !hasInternalDetail(details) {
+		return details
+	}
+
+	res := make([]interface{}, 0, len(details))
+	resolved := make([]*InternalDetail, 0)
+	for i := range details {
+		v, ok := details[i].(*InternalDetail)
+		if !ok {
+			res = append(res, details[i])
+			continue
+		}
+
+		switch {
+		case s.development:
+			res = append(res, v.Value)
+		case len(v.correlationId) > 0:
+			// logged already
+			s.correlationId = v.correlationId
+		default:
+			s.internal = append(s.internal, Redact(fmt.Sprintf("%v", v.Value)))
+			resolved = append(resolved, v)
+		}
+	}
+
+	if !s.development {
+		if len(s.correlationId) < 1 {
+			s.correlationId = newCorrelationId()
+		}
+		for _, v := range resolved {
+			v.correlationId = s.correlationId
+		}
+		res = append(res, &CorrelationInfo{CorrelationId: s.correlationId})
+	}
+
+	return res
+}
+
+// hasInternalDetail returns true if any of details is InternalDetail
+func hasInternalDetail(details []interface{}) bool {
+	for i := range details {
+		if _, ok := details[i].(*InternalDetail); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sanitizeDetail converts error into string and redacts strings
+func sanitizeDetail(detail interface{}) interface{} {
+	switch v := detail.(type) {
+	case error:
+		return Redact(v.Error())
+	case string:
+		return Redact(v)
+	}
+
+	return detail
+}
+
+// newCorrelationId returns random 128 bit hex string
+func newCorrelationId() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+func mustCompilePatterns(patterns []string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for i := range patterns {
+		res = append(res, regexp.MustCompile(patterns[i]))
+	}
+
+	return res
+}
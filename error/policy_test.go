@@ -0,0 +1,184 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "password=[REDACTED] user=bob", Redact("password=abc123 user=bob"))
+	assert.Equal(t, "api_key: [REDACTED]", Redact("api_key: xyz"))
+	assert.Equal(t, "Authorization: Bearer [REDACTED]", Redact("Authorization: Bearer abc.def-123"))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
+
+func TestSetRedactPatterns(t *testing.T) {
+	defer SetRedactPatterns(DefaultRedactPatterns...)
+
+	// invalid pattern
+	assert.NotNil(t, SetRedactPatterns("("))
+
+	// pattern without group
+	assert.Nil(t, SetRedactPatterns(`\d{3}-\d{2}-\d{4}`))
+	assert.Equal(t, "ssn [REDACTED]", Redact("ssn 123-45-6789"))
+	assert.Equal(t, "password=abc", Redact("password=abc"))
+}
+
+func TestNew_WithRedaction(t *testing.T) {
+	builders := []ErrorBuilder{NewErrorBuilderGoogle(), NewErrorBuilderAMZN(), NewErrorBuilderProblem()}
+
+	for _, builder := range builders {
+		err := builder.New(http.StatusBadRequest, "login failed with password=abc", errors.New("token=xyz"), "secret: s3")
+		assert.Equal(t, "login failed with password=[REDACTED]", err.Message())
+		assert.Equal(t, []interface{}{"token=[REDACTED]", "secret: [REDACTED]"}, err.Details())
+		assert.NotContains(t, err.Error(), "abc")
+	}
+}
+
+func TestNew_WithInternalDetail(t *testing.T) {
+	defer SetInternalLogger(nil)
+
+	logged := false
+	SetInternalLogger(func(correlationId string, code int, msg string, details []string) {
+		logged = true
+	})
+
+	cause := errors.New("select * from users where password='abc'")
+	builders := []ErrorBuilder{NewErrorBuilderGoogle(), NewErrorBuilderAMZN(), NewErrorBuilderProblem()}
+
+	for _, builder := range builders {
+		// nothing logged and nothing exposed while building
+		err := builder.New(http.StatusInternalServerError, "ut-msg", "public", Internal(cause))
+		assert.False(t, logged)
+		assert.Len(t, err.Details(), 2)
+		assert.IsType(t, &InternalDetail{}, err.Details()[1])
+		assert.NotContains(t, err.Error(), "select")
+		assert.Contains(t, err.Error(), RedactReplacement)
+
+		// internal error is still kept as cause
+		assert.True(t, errors.Is(err, cause))
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	defer SetInternalLogger(nil)
+
+	var loggedId, loggedMsg string
+	var loggedDetails []string
+	SetInternalLogger(func(correlationId string, code int, msg string, details []string) {
+		loggedId, loggedMsg, loggedDetails = correlationId, msg, details
+	})
+
+	cause := errors.New("select * from users where password='abc'")
+	builders := []ErrorBuilder{NewErrorBuilderGoogle(), NewErrorBuilderAMZN(), NewErrorBuilderProblem()}
+
+	for _, builder := range builders {
+		// production mode
+		origin := builder.New(http.StatusInternalServerError, "ut-msg", "public", Internal(cause), Internal("internal"))
+		err := Sanitize(origin)
+		assert.Len(t, err.Details(), 2)
+		assert.Equal(t, "public", err.Details()[0])
+		correlation, ok := err.Details()[1].(*CorrelationInfo)
+		assert.True(t, ok)
+		assert.Len(t, correlation.CorrelationId, 32)
+		assert.NotContains(t, err.Error(), "select")
+
+		assert.Equal(t, correlation.CorrelationId, loggedId)
+		assert.Equal(t, "ut-msg", loggedMsg)
+		assert.Equal(t, []string{"select * from users where password='[REDACTED]'", "internal"}, loggedDetails)
+
+		// origin is untouched and cause is kept
+		assert.Len(t, origin.Details(), 3)
+		assert.True(t, errors.Is(err, cause))
+
+		// development mode
+		SetDevelopment(true)
+		err = Sanitize(builder.New(http.StatusInternalServerError, "ut-msg", Internal(cause)))
+		assert.Equal(t, []interface{}{"select * from users where password='[REDACTED]'"}, err.Details())
+		SetDevelopment(false)
+
+		// nothing to sanitize
+		loggedId = ""
+		origin = builder.New(http.StatusBadRequest, "ut-msg", "public")
+		assert.Equal(t, origin, Sanitize(origin))
+		assert.Empty(t, loggedId)
+	}
+
+	// custom style
+	assert.Nil(t, Sanitize(nil))
+}
+
+func TestSanitize_WithMultiError(t *testing.T) {
+	err := NewErrorBuilderAMZN().New(http.StatusBadRequest, "first", Internal("first-internal")).(*ErrorAMZN)
+	err.Append(http.StatusBadRequest, "second", Internal("second-internal"))
+
+	res := Sanitize(err).(*ErrorAMZN)
+	assert.Equal(t, 2, res.Len())
+	first := res.Resp.Errors[0].Err.Details[0].(*CorrelationInfo)
+	second := res.Resp.Errors[1].Err.Details[0].(*CorrelationInfo)
+	assert.Equal(t, first.CorrelationId, second.CorrelationId)
+	assert.IsType(t, &InternalDetail{}, err.Resp.Errors[1].Err.Details[0])
+}
+
+func TestFromError_HidesUnknownError(t *testing.T) {
+	err := Sanitize(FromErrorWithBuilder(NewErrorBuilderGoogle(), errors.New("pq: relation users does not exist")))
+	assert.Equal(t, http.StatusInternalServerError, err.Code())
+	assert.Len(t, err.Details(), 1)
+	assert.IsType(t, &CorrelationInfo{}, err.Details()[0])
+	assert.NotContains(t, err.Error(), "pq:")
+}
+
+func TestToGrpcStatus_WithInternalDetail(t *testing.T) {
+	s := ToGrpcStatus(NewErrorBuilderGoogle().New(http.StatusInternalServerError, "ut-msg", Internal("ut-internal")))
+
+	res := FromGrpcStatus(s, NewErrorBuilderGoogle())
+	assert.Len(t, res.Details(), 1)
+	assert.IsType(t, &CorrelationInfo{}, res.Details()[0])
+}
+
+func TestSanitize_Repeatedly(t *testing.T) {
+	defer SetInternalLogger(nil)
+
+	logged := make([]string, 0)
+	SetInternalLogger(func(correlationId string, code int, msg string, details []string) {
+		logged = append(logged, correlationId)
+	})
+
+	for _, builder := range []ErrorBuilder{NewErrorBuilderGoogle(), NewErrorBuilderAMZN(), NewErrorBuilderProblem()} {
+		logged = logged[:0]
+		err := builder.New(http.StatusInternalServerError, "ut-msg", Internal("ut-internal"))
+
+		// GRPCStatus is called multiple times by status.FromError and gRPC server
+		ids := make([]string, 0)
+		for i := 0; i < 3; i++ {
+			s, ok := status.FromError(err)
+			assert.True(t, ok)
+			ids = append(ids, FromGrpcStatus(s, builder).Details()[0].(*CorrelationInfo).CorrelationId)
+		}
+		ids = append(ids, Sanitize(err).Details()[0].(*CorrelationInfo).CorrelationId)
+
+		// logged once with the same correlation id returned to client
+		assert.Len(t, logged, 1)
+		for _, id := range ids {
+			assert.Equal(t, logged[0], id)
+		}
+	}
+}
+
+func TestCorrelationInfo_Grpc(t *testing.T) {
+	err := NewErrorBuilderGoogle().New(http.StatusInternalServerError, "ut-msg", &CorrelationInfo{CorrelationId: "ut-id"})
+
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+
+	res := FromGrpcStatus(s, NewErrorBuilderGoogle())
+	assert.Equal(t, []interface{}{&CorrelationInfo{CorrelationId: "ut-id"}}, res.Details())
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+const (
+	// ProblemTypeDefault default value of type member which indicates no additional semantics beyond HTTP status code
+	ProblemTypeDefault = "about:blank"
+
+	// ProblemExtensionDetails extension member which holds details passed to ErrorBuilderProblem.New, omitted if empty
+	ProblemExtensionDetails = "details"
+)
+
+// problemMembers standard members which could not be used as extension members
+var problemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+func NewErrorBuilderProblem() ErrorBuilder {
+	return &ErrorBuilderProblem{}
+}
+
+// ErrorBuilderProblem builds RFC 7807 / RFC 9457 style error.
+// TypeBaseUrl would be used as type member of every error if not empty.
+type ErrorBuilderProblem struct {
+	TypeBaseUrl string
+}
+
+func (e *ErrorBuilderProblem) New(code int, msg string, details ...interface{}) ErrorInterface {
+	resp := &ErrorProblem{
+		Type:       e.TypeBaseUrl,
+		Status:     code,
+		Title:      http.StatusText(code),
+		Detail:     Redact(msg),
+		Extensions: map[string]interface{}{},
+		chain:      newErrorChain(1, details),
+	}
+
+	details = redactDetails(details)
+
+	if len(resp.Type) < 1 {
+		resp.Type = ProblemTypeDefault
+	}
+
+	if code < 1 {
+		resp.Status = http.StatusInternalServerError
+		resp.Title = http.StatusText(http.StatusInternalServerError)
+	}
+
+	list := make([]interface{}, 0)
+	for i := range details {
+		detail := details[i]
+		if v, ok := detail.(error); ok {
+			list = append(list, v.Error())
+		} else {
+			list = append(list, detail)
+		}
+	}
+
+	// omit details member if nothing to show
+	if len(list) > 0 {
+		resp.Extensions[ProblemExtensionDetails] = list
+	}
+
+	return resp
+}
+
+func (e *ErrorBuilderProblem) NewCustom() ErrorInterface {
+	return e.New(http.StatusInternalServerError, "")
+}
+
+// ErrorProblem is RFC 7807 / RFC 9457 style error
+// Referred: https://www.rfc-editor.org/rfc/rfc9457
+//
+// Extensions are serialized as top level members, keys which collide with standard members are ignored.
+type ErrorProblem struct {
+	Type       string                 `json:"type" yaml:"type" example:"about:blank"`
+	Title      string                 `json:"title" yaml:"title" example:"Internal Server Error"`
+	Status     int                    `json:"status" yaml:"status" example:"500"`
+	Detail     string                 `json:"detail,omitempty" yaml:"detail,omitempty" example:"Internal error occurs"`
+	Instance   string                 `json:"instance,omitempty" yaml:"instance,omitempty" example:"/v1/users/1"`
+	Extensions map[string]interface{} `json:"-" yaml:",inline"`
+	chain      *errorChain            `json:"-" yaml:"-"`
+}
+
+// WithType sets type member which is a URI reference identifies the problem type
+func (err *ErrorProblem) WithType(t string) *ErrorProblem {
+	err.Type = t
+	return err
+}
+
+// WithInstance sets instance member which is a URI reference identifies the specific occurrence
+func (err *ErrorProblem) WithInstance(instance string) *ErrorProblem {
+	err.Instance = instance
+	return err
+}
+
+// WithExtension sets extension member, standard members would be ignored
+func (err *ErrorProblem) WithExtension(key string, value interface{}) *ErrorProblem {
+	if problemMembers[key] {
+		return err
+	}
+
+	if err.Extensions == nil {
+		err.Extensions = map[string]interface{}{}
+	}
+	err.Extensions[key] = value
+	return err
+}
+
+func (err *ErrorProblem) Code() int {
+	return err.Status
+}
+
+func (err *ErrorProblem) Message() string {
+	return err.Detail
+}
+
+func (err *ErrorProblem) Details() []interface{} {
+	if v, ok := err.Extensions[ProblemExtensionDetails].([]interface{}); ok {
+		return v
+	}
+
+	return make([]interface{}, 0)
+}
+
+// ContentType returns application/problem+json
+func (err *ErrorProblem) ContentType() string {
+	return ContentTypeProblemJSON
+}
+
+// Unwrap returns first error passed as detail.
+func (err *ErrorProblem) Unwrap() error {
+	return err.chain.unwrap()
+}
+
+// Is reports whether any error passed as detail matches target, or target is ErrorDefinition with same reason.
+func (err *ErrorProblem) Is(target error) bool {
+	return err.chain.is(err, target)
+}
+
+// As finds first error passed as detail that matches target.
+func (err *ErrorProblem) As(target interface{}) bool {
+	return err.chain.as(target)
+}
+
+// StackTrace returns stack trace captured in development mode.
+func (err *ErrorProblem) StackTrace() []string {
+	return err.chain.stackTrace()
+}
+
+// sanitize implements sanitizable
+func (err *ErrorProblem) sanitize(s *detailsSanitizer) ErrorInterface {
+	if !hasInternalDetail(err.Details()) {
+		return err
+	}
+
+	res := *err
+	res.Extensions = make(map[string]interface{}, len(err.Extensions))
+	for k, v := range err.Extensions {
+		res.Extensions[k] = v
+	}
+	res.Extensions[ProblemExtensionDetails] = s.apply(err.Details())
+	return &res
+}
+
+// GRPCStatus converts error into gRPC status, status.FromError would use it automatically
+func (err *ErrorProblem) GRPCStatus() *status.Status {
+	return ToGrpcStatus(err)
+}
+
+// Error returns string of error
+func (err *ErrorProblem) Error() string {
+	res := "{}"
+
+	if bytes, marshalErr := json.Marshal(err); marshalErr == nil {
+		res = string(bytes)
+	}
+
+	return res
+}
+
+// MarshalJSON marshal standard members and extension members into one object
+func (err *ErrorProblem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(err.Extensions)+5)
+	for k, v := range err.Extensions {
+		if !problemMembers[k] {
+			m[k] = v
+		}
+	}
+
+	m["type"] = err.Type
+	m["title"] = err.Title
+	m["status"] = err.Status
+	if len(err.Detail) > 0 {
+		m["detail"] = err.Detail
+	}
+	if len(err.Instance) > 0 {
+		m["instance"] = err.Instance
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON unmarshal standard members and keep others as extension members
+func (err *ErrorProblem) UnmarshalJSON(bytes []byte) error {
+	m := map[string]json.RawMessage{}
+	if e := json.Unmarshal(bytes, &m); e != nil {
+		return e
+	}
+
+	type standard struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+	}
+
+	s := &standard{}
+	if e := json.Unmarshal(bytes, s); e != nil {
+		return e
+	}
+
+	err.Type = s.Type
+	err.Title = s.Title
+	err.Status = s.Status
+	err.Detail = s.Detail
+	err.Instance = s.Instance
+	err.Extensions = map[string]interface{}{}
+
+	for k, v := range m {
+		if problemMembers[k] {
+			continue
+		}
+
+		var value interface{}
+		if e := json.Unmarshal(v, &value); e != nil {
+			return e
+		}
+		err.Extensions[k] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestErrorBuilderProblem_New(t *testing.T) {
+	builder := NewErrorBuilderProblem()
+
+	err := builder.New(http.StatusNotFound, "user not found", errors.New("ut-error"), "ut-detail")
+	assert.Equal(t, http.StatusNotFound, err.Code())
+	assert.Equal(t, "user not found", err.Message())
+	assert.Equal(t, []interface{}{"ut-error", "ut-detail"}, err.Details())
+	assert.Equal(t, ContentTypeProblemJSON, ContentType(err))
+
+	problem := err.(*ErrorProblem)
+	assert.Equal(t, ProblemTypeDefault, problem.Type)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), problem.Title)
+
+	// with invalid code
+	err = builder.New(0, "")
+	assert.Equal(t, http.StatusInternalServerError, err.Code())
+	assert.Empty(t, err.Details())
+	assert.NotContains(t, err.(*ErrorProblem).Extensions, ProblemExtensionDetails)
+	assert.NotContains(t, err.Error(), `"details"`)
+
+	// custom
+	assert.Equal(t, http.StatusInternalServerError, builder.NewCustom().Code())
+
+	// with type base url
+	builder = &ErrorBuilderProblem{TypeBaseUrl: "https://example.com/probs/out-of-credit"}
+	assert.Equal(t, "https://example.com/probs/out-of-credit", builder.New(http.StatusForbidden, "").(*ErrorProblem).Type)
+}
+
+func TestErrorProblem_MarshalJSON(t *testing.T) {
+	err := NewErrorBuilderProblem().New(http.StatusForbidden, "Your current balance is 30, but that costs 50.").(*ErrorProblem)
+	err.WithType("https://example.com/probs/out-of-credit").
+		WithInstance("/account/12345/msgs/abc").
+		WithExtension("balance", 30).
+		WithExtension("status", "ignored")
+
+	m := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(err.Error()), &m))
+	assert.Equal(t, "https://example.com/probs/out-of-credit", m["type"])
+	assert.Equal(t, "Forbidden", m["title"])
+	assert.Equal(t, float64(http.StatusForbidden), m["status"])
+	assert.Equal(t, "Your current balance is 30, but that costs 50.", m["detail"])
+	assert.Equal(t, "/account/12345/msgs/abc", m["instance"])
+	assert.Equal(t, float64(30), m["balance"])
+	assert.NotContains(t, m, "details")
+
+	// unmarshal back
+	parsed := &ErrorProblem{}
+	assert.Nil(t, json.Unmarshal([]byte(err.Error()), parsed))
+	assert.Equal(t, err.Type, parsed.Type)
+	assert.Equal(t, err.Status, parsed.Status)
+	assert.Equal(t, err.Instance, parsed.Instance)
+	assert.Equal(t, float64(30), parsed.Extensions["balance"])
+	assert.NotContains(t, parsed.Extensions, "status")
+
+	// invalid json
+	assert.NotNil(t, parsed.UnmarshalJSON([]byte("invalid")))
+}
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, ContentTypeJSON, ContentType(NewErrorBuilderGoogle().New(http.StatusNotFound, "")))
+	assert.Equal(t, ContentTypeJSON, ContentType(NewErrorBuilderAMZN().New(http.StatusNotFound, "")))
+	assert.Equal(t, ContentTypeProblemJSON, ContentType(NewErrorBuilderProblem().New(http.StatusNotFound, "")))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// StyleGoogle style of ErrorBuilderGoogle
+	StyleGoogle = "google"
+	// StyleAmazon style of ErrorBuilderAMZN
+	StyleAmazon = "amazon"
+	// StyleProblem style of ErrorBuilderProblem
+	StyleProblem = "problem"
+	// StyleCustom conventional name of user defined style
+	StyleCustom = "custom"
+)
+
+var (
+	styleLock      sync.RWMutex
+	defaultBuilder ErrorBuilder = NewErrorBuilderGoogle()
+	styles                      = map[string]ErrorBuilder{
+		StyleGoogle:  NewErrorBuilderGoogle(),
+		StyleAmazon:  NewErrorBuilderAMZN(),
+		StyleProblem: NewErrorBuilderProblem(),
+	}
+)
+
+// RegisterStyle registers ErrorBuilder with style name, so that it could be selected by name from boot config.
+// Name is case-insensitive, registered style would be overridden.
+func RegisterStyle(name string, builder ErrorBuilder) {
+	if len(name) < 1 || builder == nil {
+		return
+	}
+
+	styleLock.Lock()
+	defer styleLock.Unlock()
+
+	styles[strings.ToLower(name)] = builder
+}
+
+// GetStyle returns ErrorBuilder registered with style name.
+func GetStyle(name string) (ErrorBuilder, error) {
+	styleLock.RLock()
+	defer styleLock.RUnlock()
+
+	if v, ok := styles[strings.ToLower(name)]; ok {
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("error style %s is not registered", name)
+}
+
+// SetDefaultBuilder sets ErrorBuilder used by FromError and returned by DefaultBuilder.
+func SetDefaultBuilder(builder ErrorBuilder) {
+	if builder == nil {
+		return
+	}
+
+	styleLock.Lock()
+	defer styleLock.Unlock()
+
+	defaultBuilder = builder
+}
+
+// DefaultBuilder returns default ErrorBuilder, ErrorBuilderGoogle if not set.
+func DefaultBuilder() ErrorBuilder {
+	styleLock.RLock()
+	defer styleLock.RUnlock()
+
+	return defaultBuilder
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkerror
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetStyle(t *testing.T) {
+	builder, err := GetStyle(StyleGoogle)
+	assert.Nil(t, err)
+	assert.IsType(t, &ErrorBuilderGoogle{}, builder)
+
+	builder, err = GetStyle("Amazon")
+	assert.Nil(t, err)
+	assert.IsType(t, &ErrorBuilderAMZN{}, builder)
+
+	builder, err = GetStyle(StyleProblem)
+	assert.Nil(t, err)
+	assert.IsType(t, &ErrorBuilderProblem{}, builder)
+
+	_, err = GetStyle("non-exist")
+	assert.NotNil(t, err)
+}
+
+func TestRegisterStyle(t *testing.T) {
+	defer func() {
+		styleLock.Lock()
+		delete(styles, StyleCustom)
+		styleLock.Unlock()
+	}()
+
+	// invalid input
+	RegisterStyle("", NewErrorBuilderGoogle())
+	RegisterStyle(StyleCustom, nil)
+	_, err := GetStyle(StyleCustom)
+	assert.NotNil(t, err)
+
+	RegisterStyle(StyleCustom, NewErrorBuilderProblem())
+	builder, err := GetStyle(StyleCustom)
+	assert.Nil(t, err)
+	assert.IsType(t, &ErrorBuilderProblem{}, builder)
+}
+
+func TestDefaultBuilder(t *testing.T) {
+	defer SetDefaultBuilder(NewErrorBuilderGoogle())
+
+	assert.IsType(t, &ErrorBuilderGoogle{}, DefaultBuilder())
+
+	SetDefaultBuilder(nil)
+	assert.IsType(t, &ErrorBuilderGoogle{}, DefaultBuilder())
+
+	SetDefaultBuilder(NewErrorBuilderAMZN())
+	assert.IsType(t, &ErrorBuilderAMZN{}, DefaultBuilder())
+	assert.IsType(t, &ErrorAMZN{}, FromError(assert.AnError))
+}
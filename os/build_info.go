@@ -0,0 +1,184 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Variables which could be injected while building binary with ldflags and override values read from binary.
+//
+// Example:
+// go build -ldflags "-X github.com/rookie-ninja/rk-entry/v2/os.BuildVersion=v1.0.0 -X github.com/rookie-ninja/rk-entry/v2/os.BuildCommit=$(git rev-parse HEAD)"
+var (
+	BuildVersion string
+	BuildCommit  string
+	BuildTime    string
+)
+
+// main module version reported by go build without version information
+const develVersion = "(devel)"
+
+// build settings kept in BuildInfo.Settings besides vcs.* settings, others like -ldflags may contain secrets
+var buildSettingsAllowed = map[string]bool{
+	"GOOS":        true,
+	"GOARCH":      true,
+	"CGO_ENABLED": true,
+	"-tags":       true,
+}
+
+var (
+	buildInfoOnce   sync.Once
+	buildInfoCached *BuildInfo
+)
+
+// BuildInfo defines build information read from binary with debug.ReadBuildInfo and ldflags variables.
+//
+// Version, Commit and Time read ldflags variables first, then main module and VCS information of binary.
+// Settings only contains vcs.*, GOOS, GOARCH, CGO_ENABLED and -tags.
+type BuildInfo struct {
+	Version     string            `json:"version" yaml:"version" example:"v1.0.0"`
+	Commit      string            `json:"commit" yaml:"commit" example:"6f1ad6c5a0f2c9e3e3b1a0c2d4e5f60718293a4b"`
+	Time        string            `json:"time" yaml:"time" example:"2022-03-15T12:43:05Z"`
+	GoVersion   string            `json:"goVersion" yaml:"goVersion" example:"go1.18"`
+	Path        string            `json:"path" yaml:"path" example:"github.com/rookie-ninja/rk-demo"`
+	MainPath    string            `json:"mainPath" yaml:"mainPath" example:"github.com/rookie-ninja/rk-demo"`
+	MainVersion string            `json:"mainVersion" yaml:"mainVersion" example:"v1.0.0"`
+	VcsType     string            `json:"vcsType" yaml:"vcsType" example:"git"`
+	VcsRevision string            `json:"vcsRevision" yaml:"vcsRevision" example:"6f1ad6c5a0f2c9e3e3b1a0c2d4e5f60718293a4b"`
+	VcsTime     string            `json:"vcsTime" yaml:"vcsTime" example:"2022-03-15T12:43:05Z"`
+	VcsModified bool              `json:"vcsModified" yaml:"vcsModified" example:"false"`
+	Settings    map[string]string `json:"settings" yaml:"settings"`
+	Deps        []*BuildDep       `json:"deps" yaml:"deps"`
+}
+
+// BuildDep defines a dependency module of binary
+type BuildDep struct {
+	Path    string `json:"path" yaml:"path" example:"go.uber.org/zap"`
+	Version string `json:"version" yaml:"version" example:"v1.21.0"`
+	Sum     string `json:"sum,omitempty" yaml:"sum,omitempty" example:"h1:..."`
+	Replace string `json:"replace,omitempty" yaml:"replace,omitempty" example:"../zap"`
+}
+
+// NewBuildInfo creates a new BuildInfo.
+// Binary is read only once, ldflags variables are applied every time.
+func NewBuildInfo() *BuildInfo {
+	buildInfoOnce.Do(func() {
+		info, _ := debug.ReadBuildInfo()
+		buildInfoCached = parseBuildInfo(info)
+	})
+
+	res := buildInfoCached.copy()
+	res.applyLdflags()
+	return res
+}
+
+// newBuildInfo creates BuildInfo from debug.BuildInfo with ldflags variables applied
+func newBuildInfo(info *debug.BuildInfo) *BuildInfo {
+	res := parseBuildInfo(info)
+	res.applyLdflags()
+	return res
+}
+
+// parseBuildInfo creates BuildInfo from debug.BuildInfo, info could be nil if binary was built without module support
+func parseBuildInfo(info *debug.BuildInfo) *BuildInfo {
+	res := &BuildInfo{
+		Settings: make(map[string]string),
+		Deps:     make([]*BuildDep, 0),
+	}
+
+	if info == nil {
+		return res
+	}
+
+	res.GoVersion = info.GoVersion
+	res.Path = info.Path
+	res.MainPath = info.Main.Path
+	res.MainVersion = info.Main.Version
+
+	for _, setting := range info.Settings {
+		if isBuildSettingAllowed(setting.Key) {
+			res.Settings[setting.Key] = setting.Value
+		}
+
+		switch setting.Key {
+		case "vcs":
+			res.VcsType = setting.Value
+		case "vcs.revision":
+			res.VcsRevision = setting.Value
+		case "vcs.time":
+			res.VcsTime = setting.Value
+		case "vcs.modified":
+			res.VcsModified = setting.Value == "true"
+		}
+	}
+
+	for _, dep := range info.Deps {
+		if dep == nil {
+			continue
+		}
+
+		element := &BuildDep{
+			Path:    dep.Path,
+			Version: dep.Version,
+			Sum:     dep.Sum,
+		}
+
+		if dep.Replace != nil {
+			element.Replace = dep.Replace.Path
+			if len(dep.Replace.Version) > 0 {
+				element.Replace += "@" + dep.Replace.Version
+			}
+		}
+
+		res.Deps = append(res.Deps, element)
+	}
+
+	return res
+}
+
+// isBuildSettingAllowed returns true if build setting could be exposed
+func isBuildSettingAllowed(key string) bool {
+	return key == "vcs" || strings.HasPrefix(key, "vcs.") || buildSettingsAllowed[key]
+}
+
+// applyLdflags fills Version, Commit and Time with ldflags variables first
+func (info *BuildInfo) applyLdflags() {
+	info.Version = BuildVersion
+	if len(info.Version) < 1 && info.MainVersion != develVersion {
+		info.Version = info.MainVersion
+	}
+
+	info.Commit = BuildCommit
+	if len(info.Commit) < 1 {
+		info.Commit = info.VcsRevision
+	}
+
+	info.Time = BuildTime
+	if len(info.Time) < 1 {
+		info.Time = info.VcsTime
+	}
+}
+
+// copy returns deep copy of BuildInfo, so that cached one would not be modified by caller
+func (info *BuildInfo) copy() *BuildInfo {
+	res := *info
+
+	res.Settings = make(map[string]string, len(info.Settings))
+	for k, v := range info.Settings {
+		res.Settings[k] = v
+	}
+
+	res.Deps = make([]*BuildDep, 0, len(info.Deps))
+	for i := range info.Deps {
+		dep := *info.Deps[i]
+		res.Deps = append(res.Deps, &dep)
+	}
+
+	return &res
+}
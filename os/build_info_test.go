@@ -0,0 +1,106 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"github.com/stretchr/testify/assert"
+	"runtime/debug"
+	"testing"
+)
+
+func TestNewBuildInfo_HappyCase(t *testing.T) {
+	info := NewBuildInfo()
+	assert.NotNil(t, info)
+	assert.NotNil(t, info.Settings)
+	assert.NotNil(t, info.Deps)
+}
+
+func TestNewBuildInfo_Cached(t *testing.T) {
+	defer func() {
+		BuildVersion = ""
+	}()
+
+	// modification of caller is not visible to others
+	info := NewBuildInfo()
+	info.Settings["ut"] = "ut"
+	info.Deps = append(info.Deps, &BuildDep{Path: "ut"})
+	assert.NotContains(t, NewBuildInfo().Settings, "ut")
+	assert.Equal(t, len(info.Deps)-1, len(NewBuildInfo().Deps))
+
+	// ldflags variables are still applied
+	BuildVersion = "v2.0.0"
+	assert.Equal(t, "v2.0.0", NewBuildInfo().Version)
+}
+
+func TestNewBuildInfo_WithNil(t *testing.T) {
+	info := newBuildInfo(nil)
+	assert.Empty(t, info.Version)
+	assert.Empty(t, info.Deps)
+}
+
+func TestNewBuildInfo_FromBinary(t *testing.T) {
+	info := newBuildInfo(&debug.BuildInfo{
+		GoVersion: "go1.18",
+		Path:      "github.com/rookie-ninja/ut",
+		Main:      debug.Module{Path: "github.com/rookie-ninja/ut", Version: "v1.2.3"},
+		Deps: []*debug.Module{
+			{Path: "go.uber.org/zap", Version: "v1.21.0", Sum: "h1:ut"},
+			{Path: "github.com/ut/ut", Version: "v0.1.0", Replace: &debug.Module{Path: "../ut"}},
+			nil,
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "abc"},
+			{Key: "vcs.time", Value: "2022-03-15T12:43:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+			{Key: "CGO_ENABLED", Value: "0"},
+			{Key: "GOOS", Value: "linux"},
+			{Key: "-tags", Value: "ut"},
+			{Key: "-ldflags", Value: "-X main.secret=ut"},
+			{Key: "-gcflags", Value: "all=-N"},
+		},
+	})
+
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, "abc", info.Commit)
+	assert.Equal(t, "2022-03-15T12:43:05Z", info.Time)
+	assert.Equal(t, "go1.18", info.GoVersion)
+	assert.Equal(t, "git", info.VcsType)
+	assert.True(t, info.VcsModified)
+	assert.Equal(t, map[string]string{
+		"vcs":          "git",
+		"vcs.revision": "abc",
+		"vcs.time":     "2022-03-15T12:43:05Z",
+		"vcs.modified": "true",
+		"CGO_ENABLED":  "0",
+		"GOOS":         "linux",
+		"-tags":        "ut",
+	}, info.Settings)
+	assert.Len(t, info.Deps, 2)
+	assert.Equal(t, "h1:ut", info.Deps[0].Sum)
+	assert.Equal(t, "../ut", info.Deps[1].Replace)
+}
+
+func TestNewBuildInfo_WithLdflags(t *testing.T) {
+	defer func() {
+		BuildVersion, BuildCommit, BuildTime = "", "", ""
+	}()
+
+	// devel version is ignored
+	info := newBuildInfo(&debug.BuildInfo{
+		Main: debug.Module{Version: develVersion},
+	})
+	assert.Empty(t, info.Version)
+
+	BuildVersion, BuildCommit, BuildTime = "v2.0.0", "def", "now"
+	info = newBuildInfo(&debug.BuildInfo{
+		Main:     debug.Module{Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc"}},
+	})
+	assert.Equal(t, "v2.0.0", info.Version)
+	assert.Equal(t, "def", info.Commit)
+	assert.Equal(t, "now", info.Time)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CgroupVersionNone means cgroup file system was not found
+	CgroupVersionNone = 0
+	// CgroupVersionV1 means cgroup v1 hierarchy
+	CgroupVersionV1 = 1
+	// CgroupVersionV2 means cgroup v2 unified hierarchy
+	CgroupVersionV2 = 2
+
+	// cgroup v1 reports a page aligned max int64 as memory limit if not limited
+	cgroupV1MemUnlimited = uint64(1 << 62)
+)
+
+// CgroupRoot is mount point of cgroup file system which would be read by NewCgroupInfo.
+// Override it to read cgroup files from another location, like fixture files in tests.
+var CgroupRoot = "/sys/fs/cgroup"
+
+// CgroupInfo defines CPU and memory limits of container read from cgroup v1 or v2.
+//
+// Zero value of CpuQuota and MemLimitByte means not limited.
+type CgroupInfo struct {
+	Version           int     `json:"version" yaml:"version" example:"2"`
+	CpuQuota          float64 `json:"cpuQuota" yaml:"cpuQuota" example:"1.5"`
+	CpuQuotaUs        int64   `json:"cpuQuotaUs" yaml:"cpuQuotaUs" example:"150000"`
+	CpuPeriodUs       int64   `json:"cpuPeriodUs" yaml:"cpuPeriodUs" example:"100000"`
+	MemLimitByte      uint64  `json:"memLimitByte" yaml:"memLimitByte" example:"536870912"`
+	MemUsageByte      uint64  `json:"memUsageByte" yaml:"memUsageByte" example:"134217728"`
+	MemUsedPercentage float64 `json:"memUsedPercentage" yaml:"memUsedPercentage" example:"0.25"`
+}
+
+// NewCgroupInfo creates a new CgroupInfo from CgroupRoot
+func NewCgroupInfo() *CgroupInfo {
+	return NewCgroupInfoWithRoot(CgroupRoot)
+}
+
+// NewCgroupInfoWithRoot creates a new CgroupInfo from cgroup files under root.
+//
+// Files of cgroup namespace root are read, which are the files of container itself in most container runtimes.
+func NewCgroupInfoWithRoot(root string) *CgroupInfo {
+	res := &CgroupInfo{}
+
+	if fileExists(filepath.Join(root, "cgroup.controllers")) {
+		res.Version = CgroupVersionV2
+		res.readV2(root)
+	} else if len(cgroupV1Dir(root, "cpu", "cpu,cpuacct", "cpuacct,cpu", "memory")) > 0 {
+		res.Version = CgroupVersionV1
+		res.readV1(root)
+	}
+
+	if res.CpuQuotaUs > 0 && res.CpuPeriodUs > 0 {
+		res.CpuQuota = math.Round(float64(res.CpuQuotaUs)/float64(res.CpuPeriodUs)*100) / 100
+	}
+
+	if res.MemLimitByte > 0 {
+		res.MemUsedPercentage = math.Round(float64(res.MemUsageByte)/float64(res.MemLimitByte)*100) / 100
+	}
+
+	return res
+}
+
+// readV2 reads cpu.max, memory.max and memory.current
+func (info *CgroupInfo) readV2(root string) {
+	// format of cpu.max is "$MAX $PERIOD", $MAX would be max if not limited
+	if fields := strings.Fields(readTrimmedFile(filepath.Join(root, "cpu.max"))); len(fields) == 2 {
+		if fields[0] != "max" {
+			info.CpuQuotaUs, _ = strconv.ParseInt(fields[0], 10, 64)
+		}
+		info.CpuPeriodUs, _ = strconv.ParseInt(fields[1], 10, 64)
+	}
+
+	if limit := readTrimmedFile(filepath.Join(root, "memory.max")); limit != "max" {
+		info.MemLimitByte, _ = strconv.ParseUint(limit, 10, 64)
+	}
+
+	info.MemUsageByte, _ = strconv.ParseUint(readTrimmedFile(filepath.Join(root, "memory.current")), 10, 64)
+}
+
+// readV1 reads cpu.cfs_quota_us, cpu.cfs_period_us, memory.limit_in_bytes and memory.usage_in_bytes
+func (info *CgroupInfo) readV1(root string) {
+	if dir := cgroupV1Dir(root, "cpu", "cpu,cpuacct", "cpuacct,cpu"); len(dir) > 0 {
+		// cpu.cfs_quota_us would be -1 if not limited
+		if quota, err := strconv.ParseInt(readTrimmedFile(filepath.Join(dir, "cpu.cfs_quota_us")), 10, 64); err == nil && quota > 0 {
+			info.CpuQuotaUs = quota
+		}
+		info.CpuPeriodUs, _ = strconv.ParseInt(readTrimmedFile(filepath.Join(dir, "cpu.cfs_period_us")), 10, 64)
+	}
+
+	if dir := cgroupV1Dir(root, "memory"); len(dir) > 0 {
+		if limit, err := strconv.ParseUint(readTrimmedFile(filepath.Join(dir, "memory.limit_in_bytes")), 10, 64); err == nil && limit < cgroupV1MemUnlimited {
+			info.MemLimitByte = limit
+		}
+		info.MemUsageByte, _ = strconv.ParseUint(readTrimmedFile(filepath.Join(dir, "memory.usage_in_bytes")), 10, 64)
+	}
+}
+
+// cgroupV1Dir returns first existing controller directory
+func cgroupV1Dir(root string, controllers ...string) string {
+	for _, controller := range controllers {
+		dir := filepath.Join(root, controller)
+		if fileExists(dir) {
+			return dir
+		}
+	}
+
+	return ""
+}
+
+// readTrimmedFile returns trimmed content of file, empty string returned if failed
+func readTrimmedFile(path string) string {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(bytes))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
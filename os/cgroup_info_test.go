@@ -0,0 +1,66 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewCgroupInfoWithRoot_V2(t *testing.T) {
+	info := NewCgroupInfoWithRoot("testdata/cgroup/v2")
+	assert.Equal(t, CgroupVersionV2, info.Version)
+	assert.Equal(t, int64(150000), info.CpuQuotaUs)
+	assert.Equal(t, int64(100000), info.CpuPeriodUs)
+	assert.Equal(t, 1.5, info.CpuQuota)
+	assert.Equal(t, uint64(536870912), info.MemLimitByte)
+	assert.Equal(t, uint64(134217728), info.MemUsageByte)
+	assert.Equal(t, 0.25, info.MemUsedPercentage)
+}
+
+func TestNewCgroupInfoWithRoot_V2Unlimited(t *testing.T) {
+	info := NewCgroupInfoWithRoot("testdata/cgroup/v2-unlimited")
+	assert.Equal(t, CgroupVersionV2, info.Version)
+	assert.Zero(t, info.CpuQuotaUs)
+	assert.Equal(t, int64(100000), info.CpuPeriodUs)
+	assert.Zero(t, info.CpuQuota)
+	assert.Zero(t, info.MemLimitByte)
+	assert.Equal(t, uint64(134217728), info.MemUsageByte)
+	assert.Zero(t, info.MemUsedPercentage)
+}
+
+func TestNewCgroupInfoWithRoot_V1(t *testing.T) {
+	info := NewCgroupInfoWithRoot("testdata/cgroup/v1")
+	assert.Equal(t, CgroupVersionV1, info.Version)
+	assert.Equal(t, 2.0, info.CpuQuota)
+	assert.Equal(t, uint64(1073741824), info.MemLimitByte)
+	assert.Equal(t, uint64(268435456), info.MemUsageByte)
+	assert.Equal(t, 0.25, info.MemUsedPercentage)
+}
+
+func TestNewCgroupInfoWithRoot_V1Unlimited(t *testing.T) {
+	info := NewCgroupInfoWithRoot("testdata/cgroup/v1-unlimited")
+	assert.Equal(t, CgroupVersionV1, info.Version)
+	assert.Zero(t, info.CpuQuotaUs)
+	assert.Equal(t, int64(100000), info.CpuPeriodUs)
+	assert.Zero(t, info.CpuQuota)
+	assert.Zero(t, info.MemLimitByte)
+	assert.Equal(t, uint64(268435456), info.MemUsageByte)
+}
+
+func TestNewCgroupInfoWithRoot_NotFound(t *testing.T) {
+	info := NewCgroupInfoWithRoot("testdata/cgroup/non-exist")
+	assert.Equal(t, CgroupVersionNone, info.Version)
+	assert.Zero(t, info.CpuQuota)
+	assert.Zero(t, info.MemLimitByte)
+}
+
+func TestNewCgroupInfo(t *testing.T) {
+	defer func(root string) { CgroupRoot = root }(CgroupRoot)
+
+	CgroupRoot = "testdata/cgroup/v2"
+	assert.Equal(t, 1.5, NewCgroupInfo().CpuQuota)
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// CloudAWS queries EC2 instance metadata service with IMDSv2
+	CloudAWS = "aws"
+	// CloudGCP queries GCE metadata server
+	CloudGCP = "gcp"
+	// CloudAzure queries Azure instance metadata service
+	CloudAzure = "azure"
+)
+
+// default metadata endpoints of cloud providers
+var defaultCloudMetadataEndpoints = map[string]string{
+	CloudAWS:   "http://169.254.169.254",
+	CloudGCP:   "http://metadata.google.internal",
+	CloudAzure: "http://169.254.169.254",
+}
+
+// NewCloudMetadataDetector creates a CloudMetadataDetector of provider with default endpoint
+func NewCloudMetadataDetector(provider string) *CloudMetadataDetector {
+	return &CloudMetadataDetector{
+		Provider: provider,
+		Endpoint: defaultCloudMetadataEndpoints[provider],
+		Client: &http.Client{
+			Timeout: 2 * time.Second,
+		},
+	}
+}
+
+// CloudMetadataDetector queries metadata endpoint of cloud provider for region, zone and instance.
+//
+// Nothing would be filled if endpoint is not reachable, which means process is not running in the cloud.
+type CloudMetadataDetector struct {
+	Provider string
+	Endpoint string
+	Client   *http.Client
+}
+
+// Detect queries metadata endpoint
+func (d *CloudMetadataDetector) Detect(ctx context.Context, info *EnvInfo) {
+	if d.Client == nil {
+		d.Client = http.DefaultClient
+	}
+
+	var region, zone, id, instanceType string
+	var err error
+
+	switch d.Provider {
+	case CloudAWS:
+		region, zone, id, instanceType, err = d.detectAWS(ctx)
+	case CloudGCP:
+		region, zone, id, instanceType, err = d.detectGCP(ctx)
+	case CloudAzure:
+		region, zone, id, instanceType, err = d.detectAzure(ctx)
+	default:
+		err = fmt.Errorf("cloud provider %s is not supported", d.Provider)
+	}
+
+	if err != nil {
+		return
+	}
+
+	setIfEmpty(&info.Cloud, d.Provider)
+	setIfEmpty(&info.Region, region)
+	setIfEmpty(&info.AZ, zone)
+	setIfEmpty(&info.InstanceId, id)
+	setIfEmpty(&info.InstanceType, instanceType)
+}
+
+// detectAWS fetches IMDSv2 token then reads placement and instance
+func (d *CloudMetadataDetector) detectAWS(ctx context.Context) (region, zone, id, instanceType string, err error) {
+	token, err := d.get(ctx, http.MethodPut, "/latest/api/token", map[string]string{
+		"X-aws-ec2-metadata-token-ttl-seconds": "60",
+	})
+	if err != nil {
+		return
+	}
+
+	header := map[string]string{"X-aws-ec2-metadata-token": token}
+	if zone, err = d.get(ctx, http.MethodGet, "/latest/meta-data/placement/availability-zone", header); err != nil {
+		return
+	}
+
+	region, _ = d.get(ctx, http.MethodGet, "/latest/meta-data/placement/region", header)
+	id, _ = d.get(ctx, http.MethodGet, "/latest/meta-data/instance-id", header)
+	instanceType, _ = d.get(ctx, http.MethodGet, "/latest/meta-data/instance-type", header)
+
+	return
+}
+
+// detectGCP reads zone and machine type, both are returned as projects/$PROJECT/zones/$ZONE style path
+func (d *CloudMetadataDetector) detectGCP(ctx context.Context) (region, zone, id, instanceType string, err error) {
+	header := map[string]string{"Metadata-Flavor": "Google"}
+
+	if zone, err = d.get(ctx, http.MethodGet, "/computeMetadata/v1/instance/zone", header); err != nil {
+		return
+	}
+	zone = path.Base(zone)
+
+	// zone is like us-central1-a, region is us-central1
+	if index := strings.LastIndex(zone, "-"); index > 0 {
+		region = zone[:index]
+	}
+
+	id, _ = d.get(ctx, http.MethodGet, "/computeMetadata/v1/instance/id", header)
+	if instanceType, _ = d.get(ctx, http.MethodGet, "/computeMetadata/v1/instance/machine-type", header); len(instanceType) > 0 {
+		instanceType = path.Base(instanceType)
+	}
+
+	return
+}
+
+// detectAzure reads compute section of instance metadata
+func (d *CloudMetadataDetector) detectAzure(ctx context.Context) (region, zone, id, instanceType string, err error) {
+	body, err := d.get(ctx, http.MethodGet, "/metadata/instance/compute?api-version=2021-02-01", map[string]string{
+		"Metadata": "true",
+	})
+	if err != nil {
+		return
+	}
+
+	compute := &struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VmId     string `json:"vmId"`
+		VmSize   string `json:"vmSize"`
+	}{}
+
+	if err = json.Unmarshal([]byte(body), compute); err != nil {
+		return
+	}
+
+	return compute.Location, compute.Zone, compute.VmId, compute.VmSize, nil
+}
+
+// get sends request to endpoint and returns trimmed body, non 200 status is treated as error
+func (d *CloudMetadataDetector) get(ctx context.Context, method, uri string, header map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(d.Endpoint, "/")+uri, nil)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata endpoint returns %d for %s", resp.StatusCode, uri)
+	}
+
+	bytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(bytes)), nil
+}
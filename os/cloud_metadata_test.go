@@ -0,0 +1,118 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCloudMetadataServer(header, value string, routes map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if len(header) > 0 && request.Header.Get(header) != value {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, ok := routes[request.Method+" "+request.URL.RequestURI()]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writer.Write([]byte(body))
+	}))
+}
+
+func TestCloudMetadataDetector_AWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodPut && request.URL.Path == "/latest/api/token" {
+			writer.Write([]byte("ut-token"))
+			return
+		}
+
+		if request.Header.Get("X-aws-ec2-metadata-token") != "ut-token" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch request.URL.Path {
+		case "/latest/meta-data/placement/availability-zone":
+			writer.Write([]byte("us-east-1c"))
+		case "/latest/meta-data/placement/region":
+			writer.Write([]byte("us-east-1"))
+		case "/latest/meta-data/instance-id":
+			writer.Write([]byte("i-ut"))
+		case "/latest/meta-data/instance-type":
+			writer.Write([]byte("m5.large"))
+		}
+	}))
+	defer server.Close()
+
+	detector := NewCloudMetadataDetector(CloudAWS)
+	detector.Endpoint = server.URL
+
+	info := NewEnvInfo(context.TODO(), detector)
+	assert.Equal(t, CloudAWS, info.Cloud)
+	assert.Equal(t, "us-east-1", info.Region)
+	assert.Equal(t, "us-east-1c", info.AZ)
+	assert.Equal(t, "i-ut", info.InstanceId)
+	assert.Equal(t, "m5.large", info.InstanceType)
+}
+
+func TestCloudMetadataDetector_GCP(t *testing.T) {
+	server := newCloudMetadataServer("Metadata-Flavor", "Google", map[string]string{
+		"GET /computeMetadata/v1/instance/zone":         "projects/123/zones/us-central1-a",
+		"GET /computeMetadata/v1/instance/id":           "456",
+		"GET /computeMetadata/v1/instance/machine-type": "projects/123/machineTypes/e2-medium",
+	})
+	defer server.Close()
+
+	detector := NewCloudMetadataDetector(CloudGCP)
+	detector.Endpoint = server.URL
+
+	info := NewEnvInfo(context.TODO(), detector)
+	assert.Equal(t, CloudGCP, info.Cloud)
+	assert.Equal(t, "us-central1", info.Region)
+	assert.Equal(t, "us-central1-a", info.AZ)
+	assert.Equal(t, "456", info.InstanceId)
+	assert.Equal(t, "e2-medium", info.InstanceType)
+}
+
+func TestCloudMetadataDetector_Azure(t *testing.T) {
+	server := newCloudMetadataServer("Metadata", "true", map[string]string{
+		"GET /metadata/instance/compute?api-version=2021-02-01": `{"location":"eastus","zone":"1","vmId":"vm-ut","vmSize":"Standard_D2s_v3"}`,
+	})
+	defer server.Close()
+
+	detector := NewCloudMetadataDetector(CloudAzure)
+	detector.Endpoint = server.URL
+
+	info := NewEnvInfo(context.TODO(), detector)
+	assert.Equal(t, CloudAzure, info.Cloud)
+	assert.Equal(t, "eastus", info.Region)
+	assert.Equal(t, "1", info.AZ)
+	assert.Equal(t, "vm-ut", info.InstanceId)
+	assert.Equal(t, "Standard_D2s_v3", info.InstanceType)
+}
+
+func TestCloudMetadataDetector_Unavailable(t *testing.T) {
+	server := newCloudMetadataServer("", "", map[string]string{})
+	defer server.Close()
+
+	for _, provider := range []string{CloudAWS, CloudGCP, CloudAzure, "unknown"} {
+		detector := NewCloudMetadataDetector(provider)
+		detector.Endpoint = server.URL
+
+		info := &EnvInfo{Region: "ut-region"}
+		detector.Detect(context.TODO(), info)
+		assert.Empty(t, info.Cloud)
+		assert.Equal(t, "ut-region", info.Region)
+	}
+}
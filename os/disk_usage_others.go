@@ -0,0 +1,19 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package rkos
+
+import (
+	"errors"
+	"runtime"
+)
+
+// NewDiskUsage is not supported on this platform
+func NewDiskUsage(mountPoint string) (*DiskUsage, error) {
+	return nil, errors.New("disk usage is not supported on " + runtime.GOOS)
+}
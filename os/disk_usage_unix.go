@@ -0,0 +1,24 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package rkos
+
+import (
+	"syscall"
+)
+
+// NewDiskUsage reads disk usage of mount point with statfs, free bytes are bytes available to unprivileged user
+func NewDiskUsage(mountPoint string) (*DiskUsage, error) {
+	stat := &syscall.Statfs_t{}
+	if err := syscall.Statfs(mountPoint, stat); err != nil {
+		return nil, err
+	}
+
+	blockSize := uint64(stat.Bsize)
+	return newDiskUsage(mountPoint, uint64(stat.Blocks)*blockSize, uint64(stat.Bavail)*blockSize), nil
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContainerRuntimeDocker docker
+	ContainerRuntimeDocker = "docker"
+	// ContainerRuntimeContainerd containerd
+	ContainerRuntimeContainerd = "containerd"
+	// ContainerRuntimeCrio cri-o
+	ContainerRuntimeCrio = "cri-o"
+	// ContainerRuntimePodman podman
+	ContainerRuntimePodman = "podman"
+	// ContainerRuntimeLxc lxc
+	ContainerRuntimeLxc = "lxc"
+)
+
+var (
+	envDetectors     = []EnvDetector{&EnvVarDetector{}, NewKubernetesDetector(), NewContainerDetector()}
+	envDetectorsLock sync.RWMutex
+	envInfoCache     *EnvInfo
+	envInfoTimeout   = 5 * time.Second
+
+	containerIdRegex = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+// EnvInfo defines environment of running process detected by EnvDetector.
+type EnvInfo struct {
+	Region           string          `json:"region" yaml:"region" example:"us-east-1"`
+	AZ               string          `json:"az" yaml:"az" example:"us-east-1c"`
+	Realm            string          `json:"realm" yaml:"realm" example:"rookie-ninja"`
+	Domain           string          `json:"domain" yaml:"domain" example:"dev"`
+	Cloud            string          `json:"cloud,omitempty" yaml:"cloud,omitempty" example:"aws"`
+	InstanceId       string          `json:"instanceId,omitempty" yaml:"instanceId,omitempty" example:"i-0123456789abcdef0"`
+	InstanceType     string          `json:"instanceType,omitempty" yaml:"instanceType,omitempty" example:"m5.large"`
+	ContainerRuntime string          `json:"containerRuntime,omitempty" yaml:"containerRuntime,omitempty" example:"containerd"`
+	ContainerId      string          `json:"containerId,omitempty" yaml:"containerId,omitempty" example:"3f5b1c..."`
+	Kubernetes       *KubernetesInfo `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+}
+
+// KubernetesInfo defines pod information read from Kubernetes downward API
+type KubernetesInfo struct {
+	PodName        string            `json:"podName" yaml:"podName" example:"rk-demo-6d4cf56db6-x2m9k"`
+	PodNamespace   string            `json:"podNamespace" yaml:"podNamespace" example:"default"`
+	PodIP          string            `json:"podIP" yaml:"podIP" example:"10.1.0.12"`
+	NodeName       string            `json:"nodeName" yaml:"nodeName" example:"node-1"`
+	ServiceAccount string            `json:"serviceAccount" yaml:"serviceAccount" example:"default"`
+	Labels         map[string]string `json:"labels" yaml:"labels"`
+	Annotations    map[string]string `json:"annotations" yaml:"annotations"`
+}
+
+// EnvDetector detects environment and fills EnvInfo.
+//
+// Detectors run in order of registration and should only fill fields which are still empty,
+// so that earlier detectors take precedence.
+type EnvDetector interface {
+	// Detect fills info, ctx would be canceled after timeout
+	Detect(ctx context.Context, info *EnvInfo)
+}
+
+// RegisterEnvDetector appends detector to detectors used by GetEnvInfo, cached EnvInfo is cleared.
+func RegisterEnvDetector(detector EnvDetector) {
+	if detector == nil {
+		return
+	}
+
+	envDetectorsLock.Lock()
+	defer envDetectorsLock.Unlock()
+
+	envDetectors = append(envDetectors, detector)
+	envInfoCache = nil
+}
+
+// GetEnvInfo returns EnvInfo detected by registered detectors, result is cached since environment would not change.
+//
+// By default, environment variables, Kubernetes downward API and container runtime are detected,
+// register CloudMetadataDetector to query cloud metadata endpoints.
+func GetEnvInfo() *EnvInfo {
+	envDetectorsLock.RLock()
+	if envInfoCache != nil {
+		defer envDetectorsLock.RUnlock()
+		return envInfoCache
+	}
+	detectors := append([]EnvDetector{}, envDetectors...)
+	envDetectorsLock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), envInfoTimeout)
+	defer cancel()
+	res := NewEnvInfo(ctx, detectors...)
+
+	envDetectorsLock.Lock()
+	defer envDetectorsLock.Unlock()
+	envInfoCache = res
+
+	return res
+}
+
+// NewEnvInfo creates a new EnvInfo with detectors
+func NewEnvInfo(ctx context.Context, detectors ...EnvDetector) *EnvInfo {
+	res := &EnvInfo{}
+
+	for _, detector := range detectors {
+		if detector != nil {
+			detector.Detect(ctx, res)
+		}
+	}
+
+	return res
+}
+
+// EnvVarDetector reads REGION, AZ, REALM and DOMAIN environment variables
+type EnvVarDetector struct{}
+
+// Detect reads environment variables
+func (d *EnvVarDetector) Detect(ctx context.Context, info *EnvInfo) {
+	setIfEmpty(&info.Region, os.Getenv("REGION"))
+	setIfEmpty(&info.AZ, os.Getenv("AZ"))
+	setIfEmpty(&info.Realm, os.Getenv("REALM"))
+	setIfEmpty(&info.Domain, os.Getenv("DOMAIN"))
+}
+
+// NewKubernetesDetector creates a KubernetesDetector with default paths
+func NewKubernetesDetector() *KubernetesDetector {
+	return &KubernetesDetector{
+		PodInfoDir:        "/etc/podinfo",
+		ServiceAccountDir: "/var/run/secrets/kubernetes.io/serviceaccount",
+	}
+}
+
+// KubernetesDetector reads pod information from downward API.
+//
+// Environment variables POD_NAME, POD_NAMESPACE, POD_IP, NODE_NAME and SERVICE_ACCOUNT are read,
+// files named labels and annotations under PodInfoDir are read if mounted as downward API volume.
+type KubernetesDetector struct {
+	PodInfoDir        string
+	ServiceAccountDir string
+}
+
+// Detect reads downward API, nothing would be filled if not running in Kubernetes
+func (d *KubernetesDetector) Detect(ctx context.Context, info *EnvInfo) {
+	res := &KubernetesInfo{
+		PodName:        os.Getenv("POD_NAME"),
+		PodNamespace:   os.Getenv("POD_NAMESPACE"),
+		PodIP:          os.Getenv("POD_IP"),
+		NodeName:       os.Getenv("NODE_NAME"),
+		ServiceAccount: os.Getenv("SERVICE_ACCOUNT"),
+		Labels:         readDownwardAPIFile(filepath.Join(d.PodInfoDir, "labels")),
+		Annotations:    readDownwardAPIFile(filepath.Join(d.PodInfoDir, "annotations")),
+	}
+
+	setIfEmpty(&res.PodNamespace, readTrimmedFile(filepath.Join(d.ServiceAccountDir, "namespace")))
+
+	// hostname is pod name by default
+	if len(res.PodName) < 1 && len(os.Getenv("KUBERNETES_SERVICE_HOST")) > 0 {
+		res.PodName, _ = os.Hostname()
+	}
+
+	if len(res.PodName) < 1 && len(res.PodNamespace) < 1 {
+		return
+	}
+
+	if info.Kubernetes == nil {
+		info.Kubernetes = res
+	}
+}
+
+// NewContainerDetector creates a ContainerDetector with default paths
+func NewContainerDetector() *ContainerDetector {
+	return &ContainerDetector{
+		RootDir:  "/",
+		ProcRoot: ProcRoot,
+	}
+}
+
+// ContainerDetector detects container runtime and container id from /.dockerenv and /proc/1/cgroup
+type ContainerDetector struct {
+	RootDir  string
+	ProcRoot string
+}
+
+// Detect reads files, nothing would be filled if not running in container
+func (d *ContainerDetector) Detect(ctx context.Context, info *EnvInfo) {
+	containerRuntime, id := "", ""
+
+	file, err := os.Open(filepath.Join(d.ProcRoot, "1", "cgroup"))
+	if err == nil {
+		defer file.Close()
+
+		// format of line is hierarchy-ID:controller-list:cgroup-path
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			tokens := strings.SplitN(scanner.Text(), ":", 3)
+			if len(tokens) < 3 {
+				continue
+			}
+
+			path := tokens[2]
+			if len(containerRuntime) < 1 {
+				containerRuntime = containerRuntimeFromCgroupPath(path)
+			}
+
+			if len(id) < 1 {
+				id = containerIdRegex.FindString(path)
+			}
+		}
+	}
+
+	if len(containerRuntime) < 1 && fileExists(filepath.Join(d.RootDir, ".dockerenv")) {
+		containerRuntime = ContainerRuntimeDocker
+	}
+
+	setIfEmpty(&info.ContainerRuntime, containerRuntime)
+	setIfEmpty(&info.ContainerId, id)
+}
+
+// containerRuntimeFromCgroupPath returns container runtime by keywords in cgroup path
+func containerRuntimeFromCgroupPath(path string) string {
+	switch {
+	case strings.Contains(path, "docker"):
+		return ContainerRuntimeDocker
+	case strings.Contains(path, "crio"):
+		return ContainerRuntimeCrio
+	case strings.Contains(path, "containerd"):
+		return ContainerRuntimeContainerd
+	case strings.Contains(path, "libpod"):
+		return ContainerRuntimePodman
+	case strings.Contains(path, "lxc"):
+		return ContainerRuntimeLxc
+	}
+
+	return ""
+}
+
+// readDownwardAPIFile reads file with key="value" lines
+func readDownwardAPIFile(path string) map[string]string {
+	res := make(map[string]string)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return res
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		tokens := strings.SplitN(scanner.Text(), "=", 2)
+		if len(tokens) < 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(tokens[1])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		res[strings.TrimSpace(tokens[0])] = value
+	}
+
+	return res
+}
+
+func setIfEmpty(field *string, value string) {
+	if len(*field) < 1 {
+		*field = value
+	}
+}
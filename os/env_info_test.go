@@ -0,0 +1,122 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type envDetectorMock struct {
+	region string
+}
+
+func (d *envDetectorMock) Detect(ctx context.Context, info *EnvInfo) {
+	setIfEmpty(&info.Region, d.region)
+}
+
+func TestNewEnvInfo_WithEnvVar(t *testing.T) {
+	t.Setenv("REGION", "ut-region")
+	t.Setenv("AZ", "ut-az")
+	t.Setenv("REALM", "ut-realm")
+	t.Setenv("DOMAIN", "ut-domain")
+
+	// earlier detector takes precedence
+	info := NewEnvInfo(context.TODO(), &EnvVarDetector{}, &envDetectorMock{region: "mock-region"}, nil)
+	assert.Equal(t, "ut-region", info.Region)
+	assert.Equal(t, "ut-az", info.AZ)
+	assert.Equal(t, "ut-realm", info.Realm)
+	assert.Equal(t, "ut-domain", info.Domain)
+}
+
+func TestKubernetesDetector_Detect(t *testing.T) {
+	detector := &KubernetesDetector{
+		PodInfoDir:        "testdata/env/podinfo",
+		ServiceAccountDir: "testdata/env/serviceaccount",
+	}
+
+	t.Setenv("POD_NAME", "ut-pod")
+	t.Setenv("NODE_NAME", "ut-node")
+
+	info := NewEnvInfo(context.TODO(), detector)
+	assert.NotNil(t, info.Kubernetes)
+	assert.Equal(t, "ut-pod", info.Kubernetes.PodName)
+	assert.Equal(t, "rk-ns", info.Kubernetes.PodNamespace)
+	assert.Equal(t, "ut-node", info.Kubernetes.NodeName)
+	assert.Equal(t, "rk-demo", info.Kubernetes.Labels["app"])
+	assert.Equal(t, "api", info.Kubernetes.Annotations["kubernetes.io/config.source"])
+}
+
+func TestKubernetesDetector_NotInKubernetes(t *testing.T) {
+	detector := &KubernetesDetector{
+		PodInfoDir:        "testdata/non-exist",
+		ServiceAccountDir: "testdata/non-exist",
+	}
+
+	t.Setenv("POD_NAME", "")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	assert.Nil(t, NewEnvInfo(context.TODO(), detector).Kubernetes)
+}
+
+func TestContainerDetector_Detect(t *testing.T) {
+	// containerd from cgroup
+	info := NewEnvInfo(context.TODO(), &ContainerDetector{
+		RootDir:  "testdata/env/containerd",
+		ProcRoot: "testdata/env/containerd/proc",
+	})
+	assert.Equal(t, ContainerRuntimeContainerd, info.ContainerRuntime)
+	assert.Equal(t, "3f5b1c2d4e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809", info.ContainerId)
+
+	// docker from .dockerenv
+	info = NewEnvInfo(context.TODO(), &ContainerDetector{
+		RootDir:  "testdata/env/docker",
+		ProcRoot: "testdata/env/docker/proc",
+	})
+	assert.Equal(t, ContainerRuntimeDocker, info.ContainerRuntime)
+	assert.Empty(t, info.ContainerId)
+
+	// not in container
+	info = NewEnvInfo(context.TODO(), &ContainerDetector{
+		RootDir:  "testdata/non-exist",
+		ProcRoot: "testdata/non-exist",
+	})
+	assert.Empty(t, info.ContainerRuntime)
+}
+
+func TestContainerRuntimeFromCgroupPath(t *testing.T) {
+	assert.Equal(t, ContainerRuntimeDocker, containerRuntimeFromCgroupPath("/docker/abc"))
+	assert.Equal(t, ContainerRuntimeCrio, containerRuntimeFromCgroupPath("/kubepods/crio-abc.scope"))
+	assert.Equal(t, ContainerRuntimePodman, containerRuntimeFromCgroupPath("/machine.slice/libpod-abc.scope"))
+	assert.Equal(t, ContainerRuntimeLxc, containerRuntimeFromCgroupPath("/lxc/abc"))
+	assert.Empty(t, containerRuntimeFromCgroupPath("/user.slice"))
+}
+
+func TestGetEnvInfo(t *testing.T) {
+	defer func(detectors []EnvDetector) {
+		envDetectorsLock.Lock()
+		envDetectors, envInfoCache = detectors, nil
+		envDetectorsLock.Unlock()
+	}(envDetectors)
+
+	envDetectorsLock.Lock()
+	envDetectors, envInfoCache = []EnvDetector{}, nil
+	envDetectorsLock.Unlock()
+
+	RegisterEnvDetector(nil)
+	RegisterEnvDetector(&envDetectorMock{region: "mock-region"})
+	assert.Equal(t, "mock-region", GetEnvInfo().Region)
+
+	// cached
+	assert.Same(t, GetEnvInfo(), GetEnvInfo())
+
+	// cache is cleared after registration
+	info := GetEnvInfo()
+	RegisterEnvDetector(&envDetectorMock{})
+	assert.NotSame(t, info, GetEnvInfo())
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DiskMountPoints are mount points whose disk usage would be read by NewHostInfo.
+var DiskMountPoints = []string{"/"}
+
+// HostInfo defines load, uptime, memory and disk usage of host read from proc file system.
+//
+// Fields read from proc file system would be zero value on platforms without it.
+type HostInfo struct {
+	Load1             float64      `json:"load1" yaml:"load1" example:"0.52"`
+	Load5             float64      `json:"load5" yaml:"load5" example:"0.58"`
+	Load15            float64      `json:"load15" yaml:"load15" example:"0.59"`
+	UpTimeSec         int64        `json:"upTimeSec" yaml:"upTimeSec" example:"12345"`
+	MemTotalByte      uint64       `json:"memTotalByte" yaml:"memTotalByte" example:"8192000000"`
+	MemAvailableByte  uint64       `json:"memAvailableByte" yaml:"memAvailableByte" example:"6144000000"`
+	MemUsedPercentage float64      `json:"memUsedPercentage" yaml:"memUsedPercentage" example:"0.25"`
+	Disks             []*DiskUsage `json:"disks" yaml:"disks"`
+}
+
+// DiskUsage defines disk usage of a mount point
+type DiskUsage struct {
+	MountPoint     string  `json:"mountPoint" yaml:"mountPoint" example:"/"`
+	TotalByte      uint64  `json:"totalByte" yaml:"totalByte" example:"250685575168"`
+	FreeByte       uint64  `json:"freeByte" yaml:"freeByte" example:"125342787584"`
+	UsedByte       uint64  `json:"usedByte" yaml:"usedByte" example:"125342787584"`
+	UsedPercentage float64 `json:"usedPercentage" yaml:"usedPercentage" example:"0.5"`
+}
+
+// NewHostInfo creates a new HostInfo from ProcRoot and DiskMountPoints
+func NewHostInfo() *HostInfo {
+	return NewHostInfoWithRoot(ProcRoot, DiskMountPoints...)
+}
+
+// NewHostInfoWithRoot creates a new HostInfo from files under root, mount points failed to read are ignored
+func NewHostInfoWithRoot(root string, mountPoints ...string) *HostInfo {
+	res := &HostInfo{
+		Disks: make([]*DiskUsage, 0),
+	}
+
+	// 1: load average, format is "0.52 0.58 0.59 2/345 6789"
+	if fields := strings.Fields(readTrimmedFile(filepath.Join(root, "loadavg"))); len(fields) > 2 {
+		res.Load1, _ = strconv.ParseFloat(fields[0], 64)
+		res.Load5, _ = strconv.ParseFloat(fields[1], 64)
+		res.Load15, _ = strconv.ParseFloat(fields[2], 64)
+	}
+
+	// 2: uptime, format is "$UPTIME $IDLE" in seconds
+	if fields := strings.Fields(readTrimmedFile(filepath.Join(root, "uptime"))); len(fields) > 0 {
+		upTime, _ := strconv.ParseFloat(fields[0], 64)
+		res.UpTimeSec = int64(upTime)
+	}
+
+	// 3: memory
+	memInfo := readKeyValueFile(filepath.Join(root, "meminfo"))
+	res.MemTotalByte = parseKbValue(memInfo["MemTotal"])
+	res.MemAvailableByte = parseKbValue(memInfo["MemAvailable"])
+	if res.MemTotalByte > 0 && res.MemAvailableByte <= res.MemTotalByte {
+		res.MemUsedPercentage = math.Round(float64(res.MemTotalByte-res.MemAvailableByte)/float64(res.MemTotalByte)*100) / 100
+	}
+
+	// 4: disk usage
+	for _, mountPoint := range mountPoints {
+		if usage, err := NewDiskUsage(mountPoint); err == nil {
+			res.Disks = append(res.Disks, usage)
+		}
+	}
+
+	return res
+}
+
+// newDiskUsage fills used bytes and percentage
+func newDiskUsage(mountPoint string, total, free uint64) *DiskUsage {
+	res := &DiskUsage{
+		MountPoint: mountPoint,
+		TotalByte:  total,
+		FreeByte:   free,
+	}
+
+	if total >= free {
+		res.UsedByte = total - free
+	}
+
+	if total > 0 {
+		res.UsedPercentage = math.Round(float64(res.UsedByte)/float64(total)*100) / 100
+	}
+
+	return res
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"testing"
+)
+
+func TestNewHostInfoWithRoot(t *testing.T) {
+	info := NewHostInfoWithRoot("testdata/proc")
+	assert.Equal(t, 0.52, info.Load1)
+	assert.Equal(t, 0.58, info.Load5)
+	assert.Equal(t, 0.59, info.Load15)
+	assert.Equal(t, int64(12345), info.UpTimeSec)
+	assert.Equal(t, uint64(8000000*1024), info.MemTotalByte)
+	assert.Equal(t, uint64(6000000*1024), info.MemAvailableByte)
+	assert.Equal(t, 0.25, info.MemUsedPercentage)
+	assert.Empty(t, info.Disks)
+}
+
+func TestNewHostInfoWithRoot_WithMountPoints(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("disk usage is not supported on windows")
+	}
+
+	info := NewHostInfoWithRoot("testdata/non-exist", ".", "non-exist")
+	assert.Zero(t, info.Load1)
+	assert.Len(t, info.Disks, 1)
+	assert.Equal(t, ".", info.Disks[0].MountPoint)
+	assert.True(t, info.Disks[0].TotalByte > 0)
+	assert.True(t, info.Disks[0].TotalByte >= info.Disks[0].UsedByte)
+}
+
+func TestNewDiskUsage(t *testing.T) {
+	usage := newDiskUsage("/", 1000, 250)
+	assert.Equal(t, uint64(750), usage.UsedByte)
+	assert.Equal(t, 0.75, usage.UsedPercentage)
+
+	usage = newDiskUsage("/", 0, 0)
+	assert.Zero(t, usage.UsedPercentage)
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// clock ticks per second used by utime and stime of /proc/[pid]/stat, which is sysconf(_SC_CLK_TCK).
+//
+// Kernel reports these times in USER_HZ instead of CONFIG_HZ, USER_HZ is part of kernel ABI and fixed to 100
+// on every architecture supported by Go, only alpha and ia64 use 1024 which are not supported by Go.
+// Update here if Go ever supports an architecture with different USER_HZ.
+const procClockTicks = 100
+
+// ProcRoot is mount point of proc file system which would be read by NewProcInfo and NewHostInfo.
+// Override it to read proc files from another location, like fixture files in tests.
+var ProcRoot = "/proc"
+
+// ProcInfo defines resource usage of current running process read from /proc/self.
+//
+// Fields would be zero value on platforms without proc file system.
+type ProcInfo struct {
+	RssByte                 uint64  `json:"rssByte" yaml:"rssByte" example:"20971520"`
+	VszByte                 uint64  `json:"vszByte" yaml:"vszByte" example:"740818944"`
+	OpenFds                 int     `json:"openFds" yaml:"openFds" example:"4"`
+	MaxFds                  uint64  `json:"maxFds" yaml:"maxFds" example:"1024"`
+	FdUsedPercentage        float64 `json:"fdUsedPercentage" yaml:"fdUsedPercentage" example:"0.01"`
+	Threads                 int     `json:"threads" yaml:"threads" example:"12"`
+	CpuUserSec              float64 `json:"cpuUserSec" yaml:"cpuUserSec" example:"2.5"`
+	CpuSystemSec            float64 `json:"cpuSystemSec" yaml:"cpuSystemSec" example:"1.2"`
+	VoluntaryCtxSwitches    uint64  `json:"voluntaryCtxSwitches" yaml:"voluntaryCtxSwitches" example:"150"`
+	NonVoluntaryCtxSwitches uint64  `json:"nonVoluntaryCtxSwitches" yaml:"nonVoluntaryCtxSwitches" example:"30"`
+}
+
+// NewProcInfo creates a new ProcInfo from ProcRoot
+func NewProcInfo() *ProcInfo {
+	return NewProcInfoWithRoot(ProcRoot)
+}
+
+// NewProcInfoWithRoot creates a new ProcInfo from files under root/self
+func NewProcInfoWithRoot(root string) *ProcInfo {
+	res := &ProcInfo{}
+	dir := filepath.Join(root, "self")
+
+	// 1: memory, threads and context switches from status
+	status := readKeyValueFile(filepath.Join(dir, "status"))
+	res.RssByte = parseKbValue(status["VmRSS"])
+	res.VszByte = parseKbValue(status["VmSize"])
+	res.Threads, _ = strconv.Atoi(status["Threads"])
+	res.VoluntaryCtxSwitches, _ = strconv.ParseUint(status["voluntary_ctxt_switches"], 10, 64)
+	res.NonVoluntaryCtxSwitches, _ = strconv.ParseUint(status["nonvoluntary_ctxt_switches"], 10, 64)
+
+	// 2: cpu time from stat, command name may contain spaces, so fields are split after last ')'
+	stat := readTrimmedFile(filepath.Join(dir, "stat"))
+	if index := strings.LastIndex(stat, ")"); index > 0 {
+		// utime and stime are 14th and 15th field, which are 12th and 13th after command name
+		if fields := strings.Fields(stat[index+1:]); len(fields) > 12 {
+			utime, _ := strconv.ParseUint(fields[11], 10, 64)
+			stime, _ := strconv.ParseUint(fields[12], 10, 64)
+			res.CpuUserSec = float64(utime) / procClockTicks
+			res.CpuSystemSec = float64(stime) / procClockTicks
+		}
+	}
+
+	// 3: open file descriptors vs soft limit of RLIMIT_NOFILE
+	if entries, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		res.OpenFds = len(entries)
+	}
+	res.MaxFds = parseMaxOpenFiles(filepath.Join(dir, "limits"))
+
+	if res.MaxFds > 0 {
+		res.FdUsedPercentage = math.Round(float64(res.OpenFds)/float64(res.MaxFds)*100) / 100
+	}
+
+	return res
+}
+
+// parseMaxOpenFiles returns soft limit of "Max open files" in limits file, 0 returned if unlimited or missing
+func parseMaxOpenFiles(path string) uint64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+
+		if fields := strings.Fields(strings.TrimPrefix(line, "Max open files")); len(fields) > 0 {
+			res, _ := strconv.ParseUint(fields[0], 10, 64)
+			return res
+		}
+	}
+
+	return 0
+}
+
+// readKeyValueFile reads file with "key: value" lines like /proc/self/status and /proc/meminfo
+func readKeyValueFile(path string) map[string]string {
+	res := make(map[string]string)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return res
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if tokens := strings.SplitN(scanner.Text(), ":", 2); len(tokens) == 2 {
+			res[strings.TrimSpace(tokens[0])] = strings.TrimSpace(tokens[1])
+		}
+	}
+
+	return res
+}
+
+// parseKbValue converts value like "20480 kB" into bytes
+func parseKbValue(value string) uint64 {
+	fields := strings.Fields(value)
+	if len(fields) < 1 {
+		return 0
+	}
+
+	res, _ := strconv.ParseUint(fields[0], 10, 64)
+	if len(fields) > 1 && fields[1] == "kB" {
+		res *= 1024
+	}
+
+	return res
+}
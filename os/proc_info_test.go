@@ -0,0 +1,39 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewProcInfoWithRoot(t *testing.T) {
+	info := NewProcInfoWithRoot("testdata/proc")
+	assert.Equal(t, uint64(20480*1024), info.RssByte)
+	assert.Equal(t, uint64(723456*1024), info.VszByte)
+	assert.Equal(t, 12, info.Threads)
+	assert.Equal(t, uint64(150), info.VoluntaryCtxSwitches)
+	assert.Equal(t, uint64(30), info.NonVoluntaryCtxSwitches)
+	assert.Equal(t, 2.5, info.CpuUserSec)
+	assert.Equal(t, 1.2, info.CpuSystemSec)
+	assert.Equal(t, 4, info.OpenFds)
+	assert.Equal(t, uint64(1024), info.MaxFds)
+	assert.Equal(t, 0.0, info.FdUsedPercentage)
+}
+
+func TestNewProcInfoWithRoot_NotFound(t *testing.T) {
+	info := NewProcInfoWithRoot("testdata/non-exist")
+	assert.NotNil(t, info)
+	assert.Zero(t, info.RssByte)
+	assert.Zero(t, info.MaxFds)
+}
+
+func TestNewProcInfo(t *testing.T) {
+	defer func(root string) { ProcRoot = root }(ProcRoot)
+
+	ProcRoot = "testdata/proc"
+	assert.Equal(t, 12, NewProcInfo().Threads)
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+)
+
+// metric names read by NewRuntimeInfo, metrics not supported by current go version are ignored
+const (
+	metricGcCycles        = "/gc/cycles/total:gc-cycles"
+	metricGcPauses        = "/gc/pauses:seconds"
+	metricGcHeapGoal      = "/gc/heap/goal:bytes"
+	metricGcHeapObjects   = "/gc/heap/objects:objects"
+	metricGcGoGc          = "/gc/gogc:percent"
+	metricGcGoMemLimit    = "/gc/gomemlimit:bytes"
+	metricHeapObjectBytes = "/memory/classes/heap/objects:bytes"
+	metricSchedGoroutines = "/sched/goroutines:goroutines"
+	metricSchedLatencies  = "/sched/latencies:seconds"
+)
+
+// RuntimeInfo defines go runtime stats read from runtime/metrics.
+//
+// Unlike MemInfo which reads runtime.MemStats, reading runtime/metrics does not stop the world.
+type RuntimeInfo struct {
+	GcCycles        uint64     `json:"gcCycles" yaml:"gcCycles" example:"12"`
+	GcPauseMs       *Quantiles `json:"gcPauseMs" yaml:"gcPauseMs"`
+	HeapGoalByte    uint64     `json:"heapGoalByte" yaml:"heapGoalByte" example:"4194304"`
+	HeapObjectsByte uint64     `json:"heapObjectsByte" yaml:"heapObjectsByte" example:"2097152"`
+	LiveObjects     uint64     `json:"liveObjects" yaml:"liveObjects" example:"10240"`
+	GoGcPercent     int64      `json:"goGcPercent" yaml:"goGcPercent" example:"100"`
+	GoMemLimitByte  int64      `json:"goMemLimitByte" yaml:"goMemLimitByte" example:"9223372036854775807"`
+	Goroutines      uint64     `json:"goroutines" yaml:"goroutines" example:"9"`
+	SchedLatencyMs  *Quantiles `json:"schedLatencyMs" yaml:"schedLatencyMs"`
+	CgoCalls        int64      `json:"cgoCalls" yaml:"cgoCalls" example:"1"`
+}
+
+// Quantiles defines quantiles of a histogram, upper bound of bucket is used as value
+type Quantiles struct {
+	P50 float64 `json:"p50" yaml:"p50" example:"0.05"`
+	P90 float64 `json:"p90" yaml:"p90" example:"0.1"`
+	P99 float64 `json:"p99" yaml:"p99" example:"0.5"`
+	Max float64 `json:"max" yaml:"max" example:"1"`
+}
+
+// NewRuntimeInfo creates a new RuntimeInfo
+func NewRuntimeInfo() *RuntimeInfo {
+	samples := []metrics.Sample{
+		{Name: metricGcCycles},
+		{Name: metricGcPauses},
+		{Name: metricGcHeapGoal},
+		{Name: metricGcHeapObjects},
+		{Name: metricGcGoGc},
+		{Name: metricGcGoMemLimit},
+		{Name: metricHeapObjectBytes},
+		{Name: metricSchedGoroutines},
+		{Name: metricSchedLatencies},
+	}
+	metrics.Read(samples)
+
+	res := &RuntimeInfo{
+		GcPauseMs:      &Quantiles{},
+		SchedLatencyMs: &Quantiles{},
+		GoGcPercent:    goGcPercentFromEnv(),
+		GoMemLimitByte: goMemLimitFromEnv(),
+		CgoCalls:       runtime.NumCgoCall(),
+	}
+
+	for i := range samples {
+		sample := samples[i]
+
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			value := sample.Value.Uint64()
+			switch sample.Name {
+			case metricGcCycles:
+				res.GcCycles = value
+			case metricGcHeapGoal:
+				res.HeapGoalByte = value
+			case metricGcHeapObjects:
+				res.LiveObjects = value
+			case metricGcGoGc:
+				res.GoGcPercent = int64(value)
+			case metricGcGoMemLimit:
+				res.GoMemLimitByte = int64(value)
+			case metricHeapObjectBytes:
+				res.HeapObjectsByte = value
+			case metricSchedGoroutines:
+				res.Goroutines = value
+			}
+		case metrics.KindFloat64Histogram:
+			switch sample.Name {
+			case metricGcPauses:
+				res.GcPauseMs = newQuantilesMs(sample.Value.Float64Histogram())
+			case metricSchedLatencies:
+				res.SchedLatencyMs = newQuantilesMs(sample.Value.Float64Histogram())
+			}
+		}
+	}
+
+	return res
+}
+
+// newQuantilesMs calculates quantiles of histogram in seconds and converts them into milliseconds
+func newQuantilesMs(hist *metrics.Float64Histogram) *Quantiles {
+	res := &Quantiles{}
+	if hist == nil {
+		return res
+	}
+
+	total := uint64(0)
+	for _, count := range hist.Counts {
+		total += count
+	}
+
+	if total < 1 {
+		return res
+	}
+
+	res.P50 = histogramQuantile(hist, total, 0.5)
+	res.P90 = histogramQuantile(hist, total, 0.9)
+	res.P99 = histogramQuantile(hist, total, 0.99)
+	res.Max = histogramQuantile(hist, total, 1)
+
+	return res
+}
+
+// histogramQuantile returns upper bound of bucket which contains quantile in milliseconds,
+// lower bound is used if upper bound is +Inf
+func histogramQuantile(hist *metrics.Float64Histogram, total uint64, quantile float64) float64 {
+	target := uint64(math.Ceil(float64(total) * quantile))
+	cumulative := uint64(0)
+
+	for i, count := range hist.Counts {
+		cumulative += count
+		if count < 1 || cumulative < target {
+			continue
+		}
+
+		bound := hist.Buckets[i+1]
+		if math.IsInf(bound, 1) {
+			bound = hist.Buckets[i]
+		}
+
+		return bound * 1000
+	}
+
+	return 0
+}
+
+// goGcPercentFromEnv returns GOGC from environment variable, -1 means off.
+// Used with go versions which do not support /gc/gogc:percent.
+func goGcPercentFromEnv() int64 {
+	value := strings.TrimSpace(os.Getenv("GOGC"))
+	if strings.EqualFold(value, "off") {
+		return -1
+	}
+
+	if res, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return res
+	}
+
+	return 100
+}
+
+// goMemLimitFromEnv returns GOMEMLIMIT from environment variable, math.MaxInt64 means no limit.
+// Used with go versions which do not support /gc/gomemlimit:bytes.
+func goMemLimitFromEnv() int64 {
+	value := strings.TrimSpace(os.Getenv("GOMEMLIMIT"))
+
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TiB", 1 << 40},
+		{"GiB", 1 << 30},
+		{"MiB", 1 << 20},
+		{"KiB", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	if res, err := strconv.ParseInt(value, 10, 64); err == nil && res > 0 && res <= math.MaxInt64/multiplier {
+		return res * multiplier
+	}
+
+	return math.MaxInt64
+}
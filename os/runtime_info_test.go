@@ -0,0 +1,73 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkos
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+)
+
+func TestNewRuntimeInfo_HappyCase(t *testing.T) {
+	runtime.GC()
+
+	info := NewRuntimeInfo()
+	assert.NotNil(t, info)
+	assert.True(t, info.GcCycles > 0)
+	assert.True(t, info.HeapGoalByte > 0)
+	assert.True(t, info.LiveObjects > 0)
+	assert.True(t, info.Goroutines > 0)
+	assert.True(t, info.GcPauseMs.Max >= info.GcPauseMs.P50)
+	assert.NotZero(t, info.GoMemLimitByte)
+	assert.NotNil(t, info.SchedLatencyMs)
+}
+
+func TestNewQuantilesMs(t *testing.T) {
+	// nil and empty histogram
+	assert.Equal(t, &Quantiles{}, newQuantilesMs(nil))
+	assert.Equal(t, &Quantiles{}, newQuantilesMs(&metrics.Float64Histogram{
+		Counts:  []uint64{0},
+		Buckets: []float64{0, 1},
+	}))
+
+	hist := &metrics.Float64Histogram{
+		Counts:  []uint64{50, 40, 9, 1},
+		Buckets: []float64{0, 0.001, 0.01, 0.1, math.Inf(1)},
+	}
+
+	res := newQuantilesMs(hist)
+	assert.Equal(t, 1.0, res.P50)
+	assert.Equal(t, 10.0, res.P90)
+	assert.Equal(t, 100.0, res.P99)
+	assert.Equal(t, 100.0, res.Max)
+}
+
+func TestGoGcPercentFromEnv(t *testing.T) {
+	t.Setenv("GOGC", "")
+	assert.Equal(t, int64(100), goGcPercentFromEnv())
+
+	t.Setenv("GOGC", "off")
+	assert.Equal(t, int64(-1), goGcPercentFromEnv())
+
+	t.Setenv("GOGC", "200")
+	assert.Equal(t, int64(200), goGcPercentFromEnv())
+}
+
+func TestGoMemLimitFromEnv(t *testing.T) {
+	t.Setenv("GOMEMLIMIT", "")
+	assert.Equal(t, int64(math.MaxInt64), goMemLimitFromEnv())
+
+	t.Setenv("GOMEMLIMIT", "512MiB")
+	assert.Equal(t, int64(512<<20), goMemLimitFromEnv())
+
+	t.Setenv("GOMEMLIMIT", "1024")
+	assert.Equal(t, int64(1024), goMemLimitFromEnv())
+
+	t.Setenv("GOMEMLIMIT", "invalid")
+	assert.Equal(t, int64(math.MaxInt64), goMemLimitFromEnv())
+}